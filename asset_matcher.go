@@ -0,0 +1,181 @@
+package freezer
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ForestEckhardt/freezer/github"
+)
+
+//go:generate faux --interface AssetMatcher --output fakes/asset_matcher.go
+type AssetMatcher interface {
+	Match(buildpack RemoteBuildpack, release github.Release) (github.ReleaseAsset, error)
+}
+
+// DefaultAssetNameTemplates are the names PatternAssetMatcher looks for when
+// it is not given any patterns.
+var DefaultAssetNameTemplates = []string{"{repo}-{version}-{os}-{arch}.cnb"}
+
+// PatternAssetMatcher selects the release asset whose name matches one of its
+// patterns. Name templates are tried first, then globs and then regular
+// expressions, each in the order they were given, and the first pattern that
+// matches an asset selects it.
+//
+// Templates and globs may refer to the buildpack and release with {org},
+// {repo}, {os}, {arch}, {tag} and {version}, which is the tag without a
+// leading v.
+//
+// Without any patterns the default name templates are used and, for
+// linux/amd64, the names of releases that predate multi-platform buildpacks
+// are accepted as well: {repo}-{version}.cnb or, failing that, the only .cnb
+// asset of the release.
+type PatternAssetMatcher struct {
+	templates    []string
+	globs        []string
+	regexps      []*regexp.Regexp
+	contentTypes []string
+}
+
+func NewPatternAssetMatcher() PatternAssetMatcher {
+	return PatternAssetMatcher{}
+}
+
+// WithNameTemplates matches assets whose name is exactly one of templates once
+// its placeholders have been replaced.
+func (m PatternAssetMatcher) WithNameTemplates(templates ...string) PatternAssetMatcher {
+	m.templates = templates
+	return m
+}
+
+// WithGlobs matches assets whose name matches one of patterns, using the
+// syntax of path.Match, once its placeholders have been replaced.
+func (m PatternAssetMatcher) WithGlobs(patterns ...string) PatternAssetMatcher {
+	m.globs = patterns
+	return m
+}
+
+func (m PatternAssetMatcher) WithRegexps(patterns ...*regexp.Regexp) PatternAssetMatcher {
+	m.regexps = patterns
+	return m
+}
+
+// WithContentTypes only considers assets with one of the given content types,
+// such as application/octet-stream.
+func (m PatternAssetMatcher) WithContentTypes(contentTypes ...string) PatternAssetMatcher {
+	m.contentTypes = contentTypes
+	return m
+}
+
+// Match returns the asset of release to download for buildpack. When no asset
+// matches it returns an error matching github.ErrAssetNotFound that lists the
+// assets the release does have.
+func (m PatternAssetMatcher) Match(buildpack RemoteBuildpack, release github.Release) (github.ReleaseAsset, error) {
+	var candidates []github.ReleaseAsset
+	for _, asset := range release.Assets {
+		if m.acceptsContentType(asset.ContentType) {
+			candidates = append(candidates, asset)
+		}
+	}
+
+	replacer := strings.NewReplacer(
+		"{org}", buildpack.Org,
+		"{repo}", buildpack.Repo,
+		"{os}", buildpack.Platform,
+		"{arch}", buildpack.Arch,
+		"{tag}", release.TagName,
+		"{version}", strings.TrimPrefix(release.TagName, "v"),
+	)
+
+	var legacy bool
+	templates := m.templates
+	if len(m.templates) == 0 && len(m.globs) == 0 && len(m.regexps) == 0 {
+		templates = DefaultAssetNameTemplates
+
+		legacy = buildpack.Platform == "linux" && buildpack.Arch == "amd64"
+		if legacy {
+			templates = append(append([]string(nil), templates...), "{repo}-{version}.cnb")
+		}
+	}
+
+	var patterns []string
+	for _, template := range templates {
+		name := replacer.Replace(template)
+		patterns = append(patterns, name)
+
+		if asset, ok := findAsset(candidates, func(assetName string) bool { return assetName == name }); ok {
+			return asset, nil
+		}
+	}
+
+	for _, glob := range m.globs {
+		pattern := replacer.Replace(glob)
+		patterns = append(patterns, pattern)
+
+		if asset, ok := findAsset(candidates, func(assetName string) bool {
+			matched, _ := path.Match(pattern, assetName)
+			return matched
+		}); ok {
+			return asset, nil
+		}
+	}
+
+	for _, re := range m.regexps {
+		patterns = append(patterns, re.String())
+
+		if asset, ok := findAsset(candidates, re.MatchString); ok {
+			return asset, nil
+		}
+	}
+
+	if legacy {
+		var cnbs []github.ReleaseAsset
+		for _, asset := range candidates {
+			if filepath.Ext(asset.Name) == ".cnb" {
+				cnbs = append(cnbs, asset)
+			}
+		}
+
+		if len(cnbs) == 1 {
+			return cnbs[0], nil
+		}
+	}
+
+	available := "none"
+	if len(release.Assets) > 0 {
+		var names []string
+		for _, asset := range release.Assets {
+			names = append(names, asset.Name)
+		}
+		available = strings.Join(names, ", ")
+	}
+
+	return github.ReleaseAsset{}, fmt.Errorf("%w: release %s of %s/%s has no asset matching %s (available: %s)",
+		github.ErrAssetNotFound, release.TagName, buildpack.Org, buildpack.Repo, strings.Join(patterns, ", "), available)
+}
+
+func (m PatternAssetMatcher) acceptsContentType(contentType string) bool {
+	if len(m.contentTypes) == 0 {
+		return true
+	}
+
+	for _, accepted := range m.contentTypes {
+		if contentType == accepted {
+			return true
+		}
+	}
+
+	return false
+}
+
+func findAsset(assets []github.ReleaseAsset, match func(name string) bool) (github.ReleaseAsset, bool) {
+	for _, asset := range assets {
+		if match(asset.Name) {
+			return asset, true
+		}
+	}
+
+	return github.ReleaseAsset{}, false
+}
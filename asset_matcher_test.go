@@ -0,0 +1,173 @@
+package freezer_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/ForestEckhardt/freezer"
+	"github.com/ForestEckhardt/freezer/github"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testPatternAssetMatcher(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		buildpack freezer.RemoteBuildpack
+		release   github.Release
+		matcher   freezer.PatternAssetMatcher
+	)
+
+	it.Before(func() {
+		buildpack = freezer.NewRemoteBuildpack("some-org", "some-repo", "linux", "arm64")
+
+		release = github.Release{
+			TagName: "v1.2.3",
+			Assets: []github.ReleaseAsset{
+				{Name: "some-repo-1.2.3-linux-amd64.cnb", URL: "amd64-url", ContentType: "application/octet-stream"},
+				{Name: "some-repo-1.2.3-linux-arm64.cnb", URL: "arm64-url", ContentType: "application/octet-stream"},
+				{Name: "some-repo-1.2.3-linux-arm64.tgz", URL: "arm64-tgz-url", ContentType: "application/gzip"},
+			},
+		}
+
+		matcher = freezer.NewPatternAssetMatcher()
+	})
+
+	context("Match", func() {
+		it("selects the asset named for the platform", func() {
+			asset, err := matcher.Match(buildpack, release)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(asset.URL).To(Equal("arm64-url"))
+		})
+
+		context("when the buildpack is for linux/amd64", func() {
+			it.Before(func() {
+				buildpack = freezer.NewRemoteBuildpack("some-org", "some-repo", "linux", "amd64")
+			})
+
+			it("selects the asset named for the platform", func() {
+				asset, err := matcher.Match(buildpack, release)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(asset.URL).To(Equal("amd64-url"))
+			})
+
+			context("and the release predates multi-platform buildpacks", func() {
+				it.Before(func() {
+					release.Assets = []github.ReleaseAsset{
+						{Name: "some-repo.tgz", URL: "tgz-url"},
+						{Name: "some-repo-1.2.3.cnb", URL: "cnb-url"},
+					}
+				})
+
+				it("selects the asset without a platform in its name", func() {
+					asset, err := matcher.Match(buildpack, release)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(asset.URL).To(Equal("cnb-url"))
+				})
+
+				context("and the asset is named some other way", func() {
+					it.Before(func() {
+						release.Assets[1].Name = "some-repo.cnb"
+					})
+
+					it("selects the only .cnb asset", func() {
+						asset, err := matcher.Match(buildpack, release)
+						Expect(err).NotTo(HaveOccurred())
+						Expect(asset.URL).To(Equal("cnb-url"))
+					})
+				})
+			})
+		})
+
+		context("when name templates are given", func() {
+			it.Before(func() {
+				matcher = matcher.WithNameTemplates("{org}-{repo}-{tag}.cnb", "{repo}-{version}-{os}-{arch}.tgz")
+			})
+
+			it("selects the asset named by the first template that matches", func() {
+				asset, err := matcher.Match(buildpack, release)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(asset.URL).To(Equal("arm64-tgz-url"))
+			})
+		})
+
+		context("when globs are given", func() {
+			it.Before(func() {
+				matcher = matcher.WithGlobs("*-{arch}.tgz")
+			})
+
+			it("selects the first asset that matches", func() {
+				asset, err := matcher.Match(buildpack, release)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(asset.URL).To(Equal("arm64-tgz-url"))
+			})
+		})
+
+		context("when regular expressions are given", func() {
+			it.Before(func() {
+				matcher = matcher.WithRegexps(regexp.MustCompile(`-amd64\.cnb$`))
+			})
+
+			it("selects the first asset that matches", func() {
+				asset, err := matcher.Match(buildpack, release)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(asset.URL).To(Equal("amd64-url"))
+			})
+		})
+
+		context("when content types are given", func() {
+			it.Before(func() {
+				matcher = matcher.WithGlobs("some-repo-*-linux-arm64.*").WithContentTypes("application/gzip")
+			})
+
+			it("only considers assets with those content types", func() {
+				asset, err := matcher.Match(buildpack, release)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(asset.URL).To(Equal("arm64-tgz-url"))
+			})
+		})
+
+		context("failure cases", func() {
+			context("when no asset matches", func() {
+				it.Before(func() {
+					buildpack = freezer.NewRemoteBuildpack("some-org", "some-repo", "darwin", "arm64")
+				})
+
+				it("returns an error listing the available assets", func() {
+					_, err := matcher.Match(buildpack, release)
+					Expect(err).To(MatchError(github.ErrAssetNotFound))
+					Expect(err).To(MatchError("asset not found: release v1.2.3 of some-org/some-repo has no asset matching some-repo-1.2.3-darwin-arm64.cnb " +
+						"(available: some-repo-1.2.3-linux-amd64.cnb, some-repo-1.2.3-linux-arm64.cnb, some-repo-1.2.3-linux-arm64.tgz)"))
+				})
+			})
+
+			context("when a linux/amd64 release has several unrecognised .cnb assets", func() {
+				it.Before(func() {
+					buildpack = freezer.NewRemoteBuildpack("some-org", "some-repo", "linux", "amd64")
+					release.Assets = []github.ReleaseAsset{
+						{Name: "first.cnb"},
+						{Name: "second.cnb"},
+					}
+				})
+
+				it("does not guess", func() {
+					_, err := matcher.Match(buildpack, release)
+					Expect(err).To(MatchError(github.ErrAssetNotFound))
+				})
+			})
+
+			context("when the release has no assets", func() {
+				it.Before(func() {
+					release.Assets = nil
+				})
+
+				it("says so", func() {
+					_, err := matcher.Match(buildpack, release)
+					Expect(err).To(MatchError(ContainSubstring("(available: none)")))
+				})
+			})
+		})
+	})
+}
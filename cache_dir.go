@@ -0,0 +1,256 @@
+package freezer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// CacheDirEnv names the environment variable that overrides the default
+	// cache directory.
+	CacheDirEnv = "FREEZER_CACHE_DIR"
+
+	legacyCacheDirName = ".freezer-cache"
+)
+
+// DefaultCacheDir returns the cache directory used when none is given
+// explicitly. It is, in order of precedence, the value of FREEZER_CACHE_DIR,
+// $XDG_CACHE_HOME/freezer and $HOME/.freezer-cache.
+func DefaultCacheDir() string {
+	if dir := os.Getenv(CacheDirEnv); dir != "" {
+		return dir
+	}
+
+	if dir := xdgCacheDir(); dir != "" {
+		return dir
+	}
+
+	return legacyCacheDir()
+}
+
+func xdgCacheDir() string {
+	if dir := os.Getenv("XDG_CACHE_HOME"); dir != "" {
+		return filepath.Join(dir, "freezer")
+	}
+
+	return ""
+}
+
+func legacyCacheDir() string {
+	return filepath.Join(os.Getenv("HOME"), legacyCacheDirName)
+}
+
+// MigrateLegacyCacheDir moves the cache from $HOME/.freezer-cache to dir,
+// rewriting the paths recorded in its index, so that buildpacks cached before
+// $XDG_CACHE_HOME/freezer became the default are not fetched again. Nothing is
+// done unless dir is that default and FREEZER_CACHE_DIR is not set, or if there
+// is no legacy cache or dir already contains a cache. The migration holds the
+// lock on dir that CacheManager takes, so processes that open a fresh cache at
+// the same time migrate it only once.
+func MigrateLegacyCacheDir(dir string) error {
+	if os.Getenv("HOME") == "" || os.Getenv(CacheDirEnv) != "" {
+		return nil
+	}
+
+	//A directory that was chosen explicitly may be temporary, so the legacy
+	//cache is only ever moved into the location that superseded it
+	xdgDir := xdgCacheDir()
+	if xdgDir == "" || filepath.Clean(dir) != xdgDir {
+		return nil
+	}
+
+	legacyDir := legacyCacheDir()
+
+	//Checking before taking the lock avoids creating dir when there is nothing
+	//to migrate
+	migrate, err := needsMigration(legacyDir, dir)
+	if err != nil || !migrate {
+		return err
+	}
+
+	err = os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := lockFile(filepath.Join(dir, cacheLockFile))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	//Another process may have migrated the cache while we were waiting
+	migrate, err = needsMigration(legacyDir, dir)
+	if err != nil || !migrate {
+		return err
+	}
+
+	//A legacy cache written by a newer version of freezer is left for that
+	//version to migrate, as its index could not be rewritten
+	_, schemaVersion, err := NewCacheManager(legacyDir).load()
+	if err != nil {
+		return err
+	}
+
+	if checkSchemaVersion(schemaVersion) != nil {
+		return nil
+	}
+
+	err = migrateDir(legacyDir, dir)
+	if err != nil {
+		return fmt.Errorf("failed to migrate cache from %s to %s: %w", legacyDir, dir, err)
+	}
+
+	//The lock is already held so the index is rewritten without opening the
+	//cache, which would wait for it
+	cacheManager := NewCacheManager(dir)
+	db, _, err := cacheManager.load()
+	if err != nil {
+		return err
+	}
+
+	for key, entry := range db {
+		if rel, err := filepath.Rel(legacyDir, entry.URI); err == nil && !strings.HasPrefix(rel, "..") {
+			entry.URI = filepath.Join(dir, rel)
+			db[key] = entry
+		}
+	}
+
+	return cacheManager.write(db)
+}
+
+// needsMigration reports whether there is a legacy cache at legacyDir and dir
+// holds nothing but the lock file.
+func needsMigration(legacyDir, dir string) (bool, error) {
+	info, err := os.Stat(legacyDir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if !info.IsDir() {
+		return false, nil
+	}
+
+	contents, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+
+	for _, content := range contents {
+		if content.Name() != cacheLockFile {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// migrateDir moves the contents of src into dst and removes src, falling back
+// to copying when they are on different file systems. The lock file of src is
+// left behind as the lock file of dst is held. The index is moved last so that
+// an interrupted migration leaves dst without one.
+func migrateDir(src, dst string) error {
+	contents, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for _, content := range contents {
+		if content.Name() != cacheLockFile && content.Name() != cacheIndexFile {
+			names = append(names, content.Name())
+		}
+	}
+	names = append(names, cacheIndexFile)
+
+	for _, name := range names {
+		err = move(filepath.Join(src, name), filepath.Join(dst, name))
+		if err != nil {
+			return err
+		}
+	}
+
+	//Everything has been moved so a legacy cache that cannot be removed is
+	//only wasted space
+	_ = os.RemoveAll(src)
+
+	return nil
+}
+
+func move(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	err = os.Rename(src, dst)
+	if err == nil {
+		return nil
+	}
+
+	if info.IsDir() {
+		return copyDir(src, dst)
+	}
+
+	return copyFile(src, dst, info)
+}
+
+func copyDir(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case info.Mode().IsRegular():
+			return copyFile(path, target, info)
+		default:
+			return nil
+		}
+	})
+}
+
+func copyFile(src, dst string, info os.FileInfo) error {
+	source, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	destination, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	defer destination.Close()
+
+	_, err = io.Copy(destination, source)
+	if err != nil {
+		return err
+	}
+
+	err = destination.Close()
+	if err != nil {
+		return err
+	}
+
+	//Preserving modification times keeps eviction ordering intact
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
@@ -0,0 +1,197 @@
+package freezer_test
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/ForestEckhardt/freezer"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testCacheDir(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		homeDir string
+		env     map[string]string
+	)
+
+	it.Before(func() {
+		env = map[string]string{}
+		for _, name := range []string{"HOME", "FREEZER_CACHE_DIR", "XDG_CACHE_HOME"} {
+			env[name] = os.Getenv(name)
+		}
+
+		var err error
+		homeDir, err = os.MkdirTemp("", "home")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.Setenv("HOME", homeDir)).To(Succeed())
+		Expect(os.Unsetenv("FREEZER_CACHE_DIR")).To(Succeed())
+		Expect(os.Unsetenv("XDG_CACHE_HOME")).To(Succeed())
+	})
+
+	it.After(func() {
+		for name, value := range env {
+			Expect(os.Setenv(name, value)).To(Succeed())
+		}
+
+		Expect(os.RemoveAll(homeDir)).To(Succeed())
+	})
+
+	context("DefaultCacheDir", func() {
+		it("returns the legacy location in the home directory", func() {
+			Expect(freezer.DefaultCacheDir()).To(Equal(filepath.Join(homeDir, ".freezer-cache")))
+		})
+
+		context("when XDG_CACHE_HOME is set", func() {
+			it.Before(func() {
+				Expect(os.Setenv("XDG_CACHE_HOME", filepath.Join(homeDir, "xdg"))).To(Succeed())
+			})
+
+			it("returns a directory within it", func() {
+				Expect(freezer.DefaultCacheDir()).To(Equal(filepath.Join(homeDir, "xdg", "freezer")))
+			})
+
+			context("when FREEZER_CACHE_DIR is set", func() {
+				it.Before(func() {
+					Expect(os.Setenv("FREEZER_CACHE_DIR", filepath.Join(homeDir, "some-cache"))).To(Succeed())
+				})
+
+				it("returns its value", func() {
+					Expect(freezer.DefaultCacheDir()).To(Equal(filepath.Join(homeDir, "some-cache")))
+				})
+			})
+		})
+	})
+
+	context("MigrateLegacyCacheDir", func() {
+		var (
+			legacyDir string
+			cacheDir  string
+		)
+
+		it.Before(func() {
+			legacyDir = filepath.Join(homeDir, ".freezer-cache")
+			cacheDir = filepath.Join(homeDir, "xdg", "freezer")
+
+			Expect(os.Setenv("XDG_CACHE_HOME", filepath.Join(homeDir, "xdg"))).To(Succeed())
+
+			Expect(os.MkdirAll(filepath.Join(legacyDir, "some-buildpack"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(legacyDir, "some-buildpack", "some-buildpack.cnb"), []byte("some-content"), 0644)).To(Succeed())
+
+			cacheManager := freezer.NewCacheManager(legacyDir)
+			Expect(cacheManager.Open()).To(Succeed())
+			Expect(cacheManager.Set("some-buildpack", freezer.CacheEntry{
+				Version: "testing",
+				URI:     filepath.Join(legacyDir, "some-buildpack", "some-buildpack.cnb"),
+			})).To(Succeed())
+			Expect(cacheManager.Close()).To(Succeed())
+		})
+
+		it("moves the legacy cache and rewrites the paths in its index", func() {
+			Expect(freezer.MigrateLegacyCacheDir(cacheDir)).To(Succeed())
+
+			Expect(legacyDir).NotTo(BeADirectory())
+			Expect(filepath.Join(cacheDir, "some-buildpack", "some-buildpack.cnb")).To(BeAnExistingFile())
+
+			cacheManager := freezer.NewCacheManager(cacheDir)
+			Expect(cacheManager.Open()).To(Succeed())
+
+			entry, ok, err := cacheManager.Get("some-buildpack")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(entry.URI).To(Equal(filepath.Join(cacheDir, "some-buildpack", "some-buildpack.cnb")))
+		})
+
+		context("when several processes migrate the cache at once", func() {
+			it("moves it exactly once", func() {
+				var wg sync.WaitGroup
+				errs := make([]error, 8)
+				for i := range errs {
+					wg.Add(1)
+					go func(i int) {
+						defer wg.Done()
+						errs[i] = freezer.MigrateLegacyCacheDir(cacheDir)
+					}(i)
+				}
+				wg.Wait()
+
+				for _, err := range errs {
+					Expect(err).NotTo(HaveOccurred())
+				}
+
+				Expect(legacyDir).NotTo(BeADirectory())
+				Expect(filepath.Join(cacheDir, "some-buildpack", "some-buildpack.cnb")).To(BeAnExistingFile())
+
+				cacheManager := freezer.NewCacheManager(cacheDir)
+				Expect(cacheManager.Open()).To(Succeed())
+
+				entry, ok, err := cacheManager.Get("some-buildpack")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ok).To(BeTrue())
+				Expect(entry.URI).To(Equal(filepath.Join(cacheDir, "some-buildpack", "some-buildpack.cnb")))
+			})
+		})
+
+		context("when FREEZER_CACHE_DIR is set", func() {
+			it.Before(func() {
+				Expect(os.Setenv("FREEZER_CACHE_DIR", cacheDir)).To(Succeed())
+			})
+
+			it("leaves the legacy cache alone", func() {
+				Expect(freezer.MigrateLegacyCacheDir(cacheDir)).To(Succeed())
+
+				Expect(filepath.Join(legacyDir, "some-buildpack", "some-buildpack.cnb")).To(BeAnExistingFile())
+				Expect(cacheDir).NotTo(BeADirectory())
+			})
+		})
+
+		context("when the new location is not the default location", func() {
+			it("leaves the legacy cache alone", func() {
+				Expect(freezer.MigrateLegacyCacheDir(filepath.Join(homeDir, "some-cache"))).To(Succeed())
+
+				Expect(filepath.Join(legacyDir, "some-buildpack", "some-buildpack.cnb")).To(BeAnExistingFile())
+				Expect(filepath.Join(homeDir, "some-cache")).NotTo(BeADirectory())
+			})
+		})
+
+		context("when the new location already contains a cache", func() {
+			it.Before(func() {
+				Expect(os.MkdirAll(cacheDir, os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(cacheDir, "buildpacks-cache.json"), []byte(`{}`), 0644)).To(Succeed())
+			})
+
+			it("leaves both caches alone", func() {
+				Expect(freezer.MigrateLegacyCacheDir(cacheDir)).To(Succeed())
+
+				Expect(legacyDir).To(BeADirectory())
+				Expect(filepath.Join(cacheDir, "some-buildpack")).NotTo(BeADirectory())
+			})
+		})
+
+		context("when the new location is the legacy location", func() {
+			it("does nothing", func() {
+				Expect(freezer.MigrateLegacyCacheDir(legacyDir)).To(Succeed())
+
+				Expect(filepath.Join(legacyDir, "some-buildpack", "some-buildpack.cnb")).To(BeAnExistingFile())
+			})
+		})
+
+		context("when there is no legacy cache", func() {
+			it.Before(func() {
+				Expect(os.RemoveAll(legacyDir)).To(Succeed())
+			})
+
+			it("does nothing", func() {
+				Expect(freezer.MigrateLegacyCacheDir(cacheDir)).To(Succeed())
+
+				Expect(cacheDir).NotTo(BeADirectory())
+			})
+		})
+	})
+}
@@ -0,0 +1,222 @@
+package freezer
+
+import (
+	"errors"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EvictionPolicy bounds the amount of data kept in the cache. A zero value for
+// any of the limits disables it.
+type EvictionPolicy struct {
+	// MaxSize is the maximum total size in bytes of the cached files.
+	MaxSize int64
+
+	// MaxAge is the maximum time since an entry was last accessed.
+	MaxAge time.Duration
+
+	// MaxVersions is the maximum number of versions kept for a single
+	// buildpack, counting pinned and constrained versions separately from the
+	// latest release and cached (offline) builds separately from uncached ones.
+	MaxVersions int
+}
+
+type accessTimes struct {
+	mutex sync.Mutex
+	times map[string]time.Time
+}
+
+func (c CacheManager) WithEvictionPolicy(policy EvictionPolicy) CacheManager {
+	c.eviction = policy
+	return c
+}
+
+// Evict removes entries, and the files they point at, that fall outside of the
+// given policy, evicting the least recently used entries first. It returns the
+// keys of the evicted entries.
+func (c *CacheManager) Evict(policy EvictionPolicy) ([]string, error) {
+	if c.Cache == nil {
+		return nil, errors.New("the cache manager is not loaded properly")
+	}
+
+	var evicted []string
+	err := c.update(func(db CacheDB) error {
+		var err error
+		evicted, err = evict(db, policy, time.Now())
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return evicted, nil
+}
+
+// touch records that key was accessed. Access times are persisted the next
+// time the index is written.
+func (c CacheManager) touch(key string) {
+	c.accessed.mutex.Lock()
+	c.accessed.times[key] = time.Now()
+	c.accessed.mutex.Unlock()
+}
+
+// applyAccessTimes copies the access times recorded since the index was last
+// written onto db. Only the access time is updated so that an entry that has
+// been replaced by another process is not overwritten.
+func (c CacheManager) applyAccessTimes(db CacheDB) {
+	c.accessed.mutex.Lock()
+	defer c.accessed.mutex.Unlock()
+
+	for key, accessed := range c.accessed.times {
+		entry, ok := db[key]
+		if ok && accessed.After(entry.LastAccessed) {
+			entry.LastAccessed = accessed
+			db[key] = entry
+		}
+	}
+}
+
+// accessedSinceWrite reports whether any entry has been accessed since the
+// index was last written.
+func (c CacheManager) accessedSinceWrite() bool {
+	c.accessed.mutex.Lock()
+	defer c.accessed.mutex.Unlock()
+
+	return len(c.accessed.times) > 0
+}
+
+func (c CacheManager) clearAccessTimes() {
+	c.accessed.mutex.Lock()
+	defer c.accessed.mutex.Unlock()
+
+	for key := range c.accessed.times {
+		delete(c.accessed.times, key)
+	}
+}
+
+type evictionCandidate struct {
+	key      string
+	entry    CacheEntry
+	accessed time.Time
+	size     int64
+}
+
+func evict(db CacheDB, policy EvictionPolicy, now time.Time) ([]string, error) {
+	var candidates []evictionCandidate
+	evicted := map[string]bool{}
+
+	for key, entry := range db {
+		info, err := os.Stat(entry.URI)
+		if err != nil {
+			//An entry without a file is of no use to anyone
+			if errors.Is(err, os.ErrNotExist) {
+				evicted[key] = true
+				continue
+			}
+			return nil, err
+		}
+
+		//Entries that have never been read since they were written were last
+		//accessed when they were written
+		accessed := entry.LastAccessed
+		if info.ModTime().After(accessed) {
+			accessed = info.ModTime()
+		}
+
+		candidates = append(candidates, evictionCandidate{
+			key:      key,
+			entry:    entry,
+			accessed: accessed,
+			size:     info.Size(),
+		})
+	}
+
+	//Most recently used first
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].accessed.Equal(candidates[j].accessed) {
+			return candidates[i].key < candidates[j].key
+		}
+		return candidates[i].accessed.After(candidates[j].accessed)
+	})
+
+	if policy.MaxAge > 0 {
+		for _, candidate := range candidates {
+			if now.Sub(candidate.accessed) > policy.MaxAge {
+				evicted[candidate.key] = true
+			}
+		}
+	}
+
+	if policy.MaxVersions > 0 {
+		versions := map[string]int{}
+		for _, candidate := range candidates {
+			if evicted[candidate.key] {
+				continue
+			}
+
+			group := strings.SplitN(candidate.key, "@", 2)[0]
+			versions[group]++
+			if versions[group] > policy.MaxVersions {
+				evicted[candidate.key] = true
+			}
+		}
+	}
+
+	if policy.MaxSize > 0 {
+		references := map[string]int{}
+		sizes := map[string]int64{}
+		var total int64
+		for _, candidate := range candidates {
+			if evicted[candidate.key] {
+				continue
+			}
+
+			if references[candidate.entry.URI] == 0 {
+				sizes[candidate.entry.URI] = candidate.size
+				total += candidate.size
+			}
+			references[candidate.entry.URI]++
+		}
+
+		for i := len(candidates) - 1; i >= 0 && total > policy.MaxSize; i-- {
+			candidate := candidates[i]
+			if evicted[candidate.key] {
+				continue
+			}
+
+			evicted[candidate.key] = true
+			references[candidate.entry.URI]--
+			if references[candidate.entry.URI] == 0 {
+				total -= sizes[candidate.entry.URI]
+			}
+		}
+	}
+
+	var keys []string
+	for key := range evicted {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	removed := map[string]CacheEntry{}
+	for _, key := range keys {
+		removed[key] = db[key]
+		delete(db, key)
+	}
+
+	for _, entry := range removed {
+		if db.referenced("", entry.URI) {
+			continue
+		}
+
+		err := os.RemoveAll(entry.URI)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return keys, nil
+}
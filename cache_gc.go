@@ -0,0 +1,260 @@
+package freezer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// GCOptions controls how CacheManager.GC reconciles the cache directory with
+// the index.
+type GCOptions struct {
+	// DryRun reports what would be removed or adopted without changing
+	// anything.
+	DryRun bool
+
+	// Adopt adds unreferenced buildpacks found in the cache directory back
+	// into the index, provided their location identifies a key that has no
+	// usable entry, rather than removing them.
+	Adopt bool
+
+	// MinAge protects files and directories modified more recently than the
+	// given duration. It should be set when GC can run while another process
+	// is fetching into the same cache directory, as a buildpack that is still
+	// being packaged is not yet part of the index.
+	MinAge time.Duration
+}
+
+// GCReport describes the changes made, or that would be made in a dry run,
+// by CacheManager.GC.
+type GCReport struct {
+	// Removed lists the paths of orphaned files and directories.
+	Removed []string
+
+	// Adopted lists the keys of entries that were recovered from orphaned
+	// files.
+	Adopted []string
+
+	// Dropped lists the keys of entries whose file no longer exists.
+	Dropped []string
+
+	// ReclaimedBytes is the total size of the removed files.
+	ReclaimedBytes int64
+}
+
+// GC reconciles the contents of the cache directory with the index. Files
+// written by freezer that no entry refers to, such as the packages and
+// partial downloads left behind by an interrupted run or a lost index, are
+// removed or, if requested, adopted, and entries whose file is missing are
+// dropped. Any other file is left alone, as are the directories containing
+// them, so that the cache directory can be shared with other tools.
+func (c *CacheManager) GC(options GCOptions) (GCReport, error) {
+	if c.Cache == nil {
+		return GCReport{}, errors.New("the cache manager is not loaded properly")
+	}
+
+	apply := c.update
+	if options.DryRun {
+		apply = c.view
+	}
+
+	var report GCReport
+	err := apply(func(db CacheDB) error {
+		var err error
+		report, err = c.gc(db, options, time.Now())
+		return err
+	})
+	if err != nil {
+		return GCReport{}, err
+	}
+
+	return report, nil
+}
+
+func (c CacheManager) gc(db CacheDB, options GCOptions, now time.Time) (GCReport, error) {
+	var report GCReport
+
+	referenced := map[string]bool{}
+	dangling := map[string]bool{}
+	for key, entry := range db {
+		_, err := os.Stat(entry.URI)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				return GCReport{}, err
+			}
+
+			dangling[key] = true
+			continue
+		}
+
+		referenced[filepath.Clean(entry.URI)] = true
+	}
+
+	var (
+		candidates []layoutEntry
+		dirs       []string
+		kept       []string
+		sizes      = map[string]int64{}
+	)
+
+	err := filepath.Walk(c.cacheDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if path == c.cacheDir {
+			return nil
+		}
+
+		recent := now.Sub(info.ModTime()) < options.MinAge
+
+		if info.IsDir() {
+			if path == filepath.Join(c.cacheDir, keyLocksDir) {
+				return filepath.SkipDir
+			}
+
+			dirs = append(dirs, path)
+			if recent {
+				kept = append(kept, path)
+			}
+
+			return nil
+		}
+
+		if referenced[path] || recent || !c.owned(path) {
+			kept = append(kept, path)
+			return nil
+		}
+
+		sizes[path] = info.Size()
+		candidates = append(candidates, layoutEntry{
+			entry:   CacheEntry{URI: path},
+			modTime: info.ModTime().UnixNano(),
+		})
+
+		return nil
+	})
+	if err != nil {
+		return GCReport{}, err
+	}
+
+	adopted := map[string]layoutEntry{}
+	if options.Adopt {
+		for _, candidate := range candidates {
+			entry, ok := parseLayout(c.cacheDir, candidate.entry.URI)
+			if !ok {
+				continue
+			}
+			entry.modTime = candidate.modTime
+
+			_, exists := db[entry.key]
+			if exists && !dangling[entry.key] {
+				continue
+			}
+
+			existing, ok := adopted[entry.key]
+			if !ok || newerLayoutEntry(entry, existing) {
+				adopted[entry.key] = entry
+			}
+		}
+	}
+
+	for key, entry := range adopted {
+		kept = append(kept, entry.entry.URI)
+		delete(sizes, entry.entry.URI)
+
+		if !options.DryRun {
+			entry.entry.Digest, entry.entry.Size, err = fileDigest(entry.entry.URI)
+			if err != nil {
+				return GCReport{}, err
+			}
+			db[key] = entry.entry
+		}
+
+		report.Adopted = append(report.Adopted, key)
+		delete(dangling, key)
+	}
+
+	removed := map[string]bool{}
+	for path := range sizes {
+		removed[path] = true
+	}
+
+	//A directory is only removed if it is left empty by removing the files
+	//within it
+	var removedFiles []string
+	for path := range removed {
+		removedFiles = append(removedFiles, path)
+	}
+
+	for _, dir := range dirs {
+		if containsUnder(removedFiles, dir) && !containsUnder(kept, dir) {
+			removed[dir] = true
+		}
+	}
+
+	for path := range removed {
+		report.Removed = append(report.Removed, path)
+		report.ReclaimedBytes += sizes[path]
+	}
+	sort.Strings(report.Removed)
+
+	for key := range dangling {
+		report.Dropped = append(report.Dropped, key)
+		if !options.DryRun {
+			delete(db, key)
+		}
+	}
+
+	sort.Strings(report.Adopted)
+	sort.Strings(report.Dropped)
+
+	if options.DryRun {
+		return report, nil
+	}
+
+	//Removing in reverse order removes the contents of a directory before the
+	//directory itself
+	for i := len(report.Removed) - 1; i >= 0; i-- {
+		err = os.Remove(report.Removed[i])
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return GCReport{}, err
+		}
+	}
+
+	return report, nil
+}
+
+// owned reports whether the file at path is one that freezer writes to the
+// cache directory: a packaged buildpack, a partial download or a temporary
+// copy of the index. The index itself and its lock are never removed.
+func (c CacheManager) owned(path string) bool {
+	name := filepath.Base(path)
+
+	if filepath.Dir(path) == c.cacheDir && strings.HasPrefix(name, cacheIndexFile+".") && strings.HasSuffix(name, ".tmp") {
+		return true
+	}
+
+	switch {
+	case filepath.Ext(name) == ".cnb",
+		strings.HasPrefix(name, ".download."),
+		strings.HasPrefix(name, ".") && strings.Contains(name, ".cnb."):
+		return true
+	}
+
+	return false
+}
+
+// containsUnder reports whether any of paths is dir or lies within it.
+func containsUnder(paths []string, dir string) bool {
+	for _, path := range paths {
+		if path == dir || strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,118 @@
+package freezer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// layoutEntry describes the cache entry implied by the location of a .cnb
+// file within the cache directory.
+type layoutEntry struct {
+	key     string
+	entry   CacheEntry
+	modTime int64
+}
+
+// parseLayout determines which cache entry a .cnb file would belong to based
+// on the directory layout used by the fetchers:
+//
+//	<name>/[cached/]<name>-<ulid>.cnb                  local buildpacks
+//	<org>/<repo>/<platform>/<arch>/[cached/]<tag>.cnb  remote buildpacks
+//
+// Pinned and constrained remote versions share their directory with the
+// latest release and so are always attributed to the latest key.
+func parseLayout(cacheDir, path string) (layoutEntry, bool) {
+	if filepath.Ext(path) != ".cnb" {
+		return layoutEntry{}, false
+	}
+
+	rel, err := filepath.Rel(cacheDir, path)
+	if err != nil {
+		return layoutEntry{}, false
+	}
+
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	cached := len(parts) > 2 && parts[len(parts)-2] == "cached"
+	if cached {
+		parts = append(parts[:len(parts)-2], parts[len(parts)-1])
+	}
+
+	var entry layoutEntry
+	switch len(parts) {
+	case 2:
+		entry.key = parts[0]
+		entry.entry.Version = "testing"
+	case 5:
+		entry.key = strings.Join(parts[:4], ":")
+		entry.entry.Version = strings.TrimSuffix(parts[4], ".cnb")
+	default:
+		return layoutEntry{}, false
+	}
+
+	if cached {
+		entry.key += ":cached"
+	}
+
+	entry.entry.URI = path
+
+	return entry, true
+}
+
+// rebuild reconstructs an index from the .cnb files found in the cache
+// directory. When several files map to the same key the highest version of a
+// remote buildpack or the most recently packaged local buildpack is kept.
+func (c CacheManager) rebuild() (CacheDB, error) {
+	found := map[string]layoutEntry{}
+
+	err := filepath.Walk(c.cacheDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if path == filepath.Join(c.cacheDir, keyLocksDir) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		entry, ok := parseLayout(c.cacheDir, path)
+		if !ok {
+			return nil
+		}
+		entry.modTime = info.ModTime().UnixNano()
+
+		if existing, ok := found[entry.key]; !ok || newerLayoutEntry(entry, existing) {
+			found[entry.key] = entry
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	db := CacheDB{}
+	for key, entry := range found {
+		db[key] = entry.entry
+	}
+
+	return db, nil
+}
+
+func newerLayoutEntry(entry, existing layoutEntry) bool {
+	version, err := semver.NewVersion(entry.entry.Version)
+	if err != nil {
+		return entry.modTime > existing.modTime
+	}
+
+	existingVersion, err := semver.NewVersion(existing.entry.Version)
+	if err != nil {
+		return entry.modTime > existing.modTime
+	}
+
+	return version.GreaterThan(existingVersion)
+}
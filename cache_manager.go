@@ -1,115 +1,778 @@
 package freezer
 
 import (
+	"crypto/sha256"
 	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sync"
+	"time"
 )
 
+const (
+	cacheIndexFile    = "buildpacks-cache.json"
+	legacyCacheDBFile = "buildpacks-cache.db"
+	cacheLockFile     = "buildpacks-cache.lock"
+	keyLocksDir       = "locks"
+
+	// cacheIndexSchemaVersion is incremented whenever the layout of the index
+	// changes in a way that requires older indexes to be migrated.
+	cacheIndexSchemaVersion = 1
+)
+
+// ErrNewerCacheIndex is matched, with errors.Is, by the errors returned when
+// the cache would have to be changed but its index was written by a newer
+// version of freezer. Such an index is only read, as writing it would drop
+// whatever the newer version recorded in it.
+var ErrNewerCacheIndex = errors.New("cache index was written by a newer version of freezer")
+
+// CacheManager maintains the index of buildpacks stored in the cache
+// directory. It is safe for concurrent use and multiple processes may share
+// the same cache directory: every change to the index is written through to
+// disk while holding an advisory lock on the directory, so an interrupted run
+// loses at most the entry it was writing.
 type CacheManager struct {
 	Cache CacheDB
 
 	cacheDir string
-	dbFile   *os.File
+
+	// loaded is the index as it was last read from or written to disk. It is
+	// used to detect changes made directly to Cache that still need to be
+	// persisted.
+	loaded CacheDB
+
+	verification VerificationMode
+	verified     *verifiedDigests
+
+	eviction EvictionPolicy
+	accessed *accessTimes
+
+	mutex    *sync.RWMutex
+	keyLocks *keyLocks
 }
 
+// VerificationMode controls how thoroughly Get checks that a cached file still
+// matches the digest recorded when it was stored.
+type VerificationMode int
+
+const (
+	// VerifyOnce checks the digest of an entry the first time it is retrieved
+	// by a CacheManager and only its size afterwards.
+	VerifyOnce VerificationMode = iota
+
+	// VerifyAlways checks the digest of an entry every time it is retrieved.
+	VerifyAlways
+
+	// VerifySize only checks that the size of the file matches.
+	VerifySize
+)
+
 type CacheDB map[string]CacheEntry
 
 type CacheEntry struct {
-	Version string
-	URI     string
+	Version string `json:"version"`
+	URI     string `json:"uri"`
+
+	// Digest and Size describe the file at URI when the entry was stored.
+	// Digest has the form sha256:<hex>.
+	Digest string `json:"digest,omitempty"`
+	Size   int64  `json:"size,omitempty"`
+
+	// SourceDigest identifies the source a locally packaged buildpack was
+	// built from. It is empty for remote buildpacks.
+	SourceDigest string `json:"source_digest,omitempty"`
+
+	// ETag and LastModified are the validators of the release metadata the
+	// entry was fetched with, which allow later fetches to ask GitHub whether
+	// the release has changed without downloading it again.
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+
+	// ImageDigest is the digest of the manifest, or index, the reference of a
+	// buildpack pulled from a registry resolved to. It is empty for buildpacks
+	// fetched from anywhere else.
+	ImageDigest string `json:"image_digest,omitempty"`
+
+	// LastAccessed is the last time the entry was retrieved from the cache. It
+	// is zero for entries that have not been retrieved since they were stored.
+	LastAccessed time.Time `json:"last_accessed"`
+
+	// CheckedAt is the last time GitHub confirmed that the entry holds the
+	// latest release. It is zero when that has never been checked or when no
+	// freshness window was in use at the time.
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// cacheIndex is the on-disk representation of the cache. Unknown fields are
+// ignored when it is decoded so that indexes written by newer versions of
+// freezer can still be read.
+type cacheIndex struct {
+	SchemaVersion int     `json:"schema_version"`
+	Entries       CacheDB `json:"entries"`
+}
+
+type verifiedDigests struct {
+	mutex   sync.Mutex
+	digests map[string]string
+}
+
+type keyLocks struct {
+	mutex   sync.Mutex
+	locks   map[string]*sync.Mutex
+	unlocks map[string]func() error
 }
 
 func NewCacheManager(cacheDir string) CacheManager {
 	return CacheManager{
 		cacheDir: cacheDir,
+		loaded:   CacheDB{},
+		verified: &verifiedDigests{
+			digests: map[string]string{},
+		},
+		accessed: &accessTimes{
+			times: map[string]time.Time{},
+		},
+		mutex: &sync.RWMutex{},
+		keyLocks: &keyLocks{
+			locks:   map[string]*sync.Mutex{},
+			unlocks: map[string]func() error{},
+		},
 	}
 }
 
+func (c CacheManager) WithVerification(verification VerificationMode) CacheManager {
+	c.verification = verification
+	return c
+}
+
+// Open loads the index from the cache directory, creating both if they do not
+// exist yet, and applies the eviction policy if one is configured.
 func (c *CacheManager) Open() error {
-	var err error
-	_, err = os.Stat(filepath.Join(c.cacheDir, "buildpacks-cache.db"))
+	err := os.MkdirAll(c.cacheDir, os.ModePerm)
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			err = os.MkdirAll(c.cacheDir, os.ModePerm)
+		return err
+	}
+
+	err = c.open()
+	if err != nil {
+		return err
+	}
+
+	if c.eviction != (EvictionPolicy{}) {
+		_, err = c.Evict(c.eviction)
+		//An index that is only read is left for the newer version to evict from
+		if err != nil && !errors.Is(err, ErrNewerCacheIndex) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *CacheManager) open() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	unlock, err := lockFile(filepath.Join(c.cacheDir, cacheLockFile))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	db, schemaVersion, err := c.load()
+	if err != nil {
+		return err
+	}
+
+	//Writing the index straight away creates it on first use and persists it
+	//if it had to be rebuilt or migrated from the legacy format
+	if schemaVersion < cacheIndexSchemaVersion {
+		err = c.write(db)
+		if err != nil {
+			return err
+		}
+
+		err = os.Remove(filepath.Join(c.cacheDir, legacyCacheDBFile))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	c.Cache = CacheDB{}
+	c.replace(db, db)
+
+	return nil
+}
+
+// Close persists any changes that were made directly to Cache along with the
+// access times of entries and applies the eviction policy if one is
+// configured. Changes made through Set are persisted immediately. The index is
+// not written when there is nothing to persist.
+func (c CacheManager) Close() error {
+	if c.Cache == nil {
+		return errors.New("the cache manager is not loaded properly")
+	}
+
+	if c.eviction == (EvictionPolicy{}) && !c.modified() && !c.accessedSinceWrite() {
+		return nil
+	}
+
+	err := c.update(func(db CacheDB) error {
+		if c.eviction == (EvictionPolicy{}) {
+			return nil
+		}
+
+		_, err := evict(db, c.eviction, time.Now())
+		return err
+	})
+
+	//Access times alone are not worth failing for when the index can only be
+	//read
+	if errors.Is(err, ErrNewerCacheIndex) && !c.modified() {
+		c.clearAccessTimes()
+		return nil
+	}
+
+	return err
+}
+
+// EntryStatus describes the file of a cache entry as checked against the size
+// and digest recorded when it was stored.
+type EntryStatus string
+
+const (
+	// EntryOK means that the file matches its recorded size and digest.
+	EntryOK EntryStatus = "ok"
+
+	// EntryMissing means that the file no longer exists.
+	EntryMissing EntryStatus = "missing"
+
+	// EntryCorrupt means that the file no longer matches its recorded size or
+	// digest.
+	EntryCorrupt EntryStatus = "corrupt"
+
+	// EntryUnverified means that the file exists but, as the entry was stored
+	// before digests were recorded, cannot be checked.
+	EntryUnverified EntryStatus = "unverified"
+)
+
+// Get returns the entry stored under key. An entry whose file no longer exists
+// or no longer matches the recorded size and digest is returned as not ok so
+// that callers will fetch it again.
+func (c CacheManager) Get(key string) (CacheEntry, bool, error) {
+	c.mutex.RLock()
+	entry, ok := c.Cache[key]
+	c.mutex.RUnlock()
+
+	if !ok {
+		return entry, false, nil
+	}
+
+	status, err := c.status(key, entry, c.verification)
+	if err != nil {
+		return CacheEntry{}, false, err
+	}
+
+	if status == EntryMissing || status == EntryCorrupt {
+		return entry, false, nil
+	}
+
+	c.touch(key)
+
+	return entry, true, nil
+}
+
+// Verify checks the file of the entry stored under key against the size and
+// digest recorded when it was stored, always reading the whole file whatever
+// the verification mode. It returns false if there is no entry for key.
+func (c CacheManager) Verify(key string) (EntryStatus, bool, error) {
+	c.mutex.RLock()
+	entry, ok := c.Cache[key]
+	c.mutex.RUnlock()
+
+	if !ok {
+		return "", false, nil
+	}
+
+	status, err := c.status(key, entry, VerifyAlways)
+	if err != nil {
+		return "", true, err
+	}
+
+	return status, true, nil
+}
+
+// status checks the file of entry, which is stored under key, verifying its
+// digest as the given mode requires.
+func (c CacheManager) status(key string, entry CacheEntry, mode VerificationMode) (EntryStatus, error) {
+	info, err := os.Stat(entry.URI)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return EntryMissing, nil
+		}
+		return "", err
+	}
+
+	//Entries stored before digests were recorded cannot be verified
+	if entry.Digest == "" {
+		return EntryUnverified, nil
+	}
+
+	if info.Size() != entry.Size {
+		return EntryCorrupt, nil
+	}
+
+	valid, err := c.verify(key, entry, mode)
+	if err != nil {
+		return "", err
+	}
+
+	if !valid {
+		return EntryCorrupt, nil
+	}
+
+	return EntryOK, nil
+}
+
+func (c CacheManager) verify(key string, entry CacheEntry, mode VerificationMode) (bool, error) {
+	if mode == VerifySize {
+		return true, nil
+	}
+
+	c.verified.mutex.Lock()
+	verified := c.verified.digests[key] == entry.Digest
+	c.verified.mutex.Unlock()
+
+	if verified && mode == VerifyOnce {
+		return true, nil
+	}
+
+	digest, _, err := fileDigest(entry.URI)
+	if err != nil {
+		return false, err
+	}
+
+	if digest != entry.Digest {
+		return false, nil
+	}
+
+	c.verified.mutex.Lock()
+	c.verified.digests[key] = digest
+	c.verified.mutex.Unlock()
+
+	return true, nil
+}
+
+// Set stores value under key, removing the file of the entry it replaces. If
+// value has no digest one is computed from the file at its URI.
+func (c *CacheManager) Set(key string, value CacheEntry) error {
+	if c.Cache == nil {
+		return errors.New("the cache manager is not loaded properly")
+	}
+
+	if value.Digest == "" {
+		info, err := os.Stat(value.URI)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
+		if err == nil && info.Mode().IsRegular() {
+			value.Digest, value.Size, err = fileDigest(value.URI)
 			if err != nil {
 				return err
 			}
-			c.dbFile, err = os.Create(filepath.Join(c.cacheDir, "buildpacks-cache.db"))
+		}
+	}
+
+	return c.update(func(db CacheDB) error {
+		//os.RemoveAll of a empty string is a noop if the entry does not exist then it will
+		//return and empty string
+		previousURI := db[key].URI
+		if previousURI != value.URI && !db.referenced(key, previousURI) {
+			err := os.RemoveAll(previousURI)
 			if err != nil {
 				return err
 			}
-			c.Cache = CacheDB{}
+		}
+
+		db[key] = value
+
+		return nil
+	})
+}
+
+// Delete removes the entry stored under key along with its file, unless the
+// file is shared with another entry. It reports whether an entry existed.
+func (c *CacheManager) Delete(key string) (bool, error) {
+	if c.Cache == nil {
+		return false, errors.New("the cache manager is not loaded properly")
+	}
+
+	var ok bool
+	err := c.update(func(db CacheDB) error {
+		var entry CacheEntry
+		entry, ok = db[key]
+		if !ok {
 			return nil
 		}
+
+		delete(db, key)
+
+		if db.referenced(key, entry.URI) {
+			return nil
+		}
+
+		return os.RemoveAll(entry.URI)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return ok, nil
+}
+
+// Lock acquires an exclusive lock on key that is held against both other
+// goroutines and other processes sharing the cache directory, blocking until
+// it is available. Holding the lock across a Get and the matching Set prevents
+// the same buildpack from being fetched twice; unrelated keys do not contend.
+// Lock is not reentrant and every call must be paired with a call to Unlock.
+func (c *CacheManager) Lock(key string) error {
+	c.keyLocks.mutex.Lock()
+	mutex, ok := c.keyLocks.locks[key]
+	if !ok {
+		mutex = &sync.Mutex{}
+		c.keyLocks.locks[key] = mutex
+	}
+	c.keyLocks.mutex.Unlock()
+
+	mutex.Lock()
+
+	err := os.MkdirAll(filepath.Join(c.cacheDir, keyLocksDir), os.ModePerm)
+	if err != nil {
+		mutex.Unlock()
 		return err
 	}
 
-	loadFile, err := os.Open(filepath.Join(c.cacheDir, "buildpacks-cache.db"))
+	sum := sha256.Sum256([]byte(key))
+	unlock, err := lockFile(filepath.Join(c.cacheDir, keyLocksDir, fmt.Sprintf("%s.lock", hex.EncodeToString(sum[:8]))))
+	if err != nil {
+		mutex.Unlock()
+		return err
+	}
+
+	c.keyLocks.mutex.Lock()
+	c.keyLocks.unlocks[key] = unlock
+	c.keyLocks.mutex.Unlock()
+
+	//Another process may have fetched the buildpack while we were waiting
+	err = c.refresh()
 	if err != nil {
+		c.Unlock(key)
 		return err
 	}
 
-	err = gob.NewDecoder(loadFile).Decode(&c.Cache)
+	return nil
+}
+
+func (c *CacheManager) Unlock(key string) error {
+	c.keyLocks.mutex.Lock()
+	unlock, ok := c.keyLocks.unlocks[key]
+	delete(c.keyLocks.unlocks, key)
+	mutex := c.keyLocks.locks[key]
+	c.keyLocks.mutex.Unlock()
+
+	if !ok {
+		return fmt.Errorf("key %q is not locked", key)
+	}
+	defer mutex.Unlock()
+
+	return unlock()
+}
+
+func (c CacheManager) Dir() string {
+	return c.cacheDir
+}
+
+// refresh reloads the index from disk so that entries written by other
+// processes become visible, keeping any local changes that are not yet
+// persisted.
+func (c *CacheManager) refresh() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	unlock, err := lockFile(filepath.Join(c.cacheDir, cacheLockFile))
 	if err != nil {
 		return err
 	}
+	defer unlock()
 
-	c.dbFile, err = os.OpenFile(filepath.Join(c.cacheDir, "buildpacks-cache.db"), os.O_WRONLY|os.O_TRUNC, 0666)
+	db, _, err := c.load()
 	if err != nil {
 		return err
 	}
 
+	merged := db.copy()
+	c.merge(merged)
+	c.replace(merged, db)
+
 	return nil
 }
 
-func (c CacheManager) Close() error {
-	err := gob.NewEncoder(c.dbFile).Encode(&c.Cache)
+// update applies mutate to the latest on-disk index, including any local
+// changes, and writes the result back while holding the directory lock.
+func (c CacheManager) update(mutate func(db CacheDB) error) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	unlock, err := lockFile(filepath.Join(c.cacheDir, cacheLockFile))
 	if err != nil {
 		return err
 	}
-	defer c.dbFile.Close()
+	defer unlock()
+
+	db, schemaVersion, err := c.load()
+	if err != nil {
+		return err
+	}
+
+	err = checkSchemaVersion(schemaVersion)
+	if err != nil {
+		return err
+	}
+
+	c.merge(db)
+	c.applyAccessTimes(db)
+
+	err = mutate(db)
+	if err != nil {
+		return err
+	}
+
+	err = c.write(db)
+	if err != nil {
+		return err
+	}
+
+	c.clearAccessTimes()
+	c.replace(db, db)
 
 	return nil
 }
 
-//This function exists for two reasons  one is so that is could have a standard
-//getter setter interface and the setter is a more complex function the other is
-//to allow for table locking if this were to be adapted for parallel package management
-func (c CacheManager) Get(key string) (CacheEntry, bool, error) {
-	entry, ok := c.Cache[key]
+// view applies inspect to the latest on-disk index, including any local
+// changes, while holding the directory lock. Unlike update it never writes the
+// index, so it can be used on an index written by a newer version of freezer.
+func (c CacheManager) view(inspect func(db CacheDB) error) error {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
 
-	if ok {
-		_, err := os.Stat(entry.URI)
-		if err != nil {
-			if os.IsNotExist(err) {
-				return entry, !ok, nil
-			}
-			return CacheEntry{}, !ok, err
+	unlock, err := lockFile(filepath.Join(c.cacheDir, cacheLockFile))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	db, _, err := c.load()
+	if err != nil {
+		return err
+	}
+
+	c.merge(db)
+
+	return inspect(db)
+}
+
+// merge applies the changes made to Cache since it was last synchronised with
+// disk onto db.
+func (c CacheManager) merge(db CacheDB) {
+	for key, entry := range c.Cache {
+		if loaded, ok := c.loaded[key]; !ok || loaded != entry {
+			db[key] = entry
 		}
 	}
 
-	return entry, ok, nil
+	for key := range c.loaded {
+		if _, ok := c.Cache[key]; !ok {
+			delete(db, key)
+		}
+	}
 }
 
-func (c *CacheManager) Set(key string, value CacheEntry) error {
-	//os.RemoveAll of a empty string is a noop if the entry does not exist then it will
-	//return and empty string
-	err := os.RemoveAll(c.Cache[key].URI)
+// modified reports whether changes have been made directly to Cache since it
+// was last synchronised with disk.
+func (c CacheManager) modified() bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if len(c.Cache) != len(c.loaded) {
+		return true
+	}
+
+	for key, entry := range c.Cache {
+		if loaded, ok := c.loaded[key]; !ok || loaded != entry {
+			return true
+		}
+	}
+
+	return false
+}
+
+// replace sets the contents of Cache and loaded. Both maps are updated in
+// place so that copies of the CacheManager observe the change.
+func (c CacheManager) replace(cache, loaded CacheDB) {
+	for key := range c.Cache {
+		delete(c.Cache, key)
+	}
+
+	for key, entry := range cache {
+		c.Cache[key] = entry
+	}
+
+	for key := range c.loaded {
+		delete(c.loaded, key)
+	}
+
+	for key, entry := range loaded {
+		c.loaded[key] = entry
+	}
+}
+
+// load reads the index from disk, migrating it from the legacy gob encoded
+// database if no index exists yet. An index that cannot be decoded, for
+// example because it was edited by hand, is rebuilt from the files present in
+// the cache directory rather than discarded. It also returns the schema
+// version of the index on disk, which is zero when there is none yet or it was
+// migrated or rebuilt and so has to be written.
+func (c CacheManager) load() (CacheDB, int, error) {
+	content, err := os.ReadFile(filepath.Join(c.cacheDir, cacheIndexFile))
 	if err != nil {
-		return err
+		if errors.Is(err, os.ErrNotExist) {
+			db, err := c.loadLegacy()
+			return db, 0, err
+		}
+		return nil, 0, err
 	}
 
-	if c.Cache == nil {
-		return errors.New("the cache manager is not loaded properly")
+	var index cacheIndex
+	err = json.Unmarshal(content, &index)
+	if err != nil {
+		db, err := c.rebuild()
+		return db, 0, err
 	}
 
-	c.Cache[key] = value
+	if index.Entries == nil {
+		index.Entries = CacheDB{}
+	}
+
+	return index.Entries, index.SchemaVersion, nil
+}
+
+// checkSchemaVersion returns an error if an index with the given schema
+// version must not be written.
+func checkSchemaVersion(schemaVersion int) error {
+	if schemaVersion > cacheIndexSchemaVersion {
+		return fmt.Errorf("%w: it has schema version %d but this version of freezer only supports up to %d", ErrNewerCacheIndex, schemaVersion, cacheIndexSchemaVersion)
+	}
 
 	return nil
 }
 
-func (c CacheManager) Dir() string {
-	return c.cacheDir
+func (c CacheManager) loadLegacy() (CacheDB, error) {
+	db := CacheDB{}
+
+	file, err := os.Open(filepath.Join(c.cacheDir, legacyCacheDBFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return db, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	err = gob.NewDecoder(file).Decode(&db)
+	if err != nil {
+		return c.rebuild()
+	}
+
+	return db, nil
+}
+
+// write atomically replaces the index on disk by writing it to a temporary
+// file in the cache directory and renaming it into place, so that the index
+// is never observed half written.
+func (c CacheManager) write(db CacheDB) error {
+	content, err := json.MarshalIndent(cacheIndex{
+		SchemaVersion: cacheIndexSchemaVersion,
+		Entries:       db,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(c.cacheDir, fmt.Sprintf("%s.*.tmp", cacheIndexFile))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	_, err = file.Write(append(content, '\n'))
+	if err != nil {
+		return err
+	}
+
+	err = file.Sync()
+	if err != nil {
+		return err
+	}
+
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), filepath.Join(c.cacheDir, cacheIndexFile))
+}
+
+// referenced reports whether any entry other than the one stored under key
+// points at uri, as is the case when a pinned version is also the latest.
+func (db CacheDB) referenced(key, uri string) bool {
+	for k, entry := range db {
+		if k != key && entry.URI == uri {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (db CacheDB) copy() CacheDB {
+	c := CacheDB{}
+	for key, entry := range db {
+		c[key] = entry
+	}
+
+	return c
+}
+
+// fileDigest returns the SHA-256 digest and size of the file at path.
+func fileDigest(path string) (string, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return fmt.Sprintf("sha256:%s", hex.EncodeToString(hash.Sum(nil))), size, nil
 }
@@ -3,9 +3,13 @@ package freezer_test
 import (
 	"bytes"
 	"encoding/gob"
+	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/ForestEckhardt/freezer"
 	"github.com/sclevine/spec"
@@ -35,7 +39,85 @@ func testCacheManager(t *testing.T, context spec.G, it spec.S) {
 	})
 
 	context("Open", func() {
-		context("when Open is called on the cache manager and there is a buildpacks-cache.db file present", func() {
+		context("when Open is called on the cache manager and there is a buildpacks-cache.json file present", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(cacheDir, "buildpacks-cache.json"), []byte(`{
+  "schema_version": 1,
+  "entries": {
+    "buildpack": {
+      "version": "1.2.3",
+      "uri": "some-uri",
+      "some-future-field": "some-value"
+    }
+  },
+  "some-future-field": "some-value"
+}`), os.ModePerm)).To(Succeed())
+			})
+
+			it("returns the cache map stored in the buildpacks-cache.json file ignoring unknown fields", func() {
+				err := cacheManager.Open()
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(cacheManager.Cache).To(Equal(freezer.CacheDB{"buildpack": freezer.CacheEntry{Version: "1.2.3", URI: "some-uri"}}))
+			})
+
+			it("leaves the index as it is", func() {
+				Expect(cacheManager.Open()).To(Succeed())
+
+				content, err := os.ReadFile(filepath.Join(cacheDir, "buildpacks-cache.json"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(content)).To(ContainSubstring(`"some-future-field": "some-value"`))
+			})
+		})
+
+		context("when the buildpacks-cache.json file was written by a newer version of freezer", func() {
+			var index string
+
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(cacheDir, "some-buildpack.cnb"), []byte("some-content"), 0644)).To(Succeed())
+
+				index = fmt.Sprintf(`{
+  "schema_version": 2,
+  "entries": {
+    "buildpack": {
+      "version": "1.2.3",
+      "uri": %q,
+      "some-future-field": "some-value"
+    }
+  }
+}`, filepath.Join(cacheDir, "some-buildpack.cnb"))
+				Expect(os.WriteFile(filepath.Join(cacheDir, "buildpacks-cache.json"), []byte(index), 0644)).To(Succeed())
+			})
+
+			it("reads it without writing to it", func() {
+				Expect(cacheManager.Open()).To(Succeed())
+
+				entry, ok, err := cacheManager.Get("buildpack")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ok).To(BeTrue())
+				Expect(entry.Version).To(Equal("1.2.3"))
+
+				Expect(cacheManager.Close()).To(Succeed())
+
+				content, err := os.ReadFile(filepath.Join(cacheDir, "buildpacks-cache.json"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(content)).To(Equal(index))
+			})
+
+			it("refuses to change it", func() {
+				Expect(cacheManager.Open()).To(Succeed())
+
+				err := cacheManager.Set("some-other-buildpack", freezer.CacheEntry{Version: "4.5.6"})
+				Expect(err).To(MatchError(freezer.ErrNewerCacheIndex))
+				Expect(err).To(MatchError(ContainSubstring("it has schema version 2 but this version of freezer only supports up to 1")))
+
+				content, err := os.ReadFile(filepath.Join(cacheDir, "buildpacks-cache.json"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(content)).To(Equal(index))
+			})
+		})
+
+		context("when Open is called on the cache manager and there is a legacy buildpacks-cache.db file present", func() {
 			var inputMap freezer.CacheDB
 
 			it.Before(func() {
@@ -48,15 +130,106 @@ func testCacheManager(t *testing.T, context spec.G, it spec.S) {
 				Expect(os.WriteFile(filepath.Join(cacheDir, "buildpacks-cache.db"), b.Bytes(), os.ModePerm))
 			})
 
-			it("returns the cache map stored in the buildpacks-cache.db folder", func() {
+			it("migrates the cache map stored in the buildpacks-cache.db file", func() {
 				err := cacheManager.Open()
 				Expect(err).ToNot(HaveOccurred())
 
 				Expect(cacheManager.Cache).To(Equal(inputMap))
+
+				Expect(filepath.Join(cacheDir, "buildpacks-cache.db")).NotTo(BeAnExistingFile())
+
+				content, err := os.ReadFile(filepath.Join(cacheDir, "buildpacks-cache.json"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(content)).To(MatchJSON(`{
+  "schema_version": 1,
+  "entries": {
+    "buildpack": {
+      "version": "1.2.3",
+      "uri": "some-uri",
+      "last_accessed": "0001-01-01T00:00:00Z",
+      "checked_at": "0001-01-01T00:00:00Z"
+    }
+  }
+}`))
+			})
+
+			context("when the legacy file cannot be decoded", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(filepath.Join(cacheDir, "buildpacks-cache.db"), []byte(`%%%`), os.ModePerm)).To(Succeed())
+				})
+
+				it("rebuilds the cache map from the contents of the cache directory", func() {
+					err := cacheManager.Open()
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(cacheManager.Cache).To(Equal(freezer.CacheDB{}))
+					Expect(filepath.Join(cacheDir, "buildpacks-cache.db")).NotTo(BeAnExistingFile())
+				})
 			})
 		})
 
-		context("when Open is called on the cache manager and there is no buildpacks-cache.db file present", func() {
+		context("when Open is called on the cache manager and there is no buildpacks-cache.json file present", func() {
+			it("returns an empty cache map", func() {
+				err := cacheManager.Open()
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(cacheManager.Cache).To(Equal(freezer.CacheDB{}))
+			})
+		})
+
+		context("when the buildpacks-cache.json file cannot be decoded", func() {
+			var (
+				remoteURI string
+				localURI  string
+			)
+
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(cacheDir, "buildpacks-cache.json"), []byte(`%%%`), os.ModePerm)).To(Succeed())
+
+				remoteDir := filepath.Join(cacheDir, "some-org", "some-repo", "linux", "amd64")
+				Expect(os.MkdirAll(filepath.Join(remoteDir, "cached"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(remoteDir, "1.2.3.cnb"), nil, 0644)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(remoteDir, "1.10.0.cnb"), nil, 0644)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(remoteDir, "cached", "1.2.3.cnb"), nil, 0644)).To(Succeed())
+				remoteURI = filepath.Join(remoteDir, "1.10.0.cnb")
+
+				localDir := filepath.Join(cacheDir, "some-buildpack")
+				Expect(os.MkdirAll(localDir, os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(localDir, "some-buildpack-old.cnb"), nil, 0644)).To(Succeed())
+				Expect(os.Chtimes(filepath.Join(localDir, "some-buildpack-old.cnb"), time.Now().Add(-time.Hour), time.Now().Add(-time.Hour))).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(localDir, "some-buildpack-new.cnb"), nil, 0644)).To(Succeed())
+				localURI = filepath.Join(localDir, "some-buildpack-new.cnb")
+
+				Expect(os.WriteFile(filepath.Join(cacheDir, "some-buildpack", "unrelated-file"), nil, 0644)).To(Succeed())
+			})
+
+			it("rebuilds the cache map from the contents of the cache directory", func() {
+				err := cacheManager.Open()
+				Expect(err).ToNot(HaveOccurred())
+
+				expected := freezer.CacheDB{
+					"some-org:some-repo:linux:amd64":        freezer.CacheEntry{Version: "1.10.0", URI: remoteURI},
+					"some-org:some-repo:linux:amd64:cached": freezer.CacheEntry{Version: "1.2.3", URI: filepath.Join(cacheDir, "some-org", "some-repo", "linux", "amd64", "cached", "1.2.3.cnb")},
+					"some-buildpack":                        freezer.CacheEntry{Version: "testing", URI: localURI},
+				}
+				Expect(cacheManager.Cache).To(Equal(expected))
+
+				var index struct {
+					Entries freezer.CacheDB `json:"entries"`
+				}
+				content, err := os.ReadFile(filepath.Join(cacheDir, "buildpacks-cache.json"))
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(json.Unmarshal(content, &index)).To(Succeed())
+				Expect(index.Entries).To(Equal(expected))
+			})
+		})
+
+		context("when the buildpacks-cache.json file is empty", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(cacheDir, "buildpacks-cache.json"), nil, os.ModePerm)).To(Succeed())
+			})
+
 			it("returns an empty cache map", func() {
 				err := cacheManager.Open()
 				Expect(err).ToNot(HaveOccurred())
@@ -66,7 +239,7 @@ func testCacheManager(t *testing.T, context spec.G, it spec.S) {
 		})
 
 		context("failure cases", func() {
-			context("the buildpacks-cache.db file is unable to be created", func() {
+			context("the buildpacks-cache.json file is unable to be created", func() {
 				it.Before(func() {
 					Expect(os.Chmod(cacheDir, 0222)).To(Succeed())
 				})
@@ -83,23 +256,13 @@ func testCacheManager(t *testing.T, context spec.G, it spec.S) {
 
 			context("unable to open the buildpack-cache.db", func() {
 				it.Before(func() {
-					Expect(os.WriteFile(filepath.Join(cacheDir, "buildpacks-cache.db"), []byte{}, 0000))
+					Expect(os.WriteFile(filepath.Join(cacheDir, "buildpacks-cache.json"), []byte{}, 0000))
 				})
 				it("returns an error", func() {
 					err := cacheManager.Open()
 					Expect(err).To(MatchError(ContainSubstring("permission denied")))
 				})
 			})
-
-			context("unable to open the buildpack-cache.db", func() {
-				it.Before(func() {
-					Expect(os.WriteFile(filepath.Join(cacheDir, "buildpacks-cache.db"), []byte(`%%%`), os.ModePerm))
-				})
-				it("returns an error", func() {
-					err := cacheManager.Open()
-					Expect(err).To(MatchError(ContainSubstring("unexpected EOF")))
-				})
-			})
 		})
 	})
 
@@ -115,14 +278,136 @@ func testCacheManager(t *testing.T, context spec.G, it spec.S) {
 				err := cacheManager.Close()
 				Expect(err).ToNot(HaveOccurred())
 
-				var cacheCheck freezer.CacheDB
-				file, err := os.Open(filepath.Join(cacheDir, "buildpacks-cache.db"))
+				var index struct {
+					SchemaVersion int             `json:"schema_version"`
+					Entries       freezer.CacheDB `json:"entries"`
+				}
+				content, err := os.ReadFile(filepath.Join(cacheDir, "buildpacks-cache.json"))
 				Expect(err).ToNot(HaveOccurred())
 
-				err = gob.NewDecoder(file).Decode(&cacheCheck)
+				err = json.Unmarshal(content, &index)
 				Expect(err).ToNot(HaveOccurred())
 
-				Expect(cacheCheck).To(Equal(cacheManager.Cache))
+				Expect(index.SchemaVersion).To(Equal(1))
+				Expect(index.Entries).To(Equal(cacheManager.Cache))
+			})
+		})
+
+		context("when nothing has changed since the cache was opened", func() {
+			var past time.Time
+
+			it.Before(func() {
+				Expect(cacheManager.Open()).To(Succeed())
+
+				past = time.Now().Add(-time.Hour).Truncate(time.Second)
+				Expect(os.Chtimes(filepath.Join(cacheDir, "buildpacks-cache.json"), past, past)).To(Succeed())
+			})
+
+			it("does not rewrite the index", func() {
+				Expect(cacheManager.Close()).To(Succeed())
+
+				info, err := os.Stat(filepath.Join(cacheDir, "buildpacks-cache.json"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(info.ModTime()).To(Equal(past))
+			})
+		})
+	})
+
+	context("when multiple cache managers share the same directory", func() {
+		var otherCacheManager freezer.CacheManager
+
+		it.Before(func() {
+			Expect(cacheManager.Open()).To(Succeed())
+
+			otherCacheManager = freezer.NewCacheManager(cacheDir)
+			Expect(otherCacheManager.Open()).To(Succeed())
+		})
+
+		it("keeps the entries set by each of them", func() {
+			Expect(cacheManager.Set("some-buildpack", freezer.CacheEntry{Version: "1.2.3", URI: "some-uri"})).To(Succeed())
+			Expect(otherCacheManager.Set("other-buildpack", freezer.CacheEntry{Version: "4.5.6", URI: "other-uri"})).To(Succeed())
+
+			Expect(otherCacheManager.Close()).To(Succeed())
+			Expect(cacheManager.Close()).To(Succeed())
+
+			cacheManager = freezer.NewCacheManager(cacheDir)
+			Expect(cacheManager.Open()).To(Succeed())
+
+			Expect(cacheManager.Cache).To(Equal(freezer.CacheDB{
+				"some-buildpack":  freezer.CacheEntry{Version: "1.2.3", URI: "some-uri"},
+				"other-buildpack": freezer.CacheEntry{Version: "4.5.6", URI: "other-uri"},
+			}))
+		})
+
+		it("sees entries set by the other once a key is locked", func() {
+			Expect(otherCacheManager.Set("other-buildpack", freezer.CacheEntry{Version: "4.5.6", URI: "other-uri"})).To(Succeed())
+			Expect(cacheManager.Cache).NotTo(HaveKey("other-buildpack"))
+
+			Expect(cacheManager.Lock("other-buildpack")).To(Succeed())
+			Expect(cacheManager.Cache).To(HaveKeyWithValue("other-buildpack", freezer.CacheEntry{Version: "4.5.6", URI: "other-uri"}))
+			Expect(cacheManager.Unlock("other-buildpack")).To(Succeed())
+		})
+	})
+
+	context("Lock", func() {
+		it.Before(func() {
+			Expect(cacheManager.Open()).To(Succeed())
+		})
+
+		it("prevents a key from being held by more than one caller at a time", func() {
+			var (
+				wg      sync.WaitGroup
+				mutex   sync.Mutex
+				holders int
+				maximum int
+			)
+
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+
+					if err := cacheManager.Lock("some-buildpack"); err != nil {
+						panic(err)
+					}
+
+					mutex.Lock()
+					holders++
+					if holders > maximum {
+						maximum = holders
+					}
+					mutex.Unlock()
+
+					time.Sleep(time.Millisecond)
+
+					mutex.Lock()
+					holders--
+					mutex.Unlock()
+
+					if err := cacheManager.Unlock("some-buildpack"); err != nil {
+						panic(err)
+					}
+				}()
+			}
+
+			wg.Wait()
+			Expect(maximum).To(Equal(1))
+		})
+
+		it("does not block on unrelated keys", func() {
+			Expect(cacheManager.Lock("some-buildpack")).To(Succeed())
+			Expect(cacheManager.Lock("other-buildpack")).To(Succeed())
+
+			Expect(cacheManager.Unlock("other-buildpack")).To(Succeed())
+			Expect(cacheManager.Unlock("some-buildpack")).To(Succeed())
+		})
+
+		context("failure cases", func() {
+			context("when unlocking a key that is not locked", func() {
+				it("returns an error", func() {
+					err := cacheManager.Unlock("some-buildpack")
+					Expect(err).To(MatchError(`key "some-buildpack" is not locked`))
+				})
 			})
 		})
 	})
@@ -162,6 +447,88 @@ func testCacheManager(t *testing.T, context spec.G, it spec.S) {
 			})
 		})
 
+		context("when the entry records a digest", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(uri, []byte(`some content`), 0644)).To(Succeed())
+				Expect(cacheManager.Set("some-buildpack", freezer.CacheEntry{Version: "1.2.3", URI: uri})).To(Succeed())
+			})
+
+			context("and the file matches", func() {
+				it("returns the entry and ok", func() {
+					entry, ok, err := cacheManager.Get("some-buildpack")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(ok).To(BeTrue())
+					Expect(entry.Digest).To(Equal("sha256:290f493c44f5d63d06b374d0a5abd292fae38b92cab2fae5efefe1b0e9347f56"))
+				})
+			})
+
+			context("and the file has been truncated", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(uri, []byte(`some`), 0644)).To(Succeed())
+				})
+
+				it("returns the entry and not ok", func() {
+					_, ok, err := cacheManager.Get("some-buildpack")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(ok).To(BeFalse())
+				})
+			})
+
+			context("and the file contents have changed", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(uri, []byte(`more content`), 0644)).To(Succeed())
+				})
+
+				it("returns the entry and not ok", func() {
+					_, ok, err := cacheManager.Get("some-buildpack")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(ok).To(BeFalse())
+				})
+			})
+
+			context("and the file contents change after it has been verified", func() {
+				it.Before(func() {
+					_, ok, err := cacheManager.Get("some-buildpack")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(ok).To(BeTrue())
+
+					Expect(os.WriteFile(uri, []byte(`more content`), 0644)).To(Succeed())
+				})
+
+				it("only checks the size of the file again", func() {
+					_, ok, err := cacheManager.Get("some-buildpack")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(ok).To(BeTrue())
+				})
+
+				context("when every retrieval is verified", func() {
+					it.Before(func() {
+						cacheManager = cacheManager.WithVerification(freezer.VerifyAlways)
+					})
+
+					it("returns the entry and not ok", func() {
+						_, ok, err := cacheManager.Get("some-buildpack")
+						Expect(err).NotTo(HaveOccurred())
+						Expect(ok).To(BeFalse())
+					})
+				})
+			})
+
+			context("when only sizes are verified", func() {
+				it.Before(func() {
+					cacheManager = cacheManager.WithVerification(freezer.VerifySize)
+
+					Expect(os.WriteFile(uri, []byte(`more content`), 0644)).To(Succeed())
+				})
+
+				it("returns the entry and ok", func() {
+					_, ok, err := cacheManager.Get("some-buildpack")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(ok).To(BeTrue())
+				})
+			})
+		})
+
 		context("when the does not key exist", func() {
 			it("returns with an empty entry and not ok", func() {
 				entry, ok, err := cacheManager.Get("some-buildpack-other")
@@ -191,6 +558,75 @@ func testCacheManager(t *testing.T, context spec.G, it spec.S) {
 		})
 	})
 
+	context("Verify", func() {
+		var uri string
+
+		it.Before(func() {
+			Expect(cacheManager.Open()).To(Succeed())
+
+			uri = filepath.Join(cacheDir, "some-uri")
+			Expect(os.WriteFile(uri, []byte(`some content`), 0644)).To(Succeed())
+			Expect(cacheManager.Set("some-buildpack", freezer.CacheEntry{Version: "1.2.3", URI: uri})).To(Succeed())
+		})
+
+		it("returns ok when the file matches its digest", func() {
+			status, ok, err := cacheManager.Verify("some-buildpack")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(status).To(Equal(freezer.EntryOK))
+		})
+
+		context("when the file has been modified without changing its size", func() {
+			it.Before(func() {
+				_, _, err := cacheManager.Get("some-buildpack")
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(os.WriteFile(uri, []byte(`some-content`), 0644)).To(Succeed())
+			})
+
+			it("returns corrupt even though the digest was verified before", func() {
+				status, ok, err := cacheManager.Verify("some-buildpack")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ok).To(BeTrue())
+				Expect(status).To(Equal(freezer.EntryCorrupt))
+			})
+		})
+
+		context("when the file is missing", func() {
+			it.Before(func() {
+				Expect(os.Remove(uri)).To(Succeed())
+			})
+
+			it("returns missing", func() {
+				status, ok, err := cacheManager.Verify("some-buildpack")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ok).To(BeTrue())
+				Expect(status).To(Equal(freezer.EntryMissing))
+			})
+		})
+
+		context("when the entry does not record a digest", func() {
+			it.Before(func() {
+				cacheManager.Cache["other-buildpack"] = freezer.CacheEntry{Version: "4.5.6", URI: uri}
+			})
+
+			it("returns unverified", func() {
+				status, ok, err := cacheManager.Verify("other-buildpack")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ok).To(BeTrue())
+				Expect(status).To(Equal(freezer.EntryUnverified))
+			})
+		})
+
+		context("when there is no entry for the key", func() {
+			it("returns not ok", func() {
+				_, ok, err := cacheManager.Verify("some-key")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ok).To(BeFalse())
+			})
+		})
+	})
+
 	context("Set", func() {
 		var uri string
 
@@ -215,6 +651,62 @@ func testCacheManager(t *testing.T, context spec.G, it spec.S) {
 			})
 		})
 
+		it("persists the new information immediately without leaving temporary files behind", func() {
+			err := cacheManager.Set("some-buildpack-other", freezer.CacheEntry{Version: "1.2.4", URI: "some-uri"})
+			Expect(err).NotTo(HaveOccurred())
+
+			var index struct {
+				Entries freezer.CacheDB `json:"entries"`
+			}
+			content, err := os.ReadFile(filepath.Join(cacheDir, "buildpacks-cache.json"))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(json.Unmarshal(content, &index)).To(Succeed())
+			Expect(index.Entries).To(HaveKeyWithValue("some-buildpack-other", freezer.CacheEntry{Version: "1.2.4", URI: "some-uri"}))
+
+			matches, err := filepath.Glob(filepath.Join(cacheDir, "*.tmp"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(matches).To(BeEmpty())
+		})
+
+		context("when the previous file is still referenced by another entry", func() {
+			it.Before(func() {
+				cacheManager.Cache["some-buildpack@1.2.3"] = freezer.CacheEntry{Version: "1.2.3", URI: uri}
+			})
+
+			it("keeps the previous file and sets the new information", func() {
+				err := cacheManager.Set("some-buildpack", freezer.CacheEntry{Version: "1.2.4", URI: "some-uri"})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(uri).To(BeAnExistingFile())
+				Expect(cacheManager.Cache["some-buildpack"]).To(Equal(freezer.CacheEntry{Version: "1.2.4", URI: "some-uri"}))
+			})
+		})
+
+		context("when the entry is set to the same file", func() {
+			it("keeps the file and sets the new information", func() {
+				err := cacheManager.Set("some-buildpack", freezer.CacheEntry{Version: "1.2.4", URI: uri})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(uri).To(BeAnExistingFile())
+				Expect(cacheManager.Cache["some-buildpack"]).To(Equal(freezer.CacheEntry{
+					Version: "1.2.4",
+					URI:     uri,
+					Digest:  "sha256:290f493c44f5d63d06b374d0a5abd292fae38b92cab2fae5efefe1b0e9347f56",
+					Size:    12,
+				}))
+			})
+		})
+
+		context("when the entry already has a digest", func() {
+			it("keeps the given digest", func() {
+				err := cacheManager.Set("some-buildpack-other", freezer.CacheEntry{Version: "1.2.4", URI: uri, Digest: "sha256:some-digest", Size: 1})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(cacheManager.Cache["some-buildpack-other"]).To(Equal(freezer.CacheEntry{Version: "1.2.4", URI: uri, Digest: "sha256:some-digest", Size: 1}))
+			})
+		})
+
 		context("when there is not an already existing entry", func() {
 			it("deletes the previous file and sets the new information", func() {
 				err := cacheManager.Set("some-buildpack-other", freezer.CacheEntry{Version: "1.2.4", URI: "some-uri"})
@@ -254,4 +746,404 @@ func testCacheManager(t *testing.T, context spec.G, it spec.S) {
 			})
 		})
 	})
+
+	context("Delete", func() {
+		var uri string
+
+		it.Before(func() {
+			err := cacheManager.Open()
+			Expect(err).ToNot(HaveOccurred())
+
+			uri = filepath.Join(cacheDir, "some-uri")
+			Expect(os.WriteFile(uri, []byte(`some-content`), 0644)).To(Succeed())
+			Expect(cacheManager.Set("some-buildpack", freezer.CacheEntry{Version: "1.2.3", URI: uri})).To(Succeed())
+		})
+
+		it("removes the entry and its file", func() {
+			ok, err := cacheManager.Delete("some-buildpack")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+
+			Expect(cacheManager.Cache).To(BeEmpty())
+			Expect(uri).NotTo(BeAnExistingFile())
+
+			otherManager := freezer.NewCacheManager(cacheDir)
+			Expect(otherManager.Open()).To(Succeed())
+			Expect(otherManager.Cache).To(BeEmpty())
+		})
+
+		context("when the file is still referenced by another entry", func() {
+			it.Before(func() {
+				Expect(cacheManager.Set("some-buildpack@1.2.3", freezer.CacheEntry{Version: "1.2.3", URI: uri})).To(Succeed())
+			})
+
+			it("keeps the file", func() {
+				ok, err := cacheManager.Delete("some-buildpack")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ok).To(BeTrue())
+
+				Expect(cacheManager.Cache).To(HaveLen(1))
+				Expect(uri).To(BeAnExistingFile())
+			})
+		})
+
+		context("when the key does not exist", func() {
+			it("returns not ok", func() {
+				ok, err := cacheManager.Delete("other-buildpack")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ok).To(BeFalse())
+
+				Expect(uri).To(BeAnExistingFile())
+			})
+		})
+
+		context("failure cases", func() {
+			context("when the cache is nil meaning that the database has not been opened", func() {
+				it("returns an error", func() {
+					otherManager := freezer.NewCacheManager(cacheDir)
+					_, err := otherManager.Delete("some-buildpack")
+					Expect(err).To(MatchError("the cache manager is not loaded properly"))
+				})
+			})
+		})
+	})
+
+	context("Evict", func() {
+		var (
+			now     time.Time
+			entries freezer.CacheDB
+		)
+
+		write := func(name, content string, accessed time.Time) string {
+			path := filepath.Join(cacheDir, name)
+			Expect(os.WriteFile(path, []byte(content), 0644)).To(Succeed())
+			Expect(os.Chtimes(path, accessed, accessed)).To(Succeed())
+			return path
+		}
+
+		it.Before(func() {
+			now = time.Now()
+
+			err := cacheManager.Open()
+			Expect(err).ToNot(HaveOccurred())
+
+			entries = freezer.CacheDB{
+				"org:repo:linux:amd64": freezer.CacheEntry{
+					Version:      "v1.3.0",
+					URI:          write("v1.3.0.cnb", "0123456789", now.Add(-3*time.Hour)),
+					LastAccessed: now.Add(-1 * time.Hour),
+				},
+				"org:repo:linux:amd64@1.2.0": freezer.CacheEntry{
+					Version: "v1.2.0",
+					URI:     write("v1.2.0.cnb", "0123456789", now.Add(-2*time.Hour)),
+				},
+				"org:repo:linux:amd64@1.1.0": freezer.CacheEntry{
+					Version: "v1.1.0",
+					URI:     write("v1.1.0.cnb", "0123456789", now.Add(-48*time.Hour)),
+				},
+			}
+
+			for key, entry := range entries {
+				Expect(cacheManager.Set(key, entry)).To(Succeed())
+			}
+		})
+
+		context("when the policy limits the age of entries", func() {
+			it("evicts the entries that have not been accessed within that time", func() {
+				evicted, err := cacheManager.Evict(freezer.EvictionPolicy{MaxAge: 24 * time.Hour})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(evicted).To(Equal([]string{"org:repo:linux:amd64@1.1.0"}))
+
+				Expect(cacheManager.Cache).To(HaveLen(2))
+				Expect(cacheManager.Cache).NotTo(HaveKey("org:repo:linux:amd64@1.1.0"))
+				Expect(filepath.Join(cacheDir, "v1.1.0.cnb")).NotTo(BeAnExistingFile())
+			})
+		})
+
+		context("when the policy limits the number of versions", func() {
+			it("keeps the most recently accessed versions of each buildpack", func() {
+				evicted, err := cacheManager.Evict(freezer.EvictionPolicy{MaxVersions: 2})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(evicted).To(Equal([]string{"org:repo:linux:amd64@1.1.0"}))
+
+				Expect(cacheManager.Cache).To(HaveKey("org:repo:linux:amd64@1.2.0"))
+				Expect(filepath.Join(cacheDir, "v1.1.0.cnb")).NotTo(BeAnExistingFile())
+			})
+		})
+
+		context("when the policy limits the total size", func() {
+			it("evicts the least recently used entries until the cache fits", func() {
+				evicted, err := cacheManager.Evict(freezer.EvictionPolicy{MaxSize: 15})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(evicted).To(Equal([]string{"org:repo:linux:amd64@1.1.0", "org:repo:linux:amd64@1.2.0"}))
+
+				Expect(cacheManager.Cache).To(HaveLen(1))
+				Expect(cacheManager.Cache).To(HaveKey("org:repo:linux:amd64"))
+				Expect(filepath.Join(cacheDir, "v1.3.0.cnb")).To(BeAnExistingFile())
+				Expect(filepath.Join(cacheDir, "v1.2.0.cnb")).NotTo(BeAnExistingFile())
+			})
+		})
+
+		context("when an evicted file is still referenced by another entry", func() {
+			it.Before(func() {
+				Expect(cacheManager.Set("org:repo:linux:amd64:cached@1.1.0", freezer.CacheEntry{
+					Version:      "v1.1.0",
+					URI:          filepath.Join(cacheDir, "v1.1.0.cnb"),
+					LastAccessed: now,
+				})).To(Succeed())
+			})
+
+			it("keeps the file", func() {
+				evicted, err := cacheManager.Evict(freezer.EvictionPolicy{MaxAge: 24 * time.Hour})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(evicted).To(Equal([]string{"org:repo:linux:amd64@1.1.0"}))
+
+				Expect(cacheManager.Cache).To(HaveKey("org:repo:linux:amd64:cached@1.1.0"))
+				Expect(filepath.Join(cacheDir, "v1.1.0.cnb")).To(BeAnExistingFile())
+			})
+		})
+
+		context("when the file of an entry no longer exists", func() {
+			it.Before(func() {
+				Expect(os.Remove(filepath.Join(cacheDir, "v1.2.0.cnb"))).To(Succeed())
+			})
+
+			it("evicts the entry", func() {
+				evicted, err := cacheManager.Evict(freezer.EvictionPolicy{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(evicted).To(Equal([]string{"org:repo:linux:amd64@1.2.0"}))
+			})
+		})
+
+		context("when an entry is retrieved", func() {
+			it("records when it was accessed", func() {
+				_, ok, err := cacheManager.Get("org:repo:linux:amd64@1.1.0")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ok).To(BeTrue())
+
+				Expect(cacheManager.Close()).To(Succeed())
+
+				otherManager := freezer.NewCacheManager(cacheDir)
+				Expect(otherManager.Open()).To(Succeed())
+				Expect(otherManager.Cache["org:repo:linux:amd64@1.1.0"].LastAccessed).To(BeTemporally("~", time.Now(), time.Minute))
+
+				evicted, err := otherManager.Evict(freezer.EvictionPolicy{MaxAge: 24 * time.Hour})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(evicted).To(BeEmpty())
+			})
+		})
+
+		context("when the cache manager has an eviction policy", func() {
+			it("applies it when the cache manager is opened and closed", func() {
+				otherManager := freezer.NewCacheManager(cacheDir).WithEvictionPolicy(freezer.EvictionPolicy{MaxVersions: 2})
+				Expect(otherManager.Open()).To(Succeed())
+				Expect(otherManager.Cache).To(HaveLen(2))
+
+				Expect(otherManager.Set("org:repo:linux:amd64@1.0.0", freezer.CacheEntry{
+					Version: "v1.0.0",
+					URI:     write("v1.0.0.cnb", "0123456789", now),
+				})).To(Succeed())
+				Expect(otherManager.Close()).To(Succeed())
+
+				Expect(otherManager.Cache).To(HaveLen(2))
+				Expect(otherManager.Cache).To(HaveKey("org:repo:linux:amd64@1.0.0"))
+				Expect(filepath.Join(cacheDir, "v1.2.0.cnb")).NotTo(BeAnExistingFile())
+			})
+		})
+
+		context("failure cases", func() {
+			context("when the cache is nil meaning that the database has not been opened", func() {
+				it("returns an error", func() {
+					otherManager := freezer.NewCacheManager(cacheDir)
+					_, err := otherManager.Evict(freezer.EvictionPolicy{})
+					Expect(err).To(MatchError("the cache manager is not loaded properly"))
+				})
+			})
+		})
+	})
+
+	context("GC", func() {
+		var (
+			referencedPath string
+			orphanPath     string
+			tmpDir         string
+		)
+
+		it.Before(func() {
+			err := cacheManager.Open()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(os.MkdirAll(filepath.Join(cacheDir, "org", "repo", "linux", "amd64"), os.ModePerm)).To(Succeed())
+
+			referencedPath = filepath.Join(cacheDir, "org", "repo", "linux", "amd64", "v1.2.3.cnb")
+			Expect(os.WriteFile(referencedPath, []byte("some-content"), 0644)).To(Succeed())
+			Expect(cacheManager.Set("org:repo:linux:amd64", freezer.CacheEntry{Version: "v1.2.3", URI: referencedPath})).To(Succeed())
+
+			Expect(os.MkdirAll(filepath.Join(cacheDir, "some-buildpack"), os.ModePerm)).To(Succeed())
+			orphanPath = filepath.Join(cacheDir, "some-buildpack", "some-buildpack-random-string.cnb")
+			Expect(os.WriteFile(orphanPath, []byte("some-content"), 0644)).To(Succeed())
+
+			tmpDir = filepath.Join(cacheDir, "url", "some-hash")
+			Expect(os.MkdirAll(tmpDir, os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(tmpDir, ".download.123"), []byte("some-file"), 0644)).To(Succeed())
+
+			Expect(cacheManager.Set("other-buildpack", freezer.CacheEntry{Version: "testing", URI: filepath.Join(cacheDir, "other-buildpack", "missing.cnb")})).To(Succeed())
+		})
+
+		it("removes the files that are not referenced by the index and drops entries without a file", func() {
+			report, err := cacheManager.GC(freezer.GCOptions{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(report).To(Equal(freezer.GCReport{
+				Removed: []string{
+					filepath.Join(cacheDir, "some-buildpack"),
+					orphanPath,
+					filepath.Join(cacheDir, "url"),
+					tmpDir,
+					filepath.Join(tmpDir, ".download.123"),
+				},
+				Dropped:        []string{"other-buildpack"},
+				ReclaimedBytes: 21,
+			}))
+
+			Expect(filepath.Join(cacheDir, "some-buildpack")).NotTo(BeADirectory())
+			Expect(tmpDir).NotTo(BeADirectory())
+			Expect(referencedPath).To(BeAnExistingFile())
+			Expect(filepath.Join(cacheDir, "buildpacks-cache.json")).To(BeAnExistingFile())
+
+			Expect(cacheManager.Cache).To(HaveLen(1))
+			Expect(cacheManager.Cache).To(HaveKey("org:repo:linux:amd64"))
+		})
+
+		context("when it is a dry run", func() {
+			it("reports what would be removed without removing anything", func() {
+				report, err := cacheManager.GC(freezer.GCOptions{DryRun: true})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(report.Removed).To(HaveLen(5))
+				Expect(report.Dropped).To(Equal([]string{"other-buildpack"}))
+				Expect(report.ReclaimedBytes).To(Equal(int64(21)))
+
+				Expect(orphanPath).To(BeAnExistingFile())
+				Expect(filepath.Join(tmpDir, ".download.123")).To(BeAnExistingFile())
+				Expect(cacheManager.Cache).To(HaveLen(2))
+			})
+
+			it("does not rewrite the index", func() {
+				indexPath := filepath.Join(cacheDir, "buildpacks-cache.json")
+				past := time.Now().Add(-time.Hour).Truncate(time.Second)
+				Expect(os.Chtimes(indexPath, past, past)).To(Succeed())
+
+				_, err := cacheManager.GC(freezer.GCOptions{DryRun: true})
+				Expect(err).NotTo(HaveOccurred())
+
+				info, err := os.Stat(indexPath)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(info.ModTime()).To(Equal(past))
+			})
+		})
+
+		context("when orphaned buildpacks are adopted", func() {
+			var olderPath string
+
+			it.Before(func() {
+				olderPath = filepath.Join(cacheDir, "some-buildpack", "some-buildpack-older-string.cnb")
+				Expect(os.WriteFile(olderPath, []byte("some-content"), 0644)).To(Succeed())
+
+				past := time.Now().Add(-time.Hour)
+				Expect(os.Chtimes(olderPath, past, past)).To(Succeed())
+			})
+
+			it("adds the newest buildpack for each key back into the index", func() {
+				report, err := cacheManager.GC(freezer.GCOptions{Adopt: true})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(report.Adopted).To(Equal([]string{"some-buildpack"}))
+				Expect(report.Removed).To(Equal([]string{
+					olderPath,
+					filepath.Join(cacheDir, "url"),
+					tmpDir,
+					filepath.Join(tmpDir, ".download.123"),
+				}))
+
+				Expect(orphanPath).To(BeAnExistingFile())
+				Expect(cacheManager.Cache["some-buildpack"]).To(Equal(freezer.CacheEntry{
+					Version: "testing",
+					URI:     orphanPath,
+					Digest:  "sha256:0a8cac771ca188eacc57e2c96c31f5611925c5ecedccb16b8c236d6c0d325112",
+					Size:    12,
+				}))
+			})
+		})
+
+		context("when the cache directory is shared with other tools", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(cacheDir, "notes.txt"), []byte("some-notes"), 0644)).To(Succeed())
+
+				Expect(os.MkdirAll(filepath.Join(cacheDir, "pip", "http"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(cacheDir, "pip", "http", "wheel.whl"), []byte("some-wheel"), 0644)).To(Succeed())
+
+				Expect(os.MkdirAll(filepath.Join(cacheDir, "some-tool"), os.ModePerm)).To(Succeed())
+
+				Expect(os.WriteFile(filepath.Join(cacheDir, "some-buildpack", "some-file"), []byte("some-file"), 0644)).To(Succeed())
+			})
+
+			it("only removes files that freezer writes", func() {
+				report, err := cacheManager.GC(freezer.GCOptions{})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(report.Removed).To(Equal([]string{
+					orphanPath,
+					filepath.Join(cacheDir, "url"),
+					tmpDir,
+					filepath.Join(tmpDir, ".download.123"),
+				}))
+
+				Expect(filepath.Join(cacheDir, "notes.txt")).To(BeAnExistingFile())
+				Expect(filepath.Join(cacheDir, "pip", "http", "wheel.whl")).To(BeAnExistingFile())
+				Expect(filepath.Join(cacheDir, "some-tool")).To(BeADirectory())
+				Expect(filepath.Join(cacheDir, "some-buildpack", "some-file")).To(BeAnExistingFile())
+			})
+
+			context("when there are partial downloads and index copies", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(filepath.Join(cacheDir, "org", "repo", "linux", "amd64", ".v1.3.0.cnb.123"), []byte("some-partial"), 0644)).To(Succeed())
+					Expect(os.WriteFile(filepath.Join(cacheDir, "buildpacks-cache.json.123.tmp"), []byte("{}"), 0644)).To(Succeed())
+				})
+
+				it("removes them", func() {
+					report, err := cacheManager.GC(freezer.GCOptions{})
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(report.Removed).To(ContainElements(
+						filepath.Join(cacheDir, "org", "repo", "linux", "amd64", ".v1.3.0.cnb.123"),
+						filepath.Join(cacheDir, "buildpacks-cache.json.123.tmp"),
+					))
+
+					Expect(referencedPath).To(BeAnExistingFile())
+					Expect(filepath.Join(cacheDir, "notes.txt")).To(BeAnExistingFile())
+				})
+			})
+		})
+
+		context("when files have been modified recently", func() {
+			it("leaves them alone", func() {
+				report, err := cacheManager.GC(freezer.GCOptions{MinAge: time.Hour})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(report.Removed).To(BeEmpty())
+				Expect(orphanPath).To(BeAnExistingFile())
+			})
+		})
+
+		context("failure cases", func() {
+			context("when the cache is nil meaning that the database has not been opened", func() {
+				it("returns an error", func() {
+					otherManager := freezer.NewCacheManager(cacheDir)
+					_, err := otherManager.GC(freezer.GCOptions{})
+					Expect(err).To(MatchError("the cache manager is not loaded properly"))
+				})
+			})
+		})
+	})
 }
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/ForestEckhardt/freezer"
+)
+
+func clearCache(args []string, stdout io.Writer) (err error) {
+	var (
+		opts    options
+		orphans bool
+	)
+	flags := newFlagSet("clear", &opts)
+	flags.BoolVar(&orphans, "orphans", false, "also remove files freezer wrote that the index does not refer to, such as the leftovers of an interrupted run")
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+
+	if len(positional) > 1 {
+		return fmt.Errorf("clear takes at most one key, got %q", positional)
+	}
+
+	cacheManager, err := openCache(opts)
+	if err != nil {
+		return err
+	}
+	defer closeAndKeepError(cacheManager.Close, &err)
+
+	keys := positional
+	if len(keys) == 0 {
+		keys = sortedKeys(cacheManager.Cache)
+	}
+
+	for _, key := range keys {
+		ok, err := cacheManager.Delete(key)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return fmt.Errorf("no cache entry for key %q", key)
+		}
+	}
+
+	if orphans {
+		_, err = cacheManager.GC(freezer.GCOptions{})
+		if err != nil {
+			return err
+		}
+	}
+
+	if opts.json {
+		if keys == nil {
+			keys = []string{}
+		}
+
+		return printJSON(stdout, struct {
+			Removed []string `json:"removed"`
+		}{keys})
+	}
+
+	for _, key := range keys {
+		fmt.Fprintf(stdout, "removed  %s\n", key)
+	}
+
+	return nil
+}
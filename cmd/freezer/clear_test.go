@@ -0,0 +1,106 @@
+package main_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/ForestEckhardt/freezer"
+	"github.com/onsi/gomega/gexec"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testClear(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect     = NewWithT(t).Expect
+		Eventually = NewWithT(t).Eventually
+
+		cacheDir string
+	)
+
+	it.Before(func() {
+		var err error
+		cacheDir, err = os.MkdirTemp("", "cache")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.WriteFile(filepath.Join(cacheDir, "v1.2.3.cnb"), []byte("some-content"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(cacheDir, "v1.1.0.cnb"), []byte("some-content"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(cacheDir, "orphan.cnb"), []byte("some-content"), 0644)).To(Succeed())
+
+		cacheManager := freezer.NewCacheManager(cacheDir)
+		Expect(cacheManager.Open()).To(Succeed())
+		Expect(cacheManager.Set("org:repo:linux:amd64", freezer.CacheEntry{Version: "v1.2.3", URI: filepath.Join(cacheDir, "v1.2.3.cnb")})).To(Succeed())
+		Expect(cacheManager.Set("org:repo:linux:amd64@1.1.0", freezer.CacheEntry{Version: "v1.1.0", URI: filepath.Join(cacheDir, "v1.1.0.cnb")})).To(Succeed())
+		Expect(cacheManager.Close()).To(Succeed())
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(cacheDir)).To(Succeed())
+	})
+
+	context("when a key is given", func() {
+		it("removes only that buildpack", func() {
+			command := exec.Command(freezerPath, "clear", "--cache-dir", cacheDir, "org:repo:linux:amd64@1.1.0")
+			session, err := gexec.Start(command, nil, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0), output(session))
+
+			Expect(string(session.Out.Contents())).To(ContainSubstring("removed  org:repo:linux:amd64@1.1.0"))
+
+			Expect(filepath.Join(cacheDir, "v1.1.0.cnb")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(cacheDir, "v1.2.3.cnb")).To(BeAnExistingFile())
+			Expect(filepath.Join(cacheDir, "orphan.cnb")).To(BeAnExistingFile())
+
+			cacheManager := freezer.NewCacheManager(cacheDir)
+			Expect(cacheManager.Open()).To(Succeed())
+			Expect(cacheManager.Cache).To(HaveLen(1))
+		})
+	})
+
+	context("when no key is given", func() {
+		it("removes every buildpack", func() {
+			command := exec.Command(freezerPath, "clear", "--cache-dir", cacheDir, "--json")
+			session, err := gexec.Start(command, nil, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0), output(session))
+
+			Expect(string(session.Out.Contents())).To(MatchJSON(`{"removed": ["org:repo:linux:amd64", "org:repo:linux:amd64@1.1.0"]}`))
+
+			Expect(filepath.Join(cacheDir, "v1.1.0.cnb")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(cacheDir, "v1.2.3.cnb")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(cacheDir, "orphan.cnb")).To(BeAnExistingFile())
+		})
+
+		context("when --orphans is given", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(cacheDir, "notes.txt"), []byte("some-notes"), 0644)).To(Succeed())
+			})
+
+			it("also removes the orphaned files freezer wrote", func() {
+				command := exec.Command(freezerPath, "clear", "--cache-dir", cacheDir, "--orphans")
+				session, err := gexec.Start(command, nil, nil)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(session).Should(gexec.Exit(0), output(session))
+
+				Expect(filepath.Join(cacheDir, "orphan.cnb")).NotTo(BeAnExistingFile())
+				Expect(filepath.Join(cacheDir, "notes.txt")).To(BeAnExistingFile())
+			})
+		})
+	})
+
+	context("failure cases", func() {
+		context("when the key is not in the cache", func() {
+			it("returns an error", func() {
+				command := exec.Command(freezerPath, "clear", "--cache-dir", cacheDir, "some-key")
+				session, err := gexec.Start(command, nil, nil)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(session).Should(gexec.Exit(1), output(session))
+
+				Expect(string(session.Err.Contents())).To(ContainSubstring(`freezer: no cache entry for key "some-key"`))
+			})
+		})
+	})
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/ForestEckhardt/freezer"
+)
+
+func fetch(args []string, stdout io.Writer) (err error) {
+	var (
+		opts    options
+		cached  bool
+		offline bool
+		timeout time.Duration
+	)
+	flags := newFlagSet("fetch", &opts)
+	flags.BoolVar(&cached, "cached", false, "package the buildpack for offline use")
+	flags.BoolVar(&offline, "offline", false, "only resolve remote buildpacks from the cache")
+	flags.DurationVar(&timeout, "timeout", 0, "give up if the buildpack has not been fetched within this time")
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+
+	if len(positional) != 1 {
+		return fmt.Errorf("fetch takes exactly one reference, got %q", positional)
+	}
+	reference := positional[0]
+
+	mode := freezer.Uncached
+	if cached {
+		mode = freezer.Cached
+	}
+
+	err = migrateCache(opts)
+	if err != nil {
+		return err
+	}
+
+	fetcher := freezer.NewFetcher().WithCacheDir(opts.cacheDir)
+	if offline {
+		fetcher = fetcher.WithOfflineMode(true)
+	}
+
+	err = fetcher.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open cache %s: %w", opts.cacheDir, err)
+	}
+	defer closeAndKeepError(fetcher.Close, &err)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	uri, err := fetcher.GetContext(ctx, reference, mode)
+	if err != nil {
+		return err
+	}
+
+	if opts.json {
+		return printJSON(stdout, struct {
+			Reference string `json:"reference"`
+			URI       string `json:"uri"`
+		}{reference, uri})
+	}
+
+	fmt.Fprintln(stdout, uri)
+
+	return nil
+}
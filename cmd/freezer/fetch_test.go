@@ -0,0 +1,92 @@
+package main_test
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/ForestEckhardt/freezer"
+	"github.com/onsi/gomega/gexec"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testFetch(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect     = NewWithT(t).Expect
+		Eventually = NewWithT(t).Eventually
+
+		cacheDir string
+		key      string
+	)
+
+	it.Before(func() {
+		var err error
+		cacheDir, err = os.MkdirTemp("", "cache")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.WriteFile(filepath.Join(cacheDir, "1.2.3.cnb"), []byte("some-content"), 0644)).To(Succeed())
+
+		key = fmt.Sprintf("org:repo:linux:%s", runtime.GOARCH)
+
+		cacheManager := freezer.NewCacheManager(cacheDir)
+		Expect(cacheManager.Open()).To(Succeed())
+		Expect(cacheManager.Set(key, freezer.CacheEntry{Version: "1.2.3", URI: filepath.Join(cacheDir, "1.2.3.cnb")})).To(Succeed())
+		Expect(cacheManager.Close()).To(Succeed())
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(cacheDir)).To(Succeed())
+	})
+
+	context("when --offline is given", func() {
+		it("prints the path of the cached buildpack", func() {
+			command := exec.Command(freezerPath, "fetch", "github.com/org/repo", "--offline", "--cache-dir", cacheDir)
+			session, err := gexec.Start(command, nil, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0), output(session))
+
+			Expect(string(session.Out.Contents())).To(Equal(filepath.Join(cacheDir, "1.2.3.cnb") + "\n"))
+		})
+
+		context("when the buildpack is not cached", func() {
+			it("returns an error naming the key", func() {
+				command := exec.Command(freezerPath, "fetch", "github.com/org/other-repo", "--offline", "--cache-dir", cacheDir)
+				session, err := gexec.Start(command, nil, nil)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(session).Should(gexec.Exit(1), output(session))
+
+				Expect(string(session.Err.Contents())).To(ContainSubstring(fmt.Sprintf(`no cache entry for key "org:other-repo:linux:%s"`, runtime.GOARCH)))
+			})
+		})
+	})
+
+	context("when FREEZER_OFFLINE is set", func() {
+		it("prints the path of the cached buildpack", func() {
+			command := exec.Command(freezerPath, "fetch", "github.com/org/repo", "--json", "--cache-dir", cacheDir)
+			command.Env = append(os.Environ(), "FREEZER_OFFLINE=1")
+			session, err := gexec.Start(command, nil, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0), output(session))
+
+			Expect(string(session.Out.Contents())).To(ContainSubstring(fmt.Sprintf(`"uri": %q`, filepath.Join(cacheDir, "1.2.3.cnb"))))
+		})
+	})
+
+	context("failure cases", func() {
+		context("when no reference is given", func() {
+			it("returns an error", func() {
+				command := exec.Command(freezerPath, "fetch", "--cache-dir", cacheDir)
+				session, err := gexec.Start(command, nil, nil)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(session).Should(gexec.Exit(1), output(session))
+
+				Expect(string(session.Err.Contents())).To(ContainSubstring(`freezer: fetch takes exactly one reference, got []`))
+			})
+		})
+	})
+}
@@ -0,0 +1,42 @@
+package main_test
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega/gexec"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	. "github.com/onsi/gomega"
+)
+
+var freezerPath string
+
+func TestFreezerCLI(t *testing.T) {
+	var (
+		Expect = NewWithT(t).Expect
+		err    error
+	)
+
+	freezerPath, err = gexec.Build("github.com/ForestEckhardt/freezer/cmd/freezer")
+	Expect(err).NotTo(HaveOccurred())
+	defer gexec.CleanupBuildArtifacts()
+
+	suite := spec.New("freezer", spec.Report(report.Terminal{}))
+	suite("Clear", testClear)
+	suite("Fetch", testFetch)
+	suite("Inspect", testInspect)
+	suite("List", testList)
+	suite("Prune", testPrune)
+	suite("Verify", testVerify)
+	suite("Usage", testUsage)
+	suite.Run(t)
+}
+
+// output returns the output of session, both stdout and stderr, for describing
+// why it did not exit as expected.
+func output(session *gexec.Session) func() string {
+	return func() string {
+		return string(session.Out.Contents()) + string(session.Err.Contents())
+	}
+}
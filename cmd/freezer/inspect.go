@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+func inspect(args []string, stdout io.Writer) (err error) {
+	var opts options
+	flags := newFlagSet("inspect", &opts)
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+
+	if len(positional) != 1 {
+		return fmt.Errorf("inspect takes exactly one key, got %q", positional)
+	}
+	key := positional[0]
+
+	cacheManager, err := openCache(opts)
+	if err != nil {
+		return err
+	}
+	defer closeAndKeepError(cacheManager.Close, &err)
+
+	cacheEntry, ok := cacheManager.Cache[key]
+	if !ok {
+		return fmt.Errorf("no cache entry for key %q", key)
+	}
+
+	e, err := newEntry(key, cacheEntry)
+	if err != nil {
+		return err
+	}
+
+	if opts.json {
+		return printJSON(stdout, e)
+	}
+
+	now := time.Now()
+	status := "present"
+	if e.Missing {
+		status = "missing"
+	}
+
+	table := newTable(stdout)
+	fmt.Fprintf(table, "Key:\t%s\n", e.Key)
+	fmt.Fprintf(table, "Version:\t%s\n", e.Version)
+	fmt.Fprintf(table, "URI:\t%s\n", e.URI)
+	fmt.Fprintf(table, "Status:\t%s\n", status)
+	fmt.Fprintf(table, "Size:\t%s\n", formatSize(e.Size))
+	if e.Digest != "" {
+		fmt.Fprintf(table, "Digest:\t%s\n", e.Digest)
+	}
+	if e.SourceDigest != "" {
+		fmt.Fprintf(table, "Source Digest:\t%s\n", e.SourceDigest)
+	}
+	if e.ImageDigest != "" {
+		fmt.Fprintf(table, "Image Digest:\t%s\n", e.ImageDigest)
+	}
+	if e.Modified != nil {
+		fmt.Fprintf(table, "Modified:\t%s (%s ago)\n", e.Modified.Format(time.RFC3339), formatAge(*e.Modified, now))
+	}
+	if e.LastAccessed != nil {
+		fmt.Fprintf(table, "Last Accessed:\t%s (%s ago)\n", e.LastAccessed.Format(time.RFC3339), formatAge(*e.LastAccessed, now))
+	}
+	if e.CheckedAt != nil {
+		fmt.Fprintf(table, "Last Checked:\t%s (%s ago)\n", e.CheckedAt.Format(time.RFC3339), formatAge(*e.CheckedAt, now))
+	}
+
+	return table.Flush()
+}
@@ -0,0 +1,137 @@
+package main_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ForestEckhardt/freezer"
+	"github.com/onsi/gomega/gexec"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testInspect(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect     = NewWithT(t).Expect
+		Eventually = NewWithT(t).Eventually
+
+		cacheDir string
+	)
+
+	it.Before(func() {
+		var err error
+		cacheDir, err = os.MkdirTemp("", "cache")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.WriteFile(filepath.Join(cacheDir, "v1.2.3.cnb"), []byte("some-content"), 0644)).To(Succeed())
+
+		cacheManager := freezer.NewCacheManager(cacheDir)
+		Expect(cacheManager.Open()).To(Succeed())
+		Expect(cacheManager.Set("org:repo:linux:amd64", freezer.CacheEntry{Version: "v1.2.3", URI: filepath.Join(cacheDir, "v1.2.3.cnb")})).To(Succeed())
+		Expect(cacheManager.Close()).To(Succeed())
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(cacheDir)).To(Succeed())
+	})
+
+	it("prints the details of the cached buildpack", func() {
+		command := exec.Command(freezerPath, "inspect", "org:repo:linux:amd64", "--cache-dir", cacheDir)
+		session, err := gexec.Start(command, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(0), output(session))
+
+		Expect(string(session.Out.Contents())).To(MatchRegexp(`Key:\s+org:repo:linux:amd64`))
+		Expect(string(session.Out.Contents())).To(MatchRegexp(`Version:\s+v1\.2\.3`))
+		Expect(string(session.Out.Contents())).To(MatchRegexp(`URI:\s+` + filepath.Join(cacheDir, "v1.2.3.cnb")))
+		Expect(string(session.Out.Contents())).To(MatchRegexp(`Status:\s+present`))
+		Expect(string(session.Out.Contents())).To(MatchRegexp(`Size:\s+12 B`))
+		Expect(string(session.Out.Contents())).To(MatchRegexp(`Digest:\s+sha256:0a8cac771ca188eacc57e2c96c31f5611925c5ecedccb16b8c236d6c0d325112`))
+		Expect(string(session.Out.Contents())).To(MatchRegexp(`Modified:\s+\S+ \(\d+s ago\)`))
+	})
+
+	context("when the entry was pulled from a registry", func() {
+		it.Before(func() {
+			cacheManager := freezer.NewCacheManager(cacheDir)
+			Expect(cacheManager.Open()).To(Succeed())
+			Expect(cacheManager.Set("gcr.io/org/image:linux:amd64@1.2.3", freezer.CacheEntry{
+				Version:     "1.2.3",
+				URI:         filepath.Join(cacheDir, "v1.2.3.cnb"),
+				ImageDigest: "sha256:some-image-digest",
+			})).To(Succeed())
+			Expect(cacheManager.Close()).To(Succeed())
+		})
+
+		it("prints the digest of the image", func() {
+			command := exec.Command(freezerPath, "inspect", "gcr.io/org/image:linux:amd64@1.2.3", "--cache-dir", cacheDir)
+			session, err := gexec.Start(command, nil, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0), output(session))
+
+			Expect(string(session.Out.Contents())).To(MatchRegexp(`Image Digest:\s+sha256:some-image-digest`))
+		})
+	})
+
+	context("when the entry was checked against GitHub", func() {
+		it.Before(func() {
+			cacheManager := freezer.NewCacheManager(cacheDir)
+			Expect(cacheManager.Open()).To(Succeed())
+			Expect(cacheManager.Set("org:repo:linux:amd64", freezer.CacheEntry{
+				Version:   "v1.2.3",
+				URI:       filepath.Join(cacheDir, "v1.2.3.cnb"),
+				CheckedAt: time.Now().Add(-2 * time.Hour),
+			})).To(Succeed())
+			Expect(cacheManager.Close()).To(Succeed())
+		})
+
+		it("prints when it was last checked", func() {
+			command := exec.Command(freezerPath, "inspect", "org:repo:linux:amd64", "--cache-dir", cacheDir)
+			session, err := gexec.Start(command, nil, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0), output(session))
+
+			Expect(string(session.Out.Contents())).To(MatchRegexp(`Last Checked:\s+\S+ \(2h ago\)`))
+		})
+	})
+
+	context("when --json is given", func() {
+		it("prints the details as JSON", func() {
+			command := exec.Command(freezerPath, "inspect", "--json", "--cache-dir", cacheDir, "org:repo:linux:amd64")
+			session, err := gexec.Start(command, nil, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0), output(session))
+
+			Expect(string(session.Out.Contents())).To(ContainSubstring(`"key": "org:repo:linux:amd64"`))
+			Expect(string(session.Out.Contents())).To(ContainSubstring(`"version": "v1.2.3"`))
+			Expect(string(session.Out.Contents())).To(ContainSubstring(`"missing": false`))
+		})
+	})
+
+	context("failure cases", func() {
+		context("when the key is not in the cache", func() {
+			it("returns an error", func() {
+				command := exec.Command(freezerPath, "inspect", "--cache-dir", cacheDir, "some-key")
+				session, err := gexec.Start(command, nil, nil)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(session).Should(gexec.Exit(1), output(session))
+
+				Expect(string(session.Err.Contents())).To(ContainSubstring(`freezer: no cache entry for key "some-key"`))
+			})
+		})
+
+		context("when no key is given", func() {
+			it("returns an error", func() {
+				command := exec.Command(freezerPath, "inspect", "--cache-dir", cacheDir)
+				session, err := gexec.Start(command, nil, nil)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(session).Should(gexec.Exit(1), output(session))
+
+				Expect(string(session.Err.Contents())).To(ContainSubstring(`freezer: inspect takes exactly one key, got []`))
+			})
+		})
+	})
+}
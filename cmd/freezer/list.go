@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ForestEckhardt/freezer"
+)
+
+// entry is the representation of a cache entry printed by list and inspect.
+type entry struct {
+	Key          string     `json:"key"`
+	Version      string     `json:"version"`
+	URI          string     `json:"uri"`
+	Digest       string     `json:"digest,omitempty"`
+	SourceDigest string     `json:"source_digest,omitempty"`
+	ImageDigest  string     `json:"image_digest,omitempty"`
+	Size         int64      `json:"size"`
+	Modified     *time.Time `json:"modified,omitempty"`
+	LastAccessed *time.Time `json:"last_accessed,omitempty"`
+	CheckedAt    *time.Time `json:"checked_at,omitempty"`
+	Missing      bool       `json:"missing"`
+}
+
+func newEntry(key string, cacheEntry freezer.CacheEntry) (entry, error) {
+	e := entry{
+		Key:          key,
+		Version:      cacheEntry.Version,
+		URI:          cacheEntry.URI,
+		Digest:       cacheEntry.Digest,
+		SourceDigest: cacheEntry.SourceDigest,
+		ImageDigest:  cacheEntry.ImageDigest,
+		Size:         cacheEntry.Size,
+	}
+
+	if !cacheEntry.LastAccessed.IsZero() {
+		lastAccessed := cacheEntry.LastAccessed
+		e.LastAccessed = &lastAccessed
+	}
+
+	if !cacheEntry.CheckedAt.IsZero() {
+		checkedAt := cacheEntry.CheckedAt
+		e.CheckedAt = &checkedAt
+	}
+
+	info, err := os.Stat(cacheEntry.URI)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return entry{}, err
+		}
+
+		e.Missing = true
+		return e, nil
+	}
+
+	modified := info.ModTime()
+	e.Modified = &modified
+	e.Size = info.Size()
+
+	return e, nil
+}
+
+func list(args []string, stdout io.Writer) (err error) {
+	var opts options
+	flags := newFlagSet("list", &opts)
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+
+	if len(positional) != 0 {
+		return fmt.Errorf("list takes no arguments, got %q", positional)
+	}
+
+	cacheManager, err := openCache(opts)
+	if err != nil {
+		return err
+	}
+	defer closeAndKeepError(cacheManager.Close, &err)
+
+	entries := []entry{}
+	for _, key := range sortedKeys(cacheManager.Cache) {
+		e, err := newEntry(key, cacheManager.Cache[key])
+		if err != nil {
+			return err
+		}
+
+		entries = append(entries, e)
+	}
+
+	if opts.json {
+		return printJSON(stdout, entries)
+	}
+
+	now := time.Now()
+	table := newTable(stdout)
+	fmt.Fprintln(table, "KEY\tVERSION\tSIZE\tAGE\tURI")
+	for _, e := range entries {
+		size, age := "missing", "-"
+		if !e.Missing {
+			size = formatSize(e.Size)
+			age = formatAge(*e.Modified, now)
+		}
+
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\n", e.Key, e.Version, size, age, e.URI)
+	}
+
+	return table.Flush()
+}
@@ -0,0 +1,79 @@
+package main_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/ForestEckhardt/freezer"
+	"github.com/onsi/gomega/gexec"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testList(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect     = NewWithT(t).Expect
+		Eventually = NewWithT(t).Eventually
+
+		cacheDir string
+	)
+
+	it.Before(func() {
+		var err error
+		cacheDir, err = os.MkdirTemp("", "cache")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.WriteFile(filepath.Join(cacheDir, "v1.2.3.cnb"), []byte("some-content"), 0644)).To(Succeed())
+
+		cacheManager := freezer.NewCacheManager(cacheDir)
+		Expect(cacheManager.Open()).To(Succeed())
+		Expect(cacheManager.Set("org:repo:linux:amd64", freezer.CacheEntry{Version: "v1.2.3", URI: filepath.Join(cacheDir, "v1.2.3.cnb")})).To(Succeed())
+		Expect(cacheManager.Set("some-buildpack", freezer.CacheEntry{Version: "testing", URI: filepath.Join(cacheDir, "missing.cnb")})).To(Succeed())
+		Expect(cacheManager.Close()).To(Succeed())
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(cacheDir)).To(Succeed())
+	})
+
+	it("prints a table of the cached buildpacks", func() {
+		command := exec.Command(freezerPath, "list", "--cache-dir", cacheDir)
+		session, err := gexec.Start(command, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(0), output(session))
+
+		Expect(string(session.Out.Contents())).To(MatchRegexp(`KEY\s+VERSION\s+SIZE\s+AGE\s+URI`))
+		Expect(string(session.Out.Contents())).To(MatchRegexp(`org:repo:linux:amd64\s+v1\.2\.3\s+12 B\s+\d+s\s+` + filepath.Join(cacheDir, "v1.2.3.cnb")))
+		Expect(string(session.Out.Contents())).To(MatchRegexp(`some-buildpack\s+testing\s+missing\s+-\s+` + filepath.Join(cacheDir, "missing.cnb")))
+	})
+
+	context("when --json is given", func() {
+		it("prints the cached buildpacks as JSON", func() {
+			command := exec.Command(freezerPath, "list", "--cache-dir", cacheDir, "--json")
+			session, err := gexec.Start(command, nil, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0), output(session))
+
+			Expect(string(session.Out.Contents())).To(ContainSubstring(`"key": "org:repo:linux:amd64"`))
+			Expect(string(session.Out.Contents())).To(ContainSubstring(`"digest": "sha256:0a8cac771ca188eacc57e2c96c31f5611925c5ecedccb16b8c236d6c0d325112"`))
+			Expect(string(session.Out.Contents())).To(ContainSubstring(`"size": 12`))
+			Expect(string(session.Out.Contents())).To(ContainSubstring(`"missing": true`))
+		})
+	})
+
+	context("failure cases", func() {
+		context("when arguments are given", func() {
+			it("returns an error", func() {
+				command := exec.Command(freezerPath, "list", "--cache-dir", cacheDir, "some-argument")
+				session, err := gexec.Start(command, nil, nil)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(session).Should(gexec.Exit(1), output(session))
+
+				Expect(string(session.Err.Contents())).To(ContainSubstring(`freezer: list takes no arguments, got ["some-argument"]`))
+			})
+		})
+	})
+}
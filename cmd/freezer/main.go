@@ -0,0 +1,199 @@
+// Command freezer inspects and manages the buildpack cache used by the
+// freezer library.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ForestEckhardt/freezer"
+)
+
+const usage = `Usage: freezer <command> [options] [arguments]
+
+Commands:
+  list              list the cached buildpacks
+  inspect <key>     show the details of a cached buildpack
+  fetch <ref>       fetch a buildpack into the cache and print its path
+  prune             evict cached buildpacks and, with --orphans, remove
+                    orphaned files
+  verify [key...]   check cached buildpacks against their recorded digests
+  clear [key]       remove one or all cached buildpacks
+
+Every command accepts:
+  --cache-dir <dir> the cache directory (default %s)
+  --json            print JSON instead of a table
+
+Run 'freezer <command> --help' for the options of a command.
+`
+
+type command func(args []string, stdout io.Writer) error
+
+var commands = map[string]command{
+	"list":    list,
+	"inspect": inspect,
+	"fetch":   fetch,
+	"prune":   prune,
+	"verify":  verify,
+	"clear":   clearCache,
+}
+
+func main() {
+	err := run(os.Args[1:], os.Stdout, os.Stderr)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+
+		fmt.Fprintf(os.Stderr, "freezer: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		fmt.Fprintf(stderr, usage, freezer.DefaultCacheDir())
+		if len(args) == 0 {
+			return errors.New("no command given")
+		}
+		return nil
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, usage, freezer.DefaultCacheDir())
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+
+	return cmd(args[1:], stdout)
+}
+
+// options holds the flags shared by every command.
+type options struct {
+	cacheDir string
+	json     bool
+}
+
+func newFlagSet(name string, opts *options) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.StringVar(&opts.cacheDir, "cache-dir", freezer.DefaultCacheDir(), "the cache directory")
+	flags.BoolVar(&opts.json, "json", false, "print JSON instead of a table")
+	return flags
+}
+
+// parseFlags parses args allowing flags to follow positional arguments, as in
+// 'freezer inspect some-key --json'.
+func parseFlags(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		err := flags.Parse(args)
+		if err != nil {
+			return nil, err
+		}
+
+		args = flags.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// migrateCache moves a cache in the legacy location into the default cache
+// directory, as the library does, unless another directory was requested.
+func migrateCache(opts options) error {
+	if opts.cacheDir != freezer.DefaultCacheDir() {
+		return nil
+	}
+
+	return freezer.MigrateLegacyCacheDir(opts.cacheDir)
+}
+
+func openCache(opts options) (*freezer.CacheManager, error) {
+	err := migrateCache(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheManager := freezer.NewCacheManager(opts.cacheDir)
+	err = cacheManager.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cache %s: %w", opts.cacheDir, err)
+	}
+
+	return &cacheManager, nil
+}
+
+// closeAndKeepError calls close and, unless the command has already failed,
+// makes its error the error of the command so that a cache index that could
+// not be written is not reported as a success.
+func closeAndKeepError(close func() error, err *error) {
+	closeErr := close()
+	if *err == nil {
+		*err = closeErr
+	}
+}
+
+func sortedKeys(db freezer.CacheDB) []string {
+	var keys []string
+	for key := range db {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func printJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func newTable(w io.Writer) *tabwriter.Writer {
+	return tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+}
+
+// formatSize renders a size in bytes using binary units, e.g. 1.5 MiB.
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// formatAge renders the time elapsed since t in its largest whole unit.
+func formatAge(t, now time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+
+	age := now.Sub(t)
+	switch {
+	case age < time.Minute:
+		return fmt.Sprintf("%ds", int(age.Seconds()))
+	case age < time.Hour:
+		return fmt.Sprintf("%dm", int(age.Minutes()))
+	case age < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(age.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(age.Hours()/24))
+	}
+}
@@ -0,0 +1,54 @@
+package main_test
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/onsi/gomega/gexec"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testUsage(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect     = NewWithT(t).Expect
+		Eventually = NewWithT(t).Eventually
+	)
+
+	context("when --help is given", func() {
+		it("prints the usage", func() {
+			command := exec.Command(freezerPath, "--help")
+			session, err := gexec.Start(command, nil, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0), output(session))
+
+			Expect(string(session.Err.Contents())).To(ContainSubstring("Usage: freezer <command> [options] [arguments]"))
+		})
+	})
+
+	context("failure cases", func() {
+		context("when no command is given", func() {
+			it("prints the usage and returns an error", func() {
+				command := exec.Command(freezerPath)
+				session, err := gexec.Start(command, nil, nil)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(session).Should(gexec.Exit(1), output(session))
+
+				Expect(string(session.Err.Contents())).To(ContainSubstring("Usage: freezer <command> [options] [arguments]"))
+				Expect(string(session.Err.Contents())).To(ContainSubstring("freezer: no command given"))
+			})
+		})
+
+		context("when the command is unknown", func() {
+			it("returns an error", func() {
+				command := exec.Command(freezerPath, "some-command")
+				session, err := gexec.Start(command, nil, nil)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(session).Should(gexec.Exit(1), output(session))
+
+				Expect(string(session.Err.Contents())).To(ContainSubstring(`freezer: unknown command "some-command"`))
+			})
+		})
+	})
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ForestEckhardt/freezer"
+)
+
+func prune(args []string, stdout io.Writer) (err error) {
+	var (
+		opts      options
+		orphans   bool
+		maxSize   string
+		maxAge    string
+		gcOptions freezer.GCOptions
+		policy    freezer.EvictionPolicy
+	)
+	flags := newFlagSet("prune", &opts)
+	flags.StringVar(&maxSize, "max-size", "", "evict the least recently used buildpacks until the cache is smaller than this size, e.g. 5G")
+	flags.StringVar(&maxAge, "max-age", "", "evict buildpacks that have not been used for this long, e.g. 30d or 12h")
+	flags.IntVar(&policy.MaxVersions, "max-versions", 0, "keep at most this many versions of each buildpack")
+	flags.BoolVar(&orphans, "orphans", false, "remove files freezer wrote that the index does not refer to and drop entries whose file is missing")
+	flags.BoolVar(&gcOptions.Adopt, "adopt", false, "with --orphans, add orphaned buildpacks back into the index instead of removing them")
+	flags.BoolVar(&gcOptions.DryRun, "dry-run", false, "with --orphans, report orphaned files without removing them")
+	flags.DurationVar(&gcOptions.MinAge, "min-age", time.Hour, "leave files modified more recently than this alone")
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+
+	if len(positional) != 0 {
+		return fmt.Errorf("prune takes no arguments, got %q", positional)
+	}
+
+	if maxSize != "" {
+		policy.MaxSize, err = parseSize(maxSize)
+		if err != nil {
+			return err
+		}
+	}
+
+	if maxAge != "" {
+		policy.MaxAge, err = parseAge(maxAge)
+		if err != nil {
+			return err
+		}
+	}
+
+	if (gcOptions.Adopt || gcOptions.DryRun) && !orphans {
+		return errors.New("--adopt and --dry-run require --orphans")
+	}
+
+	evicting := policy != (freezer.EvictionPolicy{})
+	if evicting && gcOptions.DryRun {
+		return errors.New("--dry-run cannot be combined with --max-size, --max-age or --max-versions")
+	}
+
+	cacheManager, err := openCache(opts)
+	if err != nil {
+		return err
+	}
+	defer closeAndKeepError(cacheManager.Close, &err)
+
+	var result struct {
+		DryRun         bool     `json:"dry_run"`
+		Evicted        []string `json:"evicted"`
+		Removed        []string `json:"removed"`
+		Adopted        []string `json:"adopted"`
+		Dropped        []string `json:"dropped"`
+		ReclaimedBytes int64    `json:"reclaimed_bytes"`
+	}
+	result.DryRun = gcOptions.DryRun
+
+	if evicting {
+		sizes := map[string]int64{}
+		for _, entry := range cacheManager.Cache {
+			info, err := os.Stat(entry.URI)
+			if err == nil {
+				sizes[entry.URI] = info.Size()
+			}
+		}
+
+		result.Evicted, err = cacheManager.Evict(policy)
+		if err != nil {
+			return err
+		}
+
+		for uri, size := range sizes {
+			_, err := os.Stat(uri)
+			if errors.Is(err, os.ErrNotExist) {
+				result.ReclaimedBytes += size
+			}
+		}
+	}
+
+	if orphans {
+		report, err := cacheManager.GC(gcOptions)
+		if err != nil {
+			return err
+		}
+
+		result.Removed = report.Removed
+		result.Adopted = report.Adopted
+		result.Dropped = report.Dropped
+		result.ReclaimedBytes += report.ReclaimedBytes
+	}
+
+	if opts.json {
+		for _, list := range []*[]string{&result.Evicted, &result.Removed, &result.Adopted, &result.Dropped} {
+			if *list == nil {
+				*list = []string{}
+			}
+		}
+
+		return printJSON(stdout, result)
+	}
+
+	verb := "Reclaimed"
+	if gcOptions.DryRun {
+		verb = "Would reclaim"
+	}
+
+	for _, key := range result.Evicted {
+		fmt.Fprintf(stdout, "evicted  %s\n", key)
+	}
+	for _, key := range result.Dropped {
+		fmt.Fprintf(stdout, "dropped  %s\n", key)
+	}
+	for _, key := range result.Adopted {
+		fmt.Fprintf(stdout, "adopted  %s\n", key)
+	}
+	for _, path := range result.Removed {
+		fmt.Fprintf(stdout, "removed  %s\n", path)
+	}
+	fmt.Fprintf(stdout, "%s %s\n", verb, formatSize(result.ReclaimedBytes))
+
+	return nil
+}
+
+// parseSize parses a size in bytes with an optional binary unit suffix, e.g.
+// 512M or 5G.
+func parseSize(s string) (int64, error) {
+	value := strings.TrimSuffix(strings.TrimSuffix(strings.ToUpper(s), "B"), "I")
+
+	multiplier := int64(1)
+	if value != "" {
+		if i := strings.IndexByte("KMGT", value[len(value)-1]); i >= 0 {
+			value = value[:len(value)-1]
+			for ; i >= 0; i-- {
+				multiplier *= 1024
+			}
+		}
+	}
+
+	size, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+
+	return size * multiplier, nil
+}
+
+// parseAge parses a duration, additionally accepting a number of days such as
+// 30d.
+func parseAge(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil || days < 0 {
+			return 0, fmt.Errorf("invalid age %q", s)
+		}
+
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+
+	age, err := time.ParseDuration(s)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("invalid age %q", s)
+	}
+
+	return age, nil
+}
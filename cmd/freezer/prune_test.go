@@ -0,0 +1,144 @@
+package main_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ForestEckhardt/freezer"
+	"github.com/onsi/gomega/gexec"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testPrune(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect     = NewWithT(t).Expect
+		Eventually = NewWithT(t).Eventually
+
+		cacheDir string
+	)
+
+	it.Before(func() {
+		var err error
+		cacheDir, err = os.MkdirTemp("", "cache")
+		Expect(err).NotTo(HaveOccurred())
+
+		past := time.Now().Add(-48 * time.Hour)
+		for _, name := range []string{"v1.2.3.cnb", "v1.1.0.cnb", "orphan.cnb"} {
+			path := filepath.Join(cacheDir, name)
+			Expect(os.WriteFile(path, []byte("some-content"), 0644)).To(Succeed())
+			Expect(os.Chtimes(path, past, past)).To(Succeed())
+		}
+		Expect(os.Chtimes(filepath.Join(cacheDir, "v1.2.3.cnb"), time.Now(), time.Now())).To(Succeed())
+
+		cacheManager := freezer.NewCacheManager(cacheDir)
+		Expect(cacheManager.Open()).To(Succeed())
+		Expect(cacheManager.Set("org:repo:linux:amd64", freezer.CacheEntry{Version: "v1.2.3", URI: filepath.Join(cacheDir, "v1.2.3.cnb")})).To(Succeed())
+		Expect(cacheManager.Set("org:repo:linux:amd64@1.1.0", freezer.CacheEntry{Version: "v1.1.0", URI: filepath.Join(cacheDir, "v1.1.0.cnb")})).To(Succeed())
+		Expect(cacheManager.Close()).To(Succeed())
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(cacheDir)).To(Succeed())
+	})
+
+	it("evicts buildpacks outside of the limits", func() {
+		command := exec.Command(freezerPath, "prune", "--cache-dir", cacheDir, "--max-age", "1d")
+		session, err := gexec.Start(command, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(0), output(session))
+
+		Expect(string(session.Out.Contents())).To(ContainSubstring("evicted  org:repo:linux:amd64@1.1.0"))
+		Expect(string(session.Out.Contents())).NotTo(ContainSubstring("removed"))
+		Expect(string(session.Out.Contents())).To(ContainSubstring("Reclaimed 12 B"))
+
+		Expect(filepath.Join(cacheDir, "v1.1.0.cnb")).NotTo(BeAnExistingFile())
+		Expect(filepath.Join(cacheDir, "orphan.cnb")).To(BeAnExistingFile())
+		Expect(filepath.Join(cacheDir, "v1.2.3.cnb")).To(BeAnExistingFile())
+	})
+
+	it("also removes orphaned files when --orphans is given", func() {
+		command := exec.Command(freezerPath, "prune", "--cache-dir", cacheDir, "--max-age", "1d", "--orphans")
+		session, err := gexec.Start(command, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(0), output(session))
+
+		Expect(string(session.Out.Contents())).To(ContainSubstring("evicted  org:repo:linux:amd64@1.1.0"))
+		Expect(string(session.Out.Contents())).To(ContainSubstring("removed  " + filepath.Join(cacheDir, "orphan.cnb")))
+		Expect(string(session.Out.Contents())).To(ContainSubstring("Reclaimed 24 B"))
+
+		Expect(filepath.Join(cacheDir, "v1.1.0.cnb")).NotTo(BeAnExistingFile())
+		Expect(filepath.Join(cacheDir, "orphan.cnb")).NotTo(BeAnExistingFile())
+		Expect(filepath.Join(cacheDir, "v1.2.3.cnb")).To(BeAnExistingFile())
+	})
+
+	context("when --dry-run is given", func() {
+		it("reports the orphaned files without removing them", func() {
+			command := exec.Command(freezerPath, "prune", "--cache-dir", cacheDir, "--orphans", "--dry-run", "--json")
+			session, err := gexec.Start(command, nil, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0), output(session))
+
+			Expect(string(session.Out.Contents())).To(MatchJSON(`{
+				"dry_run": true,
+				"evicted": [],
+				"removed": [` + "\"" + filepath.Join(cacheDir, "orphan.cnb") + "\"" + `],
+				"adopted": [],
+				"dropped": [],
+				"reclaimed_bytes": 12
+			}`))
+
+			Expect(filepath.Join(cacheDir, "orphan.cnb")).To(BeAnExistingFile())
+		})
+	})
+
+	context("failure cases", func() {
+		context("when --dry-run is combined with eviction limits", func() {
+			it("returns an error", func() {
+				command := exec.Command(freezerPath, "prune", "--cache-dir", cacheDir, "--orphans", "--dry-run", "--max-versions", "1")
+				session, err := gexec.Start(command, nil, nil)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(session).Should(gexec.Exit(1), output(session))
+
+				Expect(string(session.Err.Contents())).To(ContainSubstring("freezer: --dry-run cannot be combined with --max-size, --max-age or --max-versions"))
+			})
+		})
+
+		context("when --dry-run is given without --orphans", func() {
+			it("returns an error", func() {
+				command := exec.Command(freezerPath, "prune", "--cache-dir", cacheDir, "--dry-run")
+				session, err := gexec.Start(command, nil, nil)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(session).Should(gexec.Exit(1), output(session))
+
+				Expect(string(session.Err.Contents())).To(ContainSubstring("freezer: --adopt and --dry-run require --orphans"))
+			})
+		})
+
+		context("when the size cannot be parsed", func() {
+			it("returns an error", func() {
+				command := exec.Command(freezerPath, "prune", "--cache-dir", cacheDir, "--max-size", "lots")
+				session, err := gexec.Start(command, nil, nil)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(session).Should(gexec.Exit(1), output(session))
+
+				Expect(string(session.Err.Contents())).To(ContainSubstring(`freezer: invalid size "lots"`))
+			})
+		})
+
+		context("when the age cannot be parsed", func() {
+			it("returns an error", func() {
+				command := exec.Command(freezerPath, "prune", "--cache-dir", cacheDir, "--max-age", "forever")
+				session, err := gexec.Start(command, nil, nil)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(session).Should(gexec.Exit(1), output(session))
+
+				Expect(string(session.Err.Contents())).To(ContainSubstring(`freezer: invalid age "forever"`))
+			})
+		})
+	})
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/ForestEckhardt/freezer"
+)
+
+func verify(args []string, stdout io.Writer) (err error) {
+	var opts options
+	flags := newFlagSet("verify", &opts)
+	keys, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+
+	cacheManager, err := openCache(opts)
+	if err != nil {
+		return err
+	}
+	defer closeAndKeepError(cacheManager.Close, &err)
+
+	if len(keys) == 0 {
+		keys = sortedKeys(cacheManager.Cache)
+	}
+
+	type result struct {
+		Key    string              `json:"key"`
+		URI    string              `json:"uri"`
+		Status freezer.EntryStatus `json:"status"`
+	}
+
+	results := []result{}
+	var failed int
+	for _, key := range keys {
+		status, ok, err := cacheManager.Verify(key)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return fmt.Errorf("no cache entry for key %q", key)
+		}
+
+		if status == freezer.EntryMissing || status == freezer.EntryCorrupt {
+			failed++
+		}
+
+		results = append(results, result{Key: key, URI: cacheManager.Cache[key].URI, Status: status})
+	}
+
+	if opts.json {
+		err = printJSON(stdout, results)
+	} else {
+		table := newTable(stdout)
+		fmt.Fprintln(table, "KEY\tSTATUS\tURI")
+		for _, r := range results {
+			fmt.Fprintf(table, "%s\t%s\t%s\n", r.Key, r.Status, r.URI)
+		}
+		err = table.Flush()
+	}
+	if err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d cached buildpacks failed verification", failed, len(results))
+	}
+
+	return nil
+}
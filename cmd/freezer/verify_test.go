@@ -0,0 +1,85 @@
+package main_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/ForestEckhardt/freezer"
+	"github.com/onsi/gomega/gexec"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testVerify(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect     = NewWithT(t).Expect
+		Eventually = NewWithT(t).Eventually
+
+		cacheDir string
+	)
+
+	it.Before(func() {
+		var err error
+		cacheDir, err = os.MkdirTemp("", "cache")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.WriteFile(filepath.Join(cacheDir, "v1.2.3.cnb"), []byte("some-content"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(cacheDir, "v1.1.0.cnb"), []byte("some-content"), 0644)).To(Succeed())
+
+		cacheManager := freezer.NewCacheManager(cacheDir)
+		Expect(cacheManager.Open()).To(Succeed())
+		Expect(cacheManager.Set("org:repo:linux:amd64", freezer.CacheEntry{Version: "v1.2.3", URI: filepath.Join(cacheDir, "v1.2.3.cnb")})).To(Succeed())
+		Expect(cacheManager.Set("org:repo:linux:amd64@1.1.0", freezer.CacheEntry{Version: "v1.1.0", URI: filepath.Join(cacheDir, "v1.1.0.cnb")})).To(Succeed())
+		Expect(cacheManager.Close()).To(Succeed())
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(cacheDir)).To(Succeed())
+	})
+
+	it("reports that every cached buildpack matches its digest", func() {
+		command := exec.Command(freezerPath, "verify", "--cache-dir", cacheDir)
+		session, err := gexec.Start(command, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(0), output(session))
+
+		Expect(string(session.Out.Contents())).To(MatchRegexp(`org:repo:linux:amd64\s+ok`))
+		Expect(string(session.Out.Contents())).To(MatchRegexp(`org:repo:linux:amd64@1\.1\.0\s+ok`))
+	})
+
+	context("when a cached buildpack has been modified", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(cacheDir, "v1.1.0.cnb"), []byte("other-content"), 0644)).To(Succeed())
+		})
+
+		it("reports it as corrupt and fails", func() {
+			command := exec.Command(freezerPath, "verify", "--cache-dir", cacheDir, "--json")
+			session, err := gexec.Start(command, nil, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(1), output(session))
+
+			Expect(string(session.Out.Contents())).To(ContainSubstring(`"status": "corrupt"`))
+			Expect(string(session.Err.Contents())).To(ContainSubstring("freezer: 1 of 2 cached buildpacks failed verification"))
+		})
+	})
+
+	context("when a cached buildpack is missing", func() {
+		it.Before(func() {
+			Expect(os.Remove(filepath.Join(cacheDir, "v1.2.3.cnb"))).To(Succeed())
+		})
+
+		it("reports it as missing and fails", func() {
+			command := exec.Command(freezerPath, "verify", "--cache-dir", cacheDir, "org:repo:linux:amd64")
+			session, err := gexec.Start(command, nil, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(1), output(session))
+
+			Expect(string(session.Out.Contents())).To(MatchRegexp(`org:repo:linux:amd64\s+missing`))
+			Expect(string(session.Out.Contents())).NotTo(ContainSubstring("@1.1.0"))
+			Expect(string(session.Err.Contents())).To(ContainSubstring("freezer: 1 of 1 cached buildpacks failed verification"))
+		})
+	})
+}
@@ -0,0 +1,79 @@
+package freezer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+
+	"github.com/paketo-buildpacks/packit/v2/pexec"
+)
+
+// ErrPackagerMissing is matched, with errors.Is, by the errors returned when an
+// executable needed to package a buildpack, such as jam or pack, is not
+// installed.
+var ErrPackagerMissing = errors.New("packager is not installed")
+
+// CommandExecutable is an executable on the $PATH, like pexec.Executable,
+// whose executions are killed when their context is done.
+type CommandExecutable struct {
+	name string
+}
+
+func NewCommandExecutable(name string) CommandExecutable {
+	return CommandExecutable{
+		name: name,
+	}
+}
+
+func (e CommandExecutable) Execute(execution pexec.Execution) error {
+	return e.ExecuteContext(context.Background(), execution)
+}
+
+// ExecuteContext invokes the executable with a set of Execution arguments,
+// killing it if ctx is done before it exits. The executable is looked up on
+// the $PATH of the current process.
+func (e CommandExecutable) ExecuteContext(ctx context.Context, execution pexec.Execution) error {
+	executable, err := exec.LookPath(e.name)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrPackagerMissing, err)
+	}
+
+	cmd := exec.CommandContext(ctx, executable, execution.Args...)
+
+	if execution.Dir != "" {
+		cmd.Dir = execution.Dir
+	}
+
+	if len(execution.Env) > 0 {
+		cmd.Env = execution.Env
+	}
+
+	cmd.Stdout = execution.Stdout
+	cmd.Stderr = execution.Stderr
+
+	err = cmd.Run()
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	return err
+}
+
+// executableContext returns executable itself when it implements
+// ExecutableContext and otherwise wraps it in one that ignores contexts.
+func executableContext(executable Executable) ExecutableContext {
+	if e, ok := executable.(ExecutableContext); ok {
+		return e
+	}
+
+	return backgroundExecutable{executable}
+}
+
+type backgroundExecutable struct {
+	Executable
+}
+
+func (e backgroundExecutable) ExecuteContext(_ context.Context, execution pexec.Execution) error {
+	return e.Execute(execution)
+}
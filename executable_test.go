@@ -0,0 +1,67 @@
+package freezer_test
+
+import (
+	"bytes"
+	gocontext "context"
+	"testing"
+	"time"
+
+	"github.com/ForestEckhardt/freezer"
+	"github.com/paketo-buildpacks/packit/v2/pexec"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testCommandExecutable(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		executable freezer.CommandExecutable
+	)
+
+	it.Before(func() {
+		executable = freezer.NewCommandExecutable("sh")
+	})
+
+	context("ExecuteContext", func() {
+		it("runs the executable", func() {
+			buffer := bytes.NewBuffer(nil)
+			err := executable.ExecuteContext(gocontext.Background(), pexec.Execution{
+				Args:   []string{"-c", "echo some-output"},
+				Stdout: buffer,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(buffer.String()).To(Equal("some-output\n"))
+		})
+
+		context("failure cases", func() {
+			context("when the context is done before the executable exits", func() {
+				it("kills it and returns the context error", func() {
+					ctx, cancel := gocontext.WithTimeout(gocontext.Background(), 100*time.Millisecond)
+					defer cancel()
+
+					start := time.Now()
+					err := executable.ExecuteContext(ctx, pexec.Execution{
+						Args: []string{"-c", "sleep 10"},
+					})
+					Expect(err).To(MatchError(gocontext.DeadlineExceeded))
+					Expect(time.Since(start)).To(BeNumerically("<", 5*time.Second))
+				})
+			})
+
+			context("when the executable cannot be found", func() {
+				it.Before(func() {
+					executable = freezer.NewCommandExecutable("some-missing-executable")
+				})
+
+				it("returns an error", func() {
+					err := executable.Execute(pexec.Execution{})
+					Expect(err).To(MatchError(ContainSubstring("executable file not found")))
+					Expect(err).To(MatchError(freezer.ErrPackagerMissing))
+				})
+			})
+		})
+	})
+}
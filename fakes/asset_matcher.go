@@ -0,0 +1,36 @@
+package fakes
+
+import (
+	"sync"
+
+	"github.com/ForestEckhardt/freezer"
+	"github.com/ForestEckhardt/freezer/github"
+)
+
+type AssetMatcher struct {
+	MatchCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Buildpack freezer.RemoteBuildpack
+			Release   github.Release
+		}
+		Returns struct {
+			ReleaseAsset github.ReleaseAsset
+			Error        error
+		}
+		Stub func(freezer.RemoteBuildpack, github.Release) (github.ReleaseAsset, error)
+	}
+}
+
+func (f *AssetMatcher) Match(param1 freezer.RemoteBuildpack, param2 github.Release) (github.ReleaseAsset, error) {
+	f.MatchCall.mutex.Lock()
+	defer f.MatchCall.mutex.Unlock()
+	f.MatchCall.CallCount++
+	f.MatchCall.Receives.Buildpack = param1
+	f.MatchCall.Receives.Release = param2
+	if f.MatchCall.Stub != nil {
+		return f.MatchCall.Stub(param1, param2)
+	}
+	return f.MatchCall.Returns.ReleaseAsset, f.MatchCall.Returns.Error
+}
@@ -28,6 +28,17 @@ type BuildpackCache struct {
 		}
 		Stub func(string) (freezer.CacheEntry, bool, error)
 	}
+	LockCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Key string
+		}
+		Returns struct {
+			Error error
+		}
+		Stub func(string) error
+	}
 	SetCall struct {
 		mutex     sync.Mutex
 		CallCount int
@@ -40,6 +51,17 @@ type BuildpackCache struct {
 		}
 		Stub func(string, freezer.CacheEntry) error
 	}
+	UnlockCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Key string
+		}
+		Returns struct {
+			Error error
+		}
+		Stub func(string) error
+	}
 }
 
 func (f *BuildpackCache) Dir() string {
@@ -61,6 +83,16 @@ func (f *BuildpackCache) Get(param1 string) (freezer.CacheEntry, bool, error) {
 	}
 	return f.GetCall.Returns.CacheEntry, f.GetCall.Returns.Bool, f.GetCall.Returns.Error
 }
+func (f *BuildpackCache) Lock(param1 string) error {
+	f.LockCall.mutex.Lock()
+	defer f.LockCall.mutex.Unlock()
+	f.LockCall.CallCount++
+	f.LockCall.Receives.Key = param1
+	if f.LockCall.Stub != nil {
+		return f.LockCall.Stub(param1)
+	}
+	return f.LockCall.Returns.Error
+}
 func (f *BuildpackCache) Set(param1 string, param2 freezer.CacheEntry) error {
 	f.SetCall.mutex.Lock()
 	defer f.SetCall.mutex.Unlock()
@@ -72,3 +104,13 @@ func (f *BuildpackCache) Set(param1 string, param2 freezer.CacheEntry) error {
 	}
 	return f.SetCall.Returns.Error
 }
+func (f *BuildpackCache) Unlock(param1 string) error {
+	f.UnlockCall.mutex.Lock()
+	defer f.UnlockCall.mutex.Unlock()
+	f.UnlockCall.CallCount++
+	f.UnlockCall.Receives.Key = param1
+	if f.UnlockCall.Stub != nil {
+		return f.UnlockCall.Stub(param1)
+	}
+	return f.UnlockCall.Returns.Error
+}
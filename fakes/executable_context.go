@@ -0,0 +1,56 @@
+package fakes
+
+import (
+	"context"
+	"sync"
+
+	"github.com/paketo-buildpacks/packit/v2/pexec"
+)
+
+type ExecutableContext struct {
+	ExecuteCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Execution pexec.Execution
+		}
+		Returns struct {
+			Error error
+		}
+		Stub func(pexec.Execution) error
+	}
+	ExecuteContextCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Context   context.Context
+			Execution pexec.Execution
+		}
+		Returns struct {
+			Error error
+		}
+		Stub func(context.Context, pexec.Execution) error
+	}
+}
+
+func (f *ExecutableContext) Execute(param1 pexec.Execution) error {
+	f.ExecuteCall.mutex.Lock()
+	defer f.ExecuteCall.mutex.Unlock()
+	f.ExecuteCall.CallCount++
+	f.ExecuteCall.Receives.Execution = param1
+	if f.ExecuteCall.Stub != nil {
+		return f.ExecuteCall.Stub(param1)
+	}
+	return f.ExecuteCall.Returns.Error
+}
+func (f *ExecutableContext) ExecuteContext(param1 context.Context, param2 pexec.Execution) error {
+	f.ExecuteContextCall.mutex.Lock()
+	defer f.ExecuteContextCall.mutex.Unlock()
+	f.ExecuteContextCall.CallCount++
+	f.ExecuteContextCall.Receives.Context = param1
+	f.ExecuteContextCall.Receives.Execution = param2
+	if f.ExecuteContextCall.Stub != nil {
+		return f.ExecuteContextCall.Stub(param1, param2)
+	}
+	return f.ExecuteContextCall.Returns.Error
+}
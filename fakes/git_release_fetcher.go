@@ -33,6 +33,20 @@ type GitReleaseFetcher struct {
 		}
 		Stub func(github.ReleaseAsset) (io.ReadCloser, error)
 	}
+	GetReleaseByTagCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Org  string
+			Repo string
+			Tag  string
+		}
+		Returns struct {
+			Release github.Release
+			Error   error
+		}
+		Stub func(string, string, string) (github.Release, error)
+	}
 	GetReleaseTarballCall struct {
 		mutex     sync.Mutex
 		CallCount int
@@ -45,6 +59,19 @@ type GitReleaseFetcher struct {
 		}
 		Stub func(string) (io.ReadCloser, error)
 	}
+	ListCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Org  string
+			Repo string
+		}
+		Returns struct {
+			ReleaseSlice []github.Release
+			Error        error
+		}
+		Stub func(string, string) ([]github.Release, error)
+	}
 }
 
 func (f *GitReleaseFetcher) Get(param1 string, param2 string) (github.Release, error) {
@@ -68,6 +95,18 @@ func (f *GitReleaseFetcher) GetReleaseAsset(param1 github.ReleaseAsset) (io.Read
 	}
 	return f.GetReleaseAssetCall.Returns.ReadCloser, f.GetReleaseAssetCall.Returns.Error
 }
+func (f *GitReleaseFetcher) GetReleaseByTag(param1 string, param2 string, param3 string) (github.Release, error) {
+	f.GetReleaseByTagCall.mutex.Lock()
+	defer f.GetReleaseByTagCall.mutex.Unlock()
+	f.GetReleaseByTagCall.CallCount++
+	f.GetReleaseByTagCall.Receives.Org = param1
+	f.GetReleaseByTagCall.Receives.Repo = param2
+	f.GetReleaseByTagCall.Receives.Tag = param3
+	if f.GetReleaseByTagCall.Stub != nil {
+		return f.GetReleaseByTagCall.Stub(param1, param2, param3)
+	}
+	return f.GetReleaseByTagCall.Returns.Release, f.GetReleaseByTagCall.Returns.Error
+}
 func (f *GitReleaseFetcher) GetReleaseTarball(param1 string) (io.ReadCloser, error) {
 	f.GetReleaseTarballCall.mutex.Lock()
 	defer f.GetReleaseTarballCall.mutex.Unlock()
@@ -78,3 +117,14 @@ func (f *GitReleaseFetcher) GetReleaseTarball(param1 string) (io.ReadCloser, err
 	}
 	return f.GetReleaseTarballCall.Returns.ReadCloser, f.GetReleaseTarballCall.Returns.Error
 }
+func (f *GitReleaseFetcher) List(param1 string, param2 string) ([]github.Release, error) {
+	f.ListCall.mutex.Lock()
+	defer f.ListCall.mutex.Unlock()
+	f.ListCall.CallCount++
+	f.ListCall.Receives.Org = param1
+	f.ListCall.Receives.Repo = param2
+	if f.ListCall.Stub != nil {
+		return f.ListCall.Stub(param1, param2)
+	}
+	return f.ListCall.Returns.ReleaseSlice, f.ListCall.Returns.Error
+}
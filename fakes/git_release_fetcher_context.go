@@ -0,0 +1,289 @@
+package fakes
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/ForestEckhardt/freezer/github"
+)
+
+type GitReleaseFetcherContext struct {
+	GetCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Org  string
+			Repo string
+		}
+		Returns struct {
+			Release github.Release
+			Error   error
+		}
+		Stub func(string, string) (github.Release, error)
+	}
+	GetContextCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Ctx  context.Context
+			Org  string
+			Repo string
+		}
+		Returns struct {
+			Release github.Release
+			Error   error
+		}
+		Stub func(context.Context, string, string) (github.Release, error)
+	}
+	GetIfModifiedContextCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Ctx          context.Context
+			Org          string
+			Repo         string
+			Etag         string
+			LastModified string
+		}
+		Returns struct {
+			Release github.Release
+			Error   error
+		}
+		Stub func(context.Context, string, string, string, string) (github.Release, error)
+	}
+	GetReleaseAssetCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Asset github.ReleaseAsset
+		}
+		Returns struct {
+			ReadCloser io.ReadCloser
+			Error      error
+		}
+		Stub func(github.ReleaseAsset) (io.ReadCloser, error)
+	}
+	GetReleaseAssetContextCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Ctx   context.Context
+			Asset github.ReleaseAsset
+		}
+		Returns struct {
+			ReadCloser io.ReadCloser
+			Error      error
+		}
+		Stub func(context.Context, github.ReleaseAsset) (io.ReadCloser, error)
+	}
+	GetReleaseByTagCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Org  string
+			Repo string
+			Tag  string
+		}
+		Returns struct {
+			Release github.Release
+			Error   error
+		}
+		Stub func(string, string, string) (github.Release, error)
+	}
+	GetReleaseByTagContextCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Ctx  context.Context
+			Org  string
+			Repo string
+			Tag  string
+		}
+		Returns struct {
+			Release github.Release
+			Error   error
+		}
+		Stub func(context.Context, string, string, string) (github.Release, error)
+	}
+	GetReleaseTarballCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Url string
+		}
+		Returns struct {
+			ReadCloser io.ReadCloser
+			Error      error
+		}
+		Stub func(string) (io.ReadCloser, error)
+	}
+	GetReleaseTarballContextCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Ctx context.Context
+			Url string
+		}
+		Returns struct {
+			ReadCloser io.ReadCloser
+			Error      error
+		}
+		Stub func(context.Context, string) (io.ReadCloser, error)
+	}
+	ListCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Org  string
+			Repo string
+		}
+		Returns struct {
+			ReleaseSlice []github.Release
+			Error        error
+		}
+		Stub func(string, string) ([]github.Release, error)
+	}
+	ListContextCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Ctx  context.Context
+			Org  string
+			Repo string
+		}
+		Returns struct {
+			ReleaseSlice []github.Release
+			Error        error
+		}
+		Stub func(context.Context, string, string) ([]github.Release, error)
+	}
+}
+
+func (f *GitReleaseFetcherContext) Get(param1 string, param2 string) (github.Release, error) {
+	f.GetCall.mutex.Lock()
+	defer f.GetCall.mutex.Unlock()
+	f.GetCall.CallCount++
+	f.GetCall.Receives.Org = param1
+	f.GetCall.Receives.Repo = param2
+	if f.GetCall.Stub != nil {
+		return f.GetCall.Stub(param1, param2)
+	}
+	return f.GetCall.Returns.Release, f.GetCall.Returns.Error
+}
+func (f *GitReleaseFetcherContext) GetContext(param1 context.Context, param2 string, param3 string) (github.Release, error) {
+	f.GetContextCall.mutex.Lock()
+	defer f.GetContextCall.mutex.Unlock()
+	f.GetContextCall.CallCount++
+	f.GetContextCall.Receives.Ctx = param1
+	f.GetContextCall.Receives.Org = param2
+	f.GetContextCall.Receives.Repo = param3
+	if f.GetContextCall.Stub != nil {
+		return f.GetContextCall.Stub(param1, param2, param3)
+	}
+	return f.GetContextCall.Returns.Release, f.GetContextCall.Returns.Error
+}
+func (f *GitReleaseFetcherContext) GetIfModifiedContext(param1 context.Context, param2 string, param3 string, param4 string, param5 string) (github.Release, error) {
+	f.GetIfModifiedContextCall.mutex.Lock()
+	defer f.GetIfModifiedContextCall.mutex.Unlock()
+	f.GetIfModifiedContextCall.CallCount++
+	f.GetIfModifiedContextCall.Receives.Ctx = param1
+	f.GetIfModifiedContextCall.Receives.Org = param2
+	f.GetIfModifiedContextCall.Receives.Repo = param3
+	f.GetIfModifiedContextCall.Receives.Etag = param4
+	f.GetIfModifiedContextCall.Receives.LastModified = param5
+	if f.GetIfModifiedContextCall.Stub != nil {
+		return f.GetIfModifiedContextCall.Stub(param1, param2, param3, param4, param5)
+	}
+	return f.GetIfModifiedContextCall.Returns.Release, f.GetIfModifiedContextCall.Returns.Error
+}
+func (f *GitReleaseFetcherContext) GetReleaseAsset(param1 github.ReleaseAsset) (io.ReadCloser, error) {
+	f.GetReleaseAssetCall.mutex.Lock()
+	defer f.GetReleaseAssetCall.mutex.Unlock()
+	f.GetReleaseAssetCall.CallCount++
+	f.GetReleaseAssetCall.Receives.Asset = param1
+	if f.GetReleaseAssetCall.Stub != nil {
+		return f.GetReleaseAssetCall.Stub(param1)
+	}
+	return f.GetReleaseAssetCall.Returns.ReadCloser, f.GetReleaseAssetCall.Returns.Error
+}
+func (f *GitReleaseFetcherContext) GetReleaseAssetContext(param1 context.Context, param2 github.ReleaseAsset) (io.ReadCloser, error) {
+	f.GetReleaseAssetContextCall.mutex.Lock()
+	defer f.GetReleaseAssetContextCall.mutex.Unlock()
+	f.GetReleaseAssetContextCall.CallCount++
+	f.GetReleaseAssetContextCall.Receives.Ctx = param1
+	f.GetReleaseAssetContextCall.Receives.Asset = param2
+	if f.GetReleaseAssetContextCall.Stub != nil {
+		return f.GetReleaseAssetContextCall.Stub(param1, param2)
+	}
+	return f.GetReleaseAssetContextCall.Returns.ReadCloser, f.GetReleaseAssetContextCall.Returns.Error
+}
+func (f *GitReleaseFetcherContext) GetReleaseByTag(param1 string, param2 string, param3 string) (github.Release, error) {
+	f.GetReleaseByTagCall.mutex.Lock()
+	defer f.GetReleaseByTagCall.mutex.Unlock()
+	f.GetReleaseByTagCall.CallCount++
+	f.GetReleaseByTagCall.Receives.Org = param1
+	f.GetReleaseByTagCall.Receives.Repo = param2
+	f.GetReleaseByTagCall.Receives.Tag = param3
+	if f.GetReleaseByTagCall.Stub != nil {
+		return f.GetReleaseByTagCall.Stub(param1, param2, param3)
+	}
+	return f.GetReleaseByTagCall.Returns.Release, f.GetReleaseByTagCall.Returns.Error
+}
+func (f *GitReleaseFetcherContext) GetReleaseByTagContext(param1 context.Context, param2 string, param3 string, param4 string) (github.Release, error) {
+	f.GetReleaseByTagContextCall.mutex.Lock()
+	defer f.GetReleaseByTagContextCall.mutex.Unlock()
+	f.GetReleaseByTagContextCall.CallCount++
+	f.GetReleaseByTagContextCall.Receives.Ctx = param1
+	f.GetReleaseByTagContextCall.Receives.Org = param2
+	f.GetReleaseByTagContextCall.Receives.Repo = param3
+	f.GetReleaseByTagContextCall.Receives.Tag = param4
+	if f.GetReleaseByTagContextCall.Stub != nil {
+		return f.GetReleaseByTagContextCall.Stub(param1, param2, param3, param4)
+	}
+	return f.GetReleaseByTagContextCall.Returns.Release, f.GetReleaseByTagContextCall.Returns.Error
+}
+func (f *GitReleaseFetcherContext) GetReleaseTarball(param1 string) (io.ReadCloser, error) {
+	f.GetReleaseTarballCall.mutex.Lock()
+	defer f.GetReleaseTarballCall.mutex.Unlock()
+	f.GetReleaseTarballCall.CallCount++
+	f.GetReleaseTarballCall.Receives.Url = param1
+	if f.GetReleaseTarballCall.Stub != nil {
+		return f.GetReleaseTarballCall.Stub(param1)
+	}
+	return f.GetReleaseTarballCall.Returns.ReadCloser, f.GetReleaseTarballCall.Returns.Error
+}
+func (f *GitReleaseFetcherContext) GetReleaseTarballContext(param1 context.Context, param2 string) (io.ReadCloser, error) {
+	f.GetReleaseTarballContextCall.mutex.Lock()
+	defer f.GetReleaseTarballContextCall.mutex.Unlock()
+	f.GetReleaseTarballContextCall.CallCount++
+	f.GetReleaseTarballContextCall.Receives.Ctx = param1
+	f.GetReleaseTarballContextCall.Receives.Url = param2
+	if f.GetReleaseTarballContextCall.Stub != nil {
+		return f.GetReleaseTarballContextCall.Stub(param1, param2)
+	}
+	return f.GetReleaseTarballContextCall.Returns.ReadCloser, f.GetReleaseTarballContextCall.Returns.Error
+}
+func (f *GitReleaseFetcherContext) List(param1 string, param2 string) ([]github.Release, error) {
+	f.ListCall.mutex.Lock()
+	defer f.ListCall.mutex.Unlock()
+	f.ListCall.CallCount++
+	f.ListCall.Receives.Org = param1
+	f.ListCall.Receives.Repo = param2
+	if f.ListCall.Stub != nil {
+		return f.ListCall.Stub(param1, param2)
+	}
+	return f.ListCall.Returns.ReleaseSlice, f.ListCall.Returns.Error
+}
+func (f *GitReleaseFetcherContext) ListContext(param1 context.Context, param2 string, param3 string) ([]github.Release, error) {
+	f.ListContextCall.mutex.Lock()
+	defer f.ListContextCall.mutex.Unlock()
+	f.ListContextCall.CallCount++
+	f.ListContextCall.Receives.Ctx = param1
+	f.ListContextCall.Receives.Org = param2
+	f.ListContextCall.Receives.Repo = param3
+	if f.ListContextCall.Stub != nil {
+		return f.ListContextCall.Stub(param1, param2, param3)
+	}
+	return f.ListContextCall.Returns.ReleaseSlice, f.ListContextCall.Returns.Error
+}
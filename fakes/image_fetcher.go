@@ -0,0 +1,91 @@
+package fakes
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/ForestEckhardt/freezer/registry"
+)
+
+type ImageFetcher struct {
+	BlobContextCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Ctx  context.Context
+			Ref  registry.Reference
+			Blob registry.Descriptor
+		}
+		Returns struct {
+			ReadCloser io.ReadCloser
+			Error      error
+		}
+		Stub func(context.Context, registry.Reference, registry.Descriptor) (io.ReadCloser, error)
+	}
+	DigestContextCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Ctx context.Context
+			Ref registry.Reference
+		}
+		Returns struct {
+			String string
+			Error  error
+		}
+		Stub func(context.Context, registry.Reference) (string, error)
+	}
+	ResolveContextCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Ctx      context.Context
+			Ref      registry.Reference
+			Platform string
+			Arch     string
+		}
+		Returns struct {
+			Image registry.Image
+			Error error
+		}
+		Stub func(context.Context, registry.Reference, string, string) (registry.Image, error)
+	}
+}
+
+func (f *ImageFetcher) BlobContext(param1 context.Context, param2 registry.Reference, param3 registry.Descriptor) (io.ReadCloser, error) {
+	f.BlobContextCall.mutex.Lock()
+	defer f.BlobContextCall.mutex.Unlock()
+	f.BlobContextCall.CallCount++
+	f.BlobContextCall.Receives.Ctx = param1
+	f.BlobContextCall.Receives.Ref = param2
+	f.BlobContextCall.Receives.Blob = param3
+	if f.BlobContextCall.Stub != nil {
+		return f.BlobContextCall.Stub(param1, param2, param3)
+	}
+	return f.BlobContextCall.Returns.ReadCloser, f.BlobContextCall.Returns.Error
+}
+func (f *ImageFetcher) DigestContext(param1 context.Context, param2 registry.Reference) (string, error) {
+	f.DigestContextCall.mutex.Lock()
+	defer f.DigestContextCall.mutex.Unlock()
+	f.DigestContextCall.CallCount++
+	f.DigestContextCall.Receives.Ctx = param1
+	f.DigestContextCall.Receives.Ref = param2
+	if f.DigestContextCall.Stub != nil {
+		return f.DigestContextCall.Stub(param1, param2)
+	}
+	return f.DigestContextCall.Returns.String, f.DigestContextCall.Returns.Error
+}
+func (f *ImageFetcher) ResolveContext(param1 context.Context, param2 registry.Reference, param3 string, param4 string) (registry.Image, error) {
+	f.ResolveContextCall.mutex.Lock()
+	defer f.ResolveContextCall.mutex.Unlock()
+	f.ResolveContextCall.CallCount++
+	f.ResolveContextCall.Receives.Ctx = param1
+	f.ResolveContextCall.Receives.Ref = param2
+	f.ResolveContextCall.Receives.Platform = param3
+	f.ResolveContextCall.Receives.Arch = param4
+	if f.ResolveContextCall.Stub != nil {
+		return f.ResolveContextCall.Stub(param1, param2, param3, param4)
+	}
+	return f.ResolveContextCall.Returns.Image, f.ResolveContextCall.Returns.Error
+}
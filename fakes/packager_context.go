@@ -0,0 +1,66 @@
+package fakes
+
+import (
+	"context"
+	"sync"
+)
+
+type PackagerContext struct {
+	ExecuteCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			BuildpackDir string
+			Output       string
+			Version      string
+			Cached       bool
+		}
+		Returns struct {
+			Error error
+		}
+		Stub func(string, string, string, bool) error
+	}
+	ExecuteContextCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Ctx          context.Context
+			BuildpackDir string
+			Output       string
+			Version      string
+			Cached       bool
+		}
+		Returns struct {
+			Error error
+		}
+		Stub func(context.Context, string, string, string, bool) error
+	}
+}
+
+func (f *PackagerContext) Execute(param1 string, param2 string, param3 string, param4 bool) error {
+	f.ExecuteCall.mutex.Lock()
+	defer f.ExecuteCall.mutex.Unlock()
+	f.ExecuteCall.CallCount++
+	f.ExecuteCall.Receives.BuildpackDir = param1
+	f.ExecuteCall.Receives.Output = param2
+	f.ExecuteCall.Receives.Version = param3
+	f.ExecuteCall.Receives.Cached = param4
+	if f.ExecuteCall.Stub != nil {
+		return f.ExecuteCall.Stub(param1, param2, param3, param4)
+	}
+	return f.ExecuteCall.Returns.Error
+}
+func (f *PackagerContext) ExecuteContext(param1 context.Context, param2 string, param3 string, param4 string, param5 bool) error {
+	f.ExecuteContextCall.mutex.Lock()
+	defer f.ExecuteContextCall.mutex.Unlock()
+	f.ExecuteContextCall.CallCount++
+	f.ExecuteContextCall.Receives.Ctx = param1
+	f.ExecuteContextCall.Receives.BuildpackDir = param2
+	f.ExecuteContextCall.Receives.Output = param3
+	f.ExecuteContextCall.Receives.Version = param4
+	f.ExecuteContextCall.Receives.Cached = param5
+	if f.ExecuteContextCall.Stub != nil {
+		return f.ExecuteContextCall.Stub(param1, param2, param3, param4, param5)
+	}
+	return f.ExecuteContextCall.Returns.Error
+}
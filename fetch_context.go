@@ -0,0 +1,101 @@
+package freezer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ForestEckhardt/freezer/github"
+)
+
+// contextReader fails reads once its context is done so that copying or
+// decompressing a download stops promptly rather than running to completion.
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	err := r.ctx.Err()
+	if err != nil {
+		return 0, err
+	}
+
+	return r.reader.Read(p)
+}
+
+// contextError names the buildpack being fetched in err when the fetch failed
+// because ctx is done, keeping the context error in the chain so that callers
+// can still match it with errors.Is.
+func contextError(ctx context.Context, buildpack string, err error) error {
+	ctxErr := ctx.Err()
+	if err == nil || ctxErr == nil {
+		return err
+	}
+
+	if errors.Is(err, ctxErr) {
+		return fmt.Errorf("fetching buildpack %s: %w", buildpack, err)
+	}
+
+	return fmt.Errorf("fetching buildpack %s: %w: %s", buildpack, ctxErr, err)
+}
+
+// gitReleaseFetcherContext returns gitReleaseFetcher itself when it implements
+// GitReleaseFetcherContext and otherwise wraps it in one that ignores contexts
+// and validators.
+func gitReleaseFetcherContext(gitReleaseFetcher GitReleaseFetcher) GitReleaseFetcherContext {
+	if fetcher, ok := gitReleaseFetcher.(GitReleaseFetcherContext); ok {
+		return fetcher
+	}
+
+	return backgroundGitReleaseFetcher{gitReleaseFetcher}
+}
+
+type backgroundGitReleaseFetcher struct {
+	GitReleaseFetcher
+}
+
+func (f backgroundGitReleaseFetcher) GetContext(_ context.Context, org, repo string) (github.Release, error) {
+	return f.Get(org, repo)
+}
+
+func (f backgroundGitReleaseFetcher) GetReleaseByTagContext(_ context.Context, org, repo, tag string) (github.Release, error) {
+	return f.GetReleaseByTag(org, repo, tag)
+}
+
+func (f backgroundGitReleaseFetcher) GetReleaseAssetContext(_ context.Context, asset github.ReleaseAsset) (io.ReadCloser, error) {
+	return f.GetReleaseAsset(asset)
+}
+
+func (f backgroundGitReleaseFetcher) GetReleaseTarballContext(_ context.Context, url string) (io.ReadCloser, error) {
+	return f.GetReleaseTarball(url)
+}
+
+// GetIfModifiedContext fetches the latest release in full, which is still
+// correct as the fetcher compares it to the cached one.
+func (f backgroundGitReleaseFetcher) GetIfModifiedContext(_ context.Context, org, repo, _, _ string) (github.Release, error) {
+	return f.Get(org, repo)
+}
+
+func (f backgroundGitReleaseFetcher) ListContext(_ context.Context, org, repo string) ([]github.Release, error) {
+	return f.List(org, repo)
+}
+
+// packagerContext returns packager itself when it implements PackagerContext
+// and otherwise wraps it in one that ignores contexts.
+func packagerContext(packager Packager) PackagerContext {
+	if p, ok := packager.(PackagerContext); ok {
+		return p
+	}
+
+	return backgroundPackager{packager}
+}
+
+type backgroundPackager struct {
+	Packager
+}
+
+func (p backgroundPackager) ExecuteContext(_ context.Context, buildpackDir, output, version string, cached bool) error {
+	return p.Execute(buildpackDir, output, version, cached)
+}
@@ -0,0 +1,351 @@
+package freezer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/ForestEckhardt/freezer/gitea"
+	"github.com/ForestEckhardt/freezer/github"
+	"github.com/ForestEckhardt/freezer/gitlab"
+	"github.com/ForestEckhardt/freezer/registry"
+	"github.com/Masterminds/semver/v3"
+)
+
+// FetchMode determines whether a fetched buildpack is packaged for offline
+// (cached) or online (uncached) use.
+type FetchMode int
+
+const (
+	Uncached FetchMode = iota
+	Cached
+)
+
+const githubHost = "github.com"
+
+var checksumPattern = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
+type Fetcher struct {
+	cacheManager    *CacheManager
+	releaseFetchers map[string]GitReleaseFetcher
+	imageFetcher    ImageFetcher
+	httpClient      *http.Client
+	packager        Packager
+	namer           Namer
+	assetMatcher    AssetMatcher
+	offlineMode     bool
+	ttl             time.Duration
+	forceRefresh    bool
+
+	migrateLegacyCache bool
+}
+
+func NewFetcher() Fetcher {
+	cacheManager := NewCacheManager(DefaultCacheDir())
+
+	return Fetcher{
+		cacheManager: &cacheManager,
+		releaseFetchers: map[string]GitReleaseFetcher{
+			githubHost:     github.NewReleaseService(github.NewConfig("https://api.github.com", os.Getenv("GIT_TOKEN"))),
+			"gitlab.com":   gitlab.NewReleaseService(gitlab.NewConfig("https://gitlab.com/api/v4", os.Getenv("GITLAB_TOKEN"))),
+			"codeberg.org": gitea.NewReleaseService(gitea.NewConfig("https://codeberg.org/api/v1", os.Getenv("GITEA_TOKEN"))),
+		},
+		imageFetcher: registry.NewClient(registry.NewConfig()),
+		httpClient:   defaultURLClient,
+		packager:     NewPackingTools(),
+		namer:        NewNameGenerator(),
+		assetMatcher: NewPatternAssetMatcher(),
+		offlineMode:  offlineModeFromEnv(),
+
+		migrateLegacyCache: true,
+	}
+}
+
+func (f Fetcher) WithCacheDir(cacheDir string) Fetcher {
+	cacheManager := NewCacheManager(cacheDir)
+	f.cacheManager = &cacheManager
+	f.migrateLegacyCache = false
+	return f
+}
+
+// WithGitReleaseFetcher sets how releases of buildpacks referenced as
+// github.com/<org>/<repo> are fetched.
+func (f Fetcher) WithGitReleaseFetcher(gitReleaseFetcher GitReleaseFetcher) Fetcher {
+	return f.WithReleaseHost(githubHost, gitReleaseFetcher)
+}
+
+// WithReleaseHost fetches the releases of buildpacks referenced as
+// <host>/<org>/<repo> with gitReleaseFetcher, for example a
+// github.ReleaseService for a GitHub Enterprise Server or a
+// gitlab.ReleaseService for a self-managed GitLab instance. github.com,
+// gitlab.com and codeberg.org are known by default, authenticated with the
+// GIT_TOKEN, GITLAB_TOKEN and GITEA_TOKEN environment variables respectively.
+func (f Fetcher) WithReleaseHost(host string, gitReleaseFetcher GitReleaseFetcher) Fetcher {
+	releaseFetchers := map[string]GitReleaseFetcher{}
+	for h, fetcher := range f.releaseFetchers {
+		releaseFetchers[h] = fetcher
+	}
+	releaseFetchers[host] = gitReleaseFetcher
+
+	f.releaseFetchers = releaseFetchers
+	return f
+}
+
+// WithImageFetcher sets how buildpacks published as images are pulled from
+// their registry, for example to use a registry.Client with credentials.
+func (f Fetcher) WithImageFetcher(imageFetcher ImageFetcher) Fetcher {
+	f.imageFetcher = imageFetcher
+	return f
+}
+
+// WithHTTPClient sets the client buildpacks referenced by an http or https URL
+// are downloaded with.
+func (f Fetcher) WithHTTPClient(client *http.Client) Fetcher {
+	f.httpClient = client
+	return f
+}
+
+func (f Fetcher) WithPackager(packager Packager) Fetcher {
+	f.packager = packager
+	return f
+}
+
+func (f Fetcher) WithNamer(namer Namer) Fetcher {
+	f.namer = namer
+	return f
+}
+
+// WithAssetMatcher sets how the release asset of a remote buildpack to
+// download is chosen.
+func (f Fetcher) WithAssetMatcher(assetMatcher AssetMatcher) Fetcher {
+	f.assetMatcher = assetMatcher
+	return f
+}
+
+// WithOfflineMode determines whether remote buildpacks are only resolved from
+// the cache, failing with ErrNotCached for those that are not in it, rather
+// than fetched from GitHub. It defaults to the value of FREEZER_OFFLINE. Local
+// buildpacks are unaffected.
+func (f Fetcher) WithOfflineMode(offlineMode bool) Fetcher {
+	f.offlineMode = offlineMode
+	return f
+}
+
+// WithTTL sets the freshness window within which remote buildpacks without a
+// pinned version are served from the cache without asking GitHub for a newer
+// release. See RemoteFetcher.WithTTL.
+func (f Fetcher) WithTTL(ttl time.Duration) Fetcher {
+	f.ttl = ttl
+	return f
+}
+
+// WithForceRefresh determines whether GitHub is asked for a newer release even
+// within the freshness window.
+func (f Fetcher) WithForceRefresh(forceRefresh bool) Fetcher {
+	f.forceRefresh = forceRefresh
+	return f
+}
+
+// Open opens the cache. When the default cache directory is used, a cache in
+// the legacy $HOME/.freezer-cache location is first migrated into it.
+func (f Fetcher) Open() error {
+	if f.migrateLegacyCache {
+		err := MigrateLegacyCacheDir(f.cacheManager.Dir())
+		if err != nil {
+			return err
+		}
+	}
+
+	return f.cacheManager.Open()
+}
+
+func (f Fetcher) Close() error {
+	return f.cacheManager.Close()
+}
+
+// Get fetches the buildpack described by reference and returns the path to
+// the resulting .cnb file. References of the form
+// <host>/<org>/<repo>[@<version>] are fetched from the releases of the forge at
+// host, such as github.com, where version is either an exact release or a
+// semver constraint such as 2.x.
+// References that are http, https or file URLs, or paths to a file rather than
+// a directory, are downloaded and, unless they are a .cnb file, packaged; a
+// fragment of the form #sha256:<hex> pins their checksum.
+// References to images, such as gcr.io/paketo-buildpacks/go:1.2.3 or
+// docker://paketobuildpacks/go, are pulled from their registry whatever the
+// mode, as images cannot be repackaged. Every other reference is treated as a
+// path to a local buildpack directory.
+func (f Fetcher) Get(reference string, mode FetchMode) (string, error) {
+	return f.GetContext(context.Background(), reference, mode)
+}
+
+// GetContext is like Get but abandons the fetch when ctx is done, so that a
+// hung download or packaging step fails with an error naming the buildpack
+// rather than blocking until the test binary times out.
+func (f Fetcher) GetContext(ctx context.Context, reference string, mode FetchMode) (string, error) {
+	if isURLReference(reference) {
+		buildpack, err := parseURLReference(reference)
+		if err != nil {
+			return "", err
+		}
+		buildpack.Offline = mode == Cached
+		buildpack.Version = "testing"
+
+		urlFetcher := NewURLFetcher(f.cacheManager, f.packager).
+			WithClient(f.httpClient).
+			WithOfflineMode(f.offlineMode)
+
+		return urlFetcher.GetContext(ctx, buildpack)
+	}
+
+	host := strings.SplitN(reference, "/", 2)[0]
+	if gitReleaseFetcher, ok := f.releaseFetchers[host]; ok && strings.Contains(reference, "/") {
+		buildpack, err := parseRemoteReference(host, reference)
+		if err != nil {
+			return "", err
+		}
+		buildpack.Offline = mode == Cached
+
+		remoteFetcher := NewRemoteFetcher(f.cacheManager, gitReleaseFetcher, f.packager).
+			WithAssetMatcher(f.assetMatcher).
+			WithOfflineMode(f.offlineMode).
+			WithTTL(f.ttl).
+			WithForceRefresh(f.forceRefresh)
+
+		return remoteFetcher.GetContext(ctx, buildpack)
+	}
+
+	if isImageReference(reference) {
+		ref, err := registry.ParseReference(reference)
+		if err != nil {
+			return "", err
+		}
+
+		//Buildpacks run in Linux containers whatever the platform of the host
+		buildpack := NewRegistryBuildpack(ref, "linux", runtime.GOARCH)
+
+		registryFetcher := NewRegistryFetcher(f.cacheManager, f.imageFetcher).
+			WithOfflineMode(f.offlineMode).
+			WithTTL(f.ttl).
+			WithForceRefresh(f.forceRefresh)
+
+		return registryFetcher.GetContext(ctx, buildpack)
+	}
+
+	path, err := filepath.Abs(reference)
+	if err != nil {
+		return "", err
+	}
+
+	buildpack := NewLocalBuildpack(path, filepath.Base(path))
+	buildpack.Offline = mode == Cached
+	buildpack.Version = "testing"
+
+	return NewLocalFetcher(f.cacheManager, f.packager, f.namer).GetContext(ctx, buildpack)
+}
+
+// parseRemoteReference parses a reference to a buildpack released on host.
+// Only GitHub requires the org to be a single element; GitLab, for one, nests
+// projects in subgroups.
+func parseRemoteReference(host, reference string) (RemoteBuildpack, error) {
+	name := strings.TrimPrefix(reference, host+"/")
+
+	var version string
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, version = name[:i], name[i+1:]
+	}
+
+	parts := strings.Split(name, "/")
+	valid := len(parts) == 2 || (len(parts) > 2 && host != githubHost)
+	for _, part := range parts {
+		valid = valid && part != ""
+	}
+
+	if !valid {
+		return RemoteBuildpack{}, fmt.Errorf("invalid remote buildpack reference %q: expected %s/<org>/<repo>[@<version>]", reference, host)
+	}
+
+	org, repo := strings.Join(parts[:len(parts)-1], "/"), parts[len(parts)-1]
+
+	//Buildpacks run in Linux containers whatever the platform of the host
+	buildpack := NewRemoteBuildpack(org, repo, "linux", runtime.GOARCH)
+	buildpack.Host = host
+
+	//Anything that is not an exact version but is a valid constraint (e.g. 2.x
+	//or ~1.4) selects the highest matching release
+	_, err := semver.StrictNewVersion(strings.TrimPrefix(version, "v"))
+	if version != "" && err != nil {
+		if _, err := semver.NewConstraint(version); err == nil {
+			buildpack.VersionConstraint = version
+			return buildpack, nil
+		}
+	}
+
+	buildpack.Version = version
+
+	return buildpack, nil
+}
+
+// isImageReference reports whether reference refers to an image rather than a
+// local buildpack. A directory that happens to look like an image reference,
+// such as some.dir/buildpack, is still treated as a local buildpack.
+func isImageReference(reference string) bool {
+	if !registry.IsReference(reference) {
+		return false
+	}
+
+	if strings.HasPrefix(reference, "docker://") {
+		return true
+	}
+
+	_, err := os.Stat(reference)
+	return err != nil
+}
+
+// isURLReference reports whether reference is a URL or the path to an archive,
+// rather than to a buildpack directory.
+func isURLReference(reference string) bool {
+	for _, scheme := range []string{"http://", "https://", "file://"} {
+		if strings.HasPrefix(reference, scheme) {
+			return true
+		}
+	}
+
+	info, err := os.Stat(reference)
+	return err == nil && info.Mode().IsRegular()
+}
+
+func parseURLReference(reference string) (URLBuildpack, error) {
+	uri, err := url.Parse(reference)
+	if err != nil {
+		return URLBuildpack{}, err
+	}
+
+	//Paths are read the same way as file URLs
+	if uri.Scheme == "" {
+		path, err := filepath.Abs(reference)
+		if err != nil {
+			return URLBuildpack{}, err
+		}
+
+		uri = &url.URL{Scheme: "file", Path: filepath.ToSlash(path)}
+	}
+
+	checksum := uri.Fragment
+	if checksum != "" && !checksumPattern.MatchString(checksum) {
+		return URLBuildpack{}, fmt.Errorf("invalid buildpack URL %q: expected a checksum of the form #sha256:<hex>", reference)
+	}
+	uri.Fragment = ""
+
+	buildpack := NewURLBuildpack(uri.String())
+	buildpack.Checksum = checksum
+
+	return buildpack, nil
+}
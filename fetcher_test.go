@@ -0,0 +1,517 @@
+package freezer_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	gocontext "context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ForestEckhardt/freezer"
+	"github.com/ForestEckhardt/freezer/fakes"
+	"github.com/ForestEckhardt/freezer/github"
+	"github.com/ForestEckhardt/freezer/registry"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testFetcher(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		cacheDir     string
+		buildpackDir string
+
+		gitReleaseFetcher *fakes.GitReleaseFetcherContext
+		imageFetcher      *fakes.ImageFetcher
+		packager          *fakes.PackagerContext
+		namer             *fakes.Namer
+
+		fetcher freezer.Fetcher
+	)
+
+	it.Before(func() {
+		var err error
+		cacheDir, err = os.MkdirTemp("", "cache")
+		Expect(err).NotTo(HaveOccurred())
+
+		buildpackDir, err = os.MkdirTemp("", "some-buildpack")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.WriteFile(filepath.Join(buildpackDir, "buildpack.toml"), []byte(`api = "0.2"`), 0644)).To(Succeed())
+
+		gitReleaseFetcher = &fakes.GitReleaseFetcherContext{}
+		gitReleaseFetcher.GetContextCall.Returns.Release = github.Release{
+			TagName: "v1.2.3",
+			Assets: []github.ReleaseAsset{
+				{
+					Name: fmt.Sprintf("some-repo-1.2.3-linux-%s.cnb", runtime.GOARCH),
+					URL:  "some-url",
+				},
+			},
+			TarballURL: "some-tarball-url",
+		}
+		gitReleaseFetcher.GetReleaseAssetContextCall.Stub = func(gocontext.Context, github.ReleaseAsset) (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewBufferString("some-asset")), nil
+		}
+
+		imageFetcher = &fakes.ImageFetcher{}
+		imageFetcher.ResolveContextCall.Returns.Image = registry.Image{
+			Digest: "sha256:" + strings.Repeat("1", 64),
+			Manifest: registry.Descriptor{
+				MediaType: registry.MediaTypeOCIManifest,
+				Digest:    "sha256:" + strings.Repeat("2", 64),
+				Size:      int64(len("some-manifest")),
+			},
+			ManifestContent: []byte("some-manifest"),
+			Config:          registry.Descriptor{Digest: "sha256:" + strings.Repeat("3", 64)},
+		}
+		imageFetcher.BlobContextCall.Returns.ReadCloser = io.NopCloser(strings.NewReader(""))
+
+		packager = &fakes.PackagerContext{}
+		packager.ExecuteContextCall.Stub = func(_ gocontext.Context, _, output, _ string, _ bool) error {
+			return os.WriteFile(output, []byte("some-buildpack"), 0644)
+		}
+
+		namer = &fakes.Namer{}
+		namer.RandomNameCall.Stub = func(name string) (string, error) {
+			return fmt.Sprintf("%s-random-string", name), nil
+		}
+
+		fetcher = freezer.NewFetcher().
+			WithCacheDir(cacheDir).
+			WithGitReleaseFetcher(gitReleaseFetcher).
+			WithImageFetcher(imageFetcher).
+			WithPackager(packager).
+			WithNamer(namer)
+
+		Expect(fetcher.Open()).To(Succeed())
+	})
+
+	it.After(func() {
+		Expect(fetcher.Close()).To(Succeed())
+		Expect(os.RemoveAll(cacheDir)).To(Succeed())
+		Expect(os.RemoveAll(buildpackDir)).To(Succeed())
+	})
+
+	context("Get", func() {
+		context("when given a local buildpack path", func() {
+			it("packages an uncached version of the buildpack", func() {
+				uri, err := fetcher.Get(buildpackDir, freezer.Uncached)
+				Expect(err).NotTo(HaveOccurred())
+
+				name := filepath.Base(buildpackDir)
+				Expect(packager.ExecuteContextCall.Receives.BuildpackDir).To(Equal(buildpackDir))
+				Expect(packager.ExecuteContextCall.Receives.Version).To(Equal("testing"))
+				Expect(packager.ExecuteContextCall.Receives.Cached).To(BeFalse())
+
+				Expect(uri).To(Equal(filepath.Join(cacheDir, name, fmt.Sprintf("%s-random-string.cnb", name))))
+				Expect(uri).To(BeAnExistingFile())
+			})
+
+			it("only packages the buildpack again once its source changes", func() {
+				_, err := fetcher.Get(buildpackDir, freezer.Uncached)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = fetcher.Get(buildpackDir, freezer.Uncached)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(packager.ExecuteContextCall.CallCount).To(Equal(1))
+
+				Expect(os.WriteFile(filepath.Join(buildpackDir, "some-file"), []byte("some-content"), 0644)).To(Succeed())
+
+				_, err = fetcher.Get(buildpackDir, freezer.Uncached)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(packager.ExecuteContextCall.CallCount).To(Equal(2))
+			})
+
+			it("packages a cached version of the buildpack", func() {
+				uri, err := fetcher.Get(buildpackDir, freezer.Cached)
+				Expect(err).NotTo(HaveOccurred())
+
+				name := filepath.Base(buildpackDir)
+				Expect(packager.ExecuteContextCall.Receives.Cached).To(BeTrue())
+
+				Expect(uri).To(Equal(filepath.Join(cacheDir, name, "cached", fmt.Sprintf("%s-random-string.cnb", name))))
+			})
+		})
+
+		context("when given a github reference", func() {
+			it("fetches the latest release of the remote buildpack", func() {
+				uri, err := fetcher.Get("github.com/some-org/some-repo", freezer.Uncached)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(gitReleaseFetcher.GetContextCall.Receives.Org).To(Equal("some-org"))
+				Expect(gitReleaseFetcher.GetContextCall.Receives.Repo).To(Equal("some-repo"))
+
+				Expect(uri).To(Equal(filepath.Join(cacheDir, "some-org", "some-repo", "linux", runtime.GOARCH, "1.2.3.cnb")))
+
+				content, err := os.ReadFile(uri)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(content)).To(Equal("some-asset"))
+			})
+
+			context("when the release also has assets for the platforms of non-Linux hosts", func() {
+				it.Before(func() {
+					gitReleaseFetcher.GetContextCall.Returns.Release.Assets = []github.ReleaseAsset{
+						{Name: fmt.Sprintf("some-repo-1.2.3-darwin-%s.cnb", runtime.GOARCH), URL: "some-darwin-url"},
+						{Name: fmt.Sprintf("some-repo-1.2.3-windows-%s.cnb", runtime.GOARCH), URL: "some-windows-url"},
+						{Name: fmt.Sprintf("some-repo-1.2.3-linux-%s.cnb", runtime.GOARCH), URL: "some-linux-url"},
+					}
+				})
+
+				it("fetches the Linux asset whatever the platform of the host", func() {
+					uri, err := fetcher.Get("github.com/some-org/some-repo", freezer.Uncached)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(gitReleaseFetcher.GetReleaseAssetContextCall.Receives.Asset.URL).To(Equal("some-linux-url"))
+					Expect(uri).To(Equal(filepath.Join(cacheDir, "some-org", "some-repo", "linux", runtime.GOARCH, "1.2.3.cnb")))
+				})
+			})
+
+			context("when the cached buildpack has been corrupted", func() {
+				var uri string
+
+				it.Before(func() {
+					var err error
+					uri, err = fetcher.Get("github.com/some-org/some-repo", freezer.Uncached)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(os.WriteFile(uri, []byte("some-ass"), 0644)).To(Succeed())
+				})
+
+				it("fetches the buildpack again", func() {
+					refetchedURI, err := fetcher.Get("github.com/some-org/some-repo", freezer.Uncached)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(refetchedURI).To(Equal(uri))
+
+					Expect(gitReleaseFetcher.GetReleaseAssetContextCall.CallCount).To(Equal(2))
+
+					content, err := os.ReadFile(uri)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(string(content)).To(Equal("some-asset"))
+				})
+			})
+
+			context("when the cached buildpack has validators", func() {
+				var uri string
+
+				it.Before(func() {
+					gitReleaseFetcher.GetContextCall.Returns.Release.ETag = `"some-etag"`
+					gitReleaseFetcher.GetIfModifiedContextCall.Returns.Error = github.ErrNotModified
+
+					var err error
+					uri, err = fetcher.Get("github.com/some-org/some-repo", freezer.Uncached)
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				it("fetches the buildpack again when the file has been deleted", func() {
+					Expect(os.Remove(uri)).To(Succeed())
+
+					refetchedURI, err := fetcher.Get("github.com/some-org/some-repo", freezer.Uncached)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(refetchedURI).To(Equal(uri))
+
+					Expect(gitReleaseFetcher.GetIfModifiedContextCall.CallCount).To(Equal(0))
+					Expect(gitReleaseFetcher.GetReleaseAssetContextCall.CallCount).To(Equal(2))
+
+					content, err := os.ReadFile(uri)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(string(content)).To(Equal("some-asset"))
+				})
+
+				it("fetches the buildpack again when the file has been corrupted", func() {
+					Expect(os.WriteFile(uri, []byte("some-ass"), 0644)).To(Succeed())
+
+					_, err := fetcher.Get("github.com/some-org/some-repo", freezer.Uncached)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(gitReleaseFetcher.GetIfModifiedContextCall.CallCount).To(Equal(0))
+
+					content, err := os.ReadFile(uri)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(string(content)).To(Equal("some-asset"))
+				})
+
+				it("asks whether it has changed while the file is intact", func() {
+					_, err := fetcher.Get("github.com/some-org/some-repo", freezer.Uncached)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(gitReleaseFetcher.GetIfModifiedContextCall.CallCount).To(Equal(1))
+					Expect(gitReleaseFetcher.GetReleaseAssetContextCall.CallCount).To(Equal(1))
+				})
+			})
+
+			context("when the reference includes a version", func() {
+				it.Before(func() {
+					gitReleaseFetcher.GetReleaseByTagContextCall.Returns.Release = gitReleaseFetcher.GetContextCall.Returns.Release
+				})
+
+				it("fetches the release with the given tag", func() {
+					uri, err := fetcher.Get("github.com/some-org/some-repo@v1.2.3", freezer.Uncached)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(gitReleaseFetcher.GetContextCall.CallCount).To(Equal(0))
+					Expect(gitReleaseFetcher.GetReleaseByTagContextCall.Receives.Tag).To(Equal("v1.2.3"))
+
+					Expect(uri).To(Equal(filepath.Join(cacheDir, "some-org", "some-repo", "linux", runtime.GOARCH, "1.2.3.cnb")))
+				})
+			})
+
+			context("when the reference includes a version constraint", func() {
+				it.Before(func() {
+					gitReleaseFetcher.ListContextCall.Returns.ReleaseSlice = []github.Release{gitReleaseFetcher.GetContextCall.Returns.Release}
+				})
+
+				it("fetches the highest matching release", func() {
+					uri, err := fetcher.Get("github.com/some-org/some-repo@1.x", freezer.Uncached)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(gitReleaseFetcher.GetContextCall.CallCount).To(Equal(0))
+					Expect(gitReleaseFetcher.GetReleaseByTagContextCall.CallCount).To(Equal(0))
+					Expect(gitReleaseFetcher.ListContextCall.CallCount).To(Equal(1))
+
+					Expect(uri).To(Equal(filepath.Join(cacheDir, "some-org", "some-repo", "linux", runtime.GOARCH, "1.2.3.cnb")))
+				})
+			})
+
+			context("when a TTL is set", func() {
+				it.Before(func() {
+					fetcher = fetcher.WithTTL(time.Hour)
+				})
+
+				it("only checks github for a new release once within the window", func() {
+					_, err := fetcher.Get("github.com/some-org/some-repo", freezer.Uncached)
+					Expect(err).NotTo(HaveOccurred())
+
+					_, err = fetcher.Get("github.com/some-org/some-repo", freezer.Uncached)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(gitReleaseFetcher.GetContextCall.CallCount).To(Equal(1))
+
+					_, err = fetcher.WithForceRefresh(true).Get("github.com/some-org/some-repo", freezer.Uncached)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(gitReleaseFetcher.GetContextCall.CallCount).To(Equal(2))
+				})
+			})
+
+			context("when offline mode is enabled", func() {
+				var uri string
+
+				it.Before(func() {
+					var err error
+					uri, err = fetcher.Get("github.com/some-org/some-repo", freezer.Uncached)
+					Expect(err).NotTo(HaveOccurred())
+
+					fetcher = fetcher.WithOfflineMode(true)
+				})
+
+				it("resolves the buildpack from the cache without contacting github", func() {
+					cachedURI, err := fetcher.Get("github.com/some-org/some-repo", freezer.Uncached)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(cachedURI).To(Equal(uri))
+
+					Expect(gitReleaseFetcher.GetContextCall.CallCount).To(Equal(1))
+				})
+
+				context("when the buildpack is not cached", func() {
+					it("returns an error naming the key", func() {
+						_, err := fetcher.Get("github.com/some-org/some-repo@1.x", freezer.Uncached)
+						Expect(err).To(MatchError(freezer.ErrNotCached))
+						Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf(`no cache entry for key "some-org:some-repo:linux:%s@1.x"`, runtime.GOARCH))))
+
+						Expect(gitReleaseFetcher.ListContextCall.CallCount).To(Equal(0))
+					})
+				})
+			})
+
+			context("when the resulting buildpack should be cached", func() {
+				it.Before(func() {
+					gitReleaseFetcher.GetReleaseTarballContextCall.Stub = func(gocontext.Context, string) (io.ReadCloser, error) {
+						return nil, fmt.Errorf("unable to get release tarball")
+					}
+				})
+
+				it("fetches the release source", func() {
+					_, err := fetcher.Get("github.com/some-org/some-repo", freezer.Cached)
+					Expect(err).To(MatchError("unable to get release tarball"))
+
+					Expect(gitReleaseFetcher.GetReleaseTarballContextCall.Receives.Url).To(Equal("some-tarball-url"))
+				})
+			})
+		})
+
+		context("when given a reference to a release on another forge", func() {
+			var forgeReleaseFetcher *fakes.GitReleaseFetcherContext
+
+			it.Before(func() {
+				forgeReleaseFetcher = &fakes.GitReleaseFetcherContext{}
+				forgeReleaseFetcher.GetContextCall.Returns.Release = gitReleaseFetcher.GetContextCall.Returns.Release
+				forgeReleaseFetcher.GetReleaseAssetContextCall.Returns.ReadCloser = io.NopCloser(bytes.NewBufferString("some-forge-asset"))
+
+				Expect(fetcher.Close()).To(Succeed())
+				fetcher = fetcher.WithReleaseHost("gitlab.example.com", forgeReleaseFetcher)
+				Expect(fetcher.Open()).To(Succeed())
+			})
+
+			it("fetches the release from that forge", func() {
+				uri, err := fetcher.Get("gitlab.example.com/some-group/some-subgroup/some-repo", freezer.Uncached)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(forgeReleaseFetcher.GetContextCall.Receives.Org).To(Equal("some-group/some-subgroup"))
+				Expect(forgeReleaseFetcher.GetContextCall.Receives.Repo).To(Equal("some-repo"))
+				Expect(gitReleaseFetcher.GetContextCall.CallCount).To(Equal(0))
+
+				Expect(uri).To(Equal(filepath.Join(cacheDir, "gitlab.example.com", "some-group", "some-subgroup", "some-repo", "linux", runtime.GOARCH, "1.2.3.cnb")))
+
+				content, err := os.ReadFile(uri)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(content)).To(Equal("some-forge-asset"))
+			})
+
+			it("keeps the release apart from one with the same name on GitHub", func() {
+				githubURI, err := fetcher.Get("github.com/some-org/some-repo", freezer.Uncached)
+				Expect(err).NotTo(HaveOccurred())
+
+				forgeURI, err := fetcher.Get("gitlab.example.com/some-org/some-repo", freezer.Uncached)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(forgeURI).NotTo(Equal(githubURI))
+				Expect(forgeReleaseFetcher.GetContextCall.CallCount).To(Equal(1))
+			})
+		})
+
+		context("when given an image reference", func() {
+			it("pulls the image for linux", func() {
+				uri, err := fetcher.Get("gcr.io/some-org/some-image:1.2.3", freezer.Uncached)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(imageFetcher.ResolveContextCall.Receives.Ref.String()).To(Equal("gcr.io/some-org/some-image:1.2.3"))
+				Expect(imageFetcher.ResolveContextCall.Receives.Platform).To(Equal("linux"))
+				Expect(imageFetcher.ResolveContextCall.Receives.Arch).To(Equal(runtime.GOARCH))
+
+				Expect(uri).To(Equal(filepath.Join(cacheDir, "registry", "gcr.io", "some-org", "some-image", "linux", runtime.GOARCH, strings.Repeat("1", 64)+".cnb")))
+				Expect(uri).To(BeAnExistingFile())
+			})
+
+			it("pulls images referenced with docker://", func() {
+				_, err := fetcher.Get("docker://paketobuildpacks/go", freezer.Uncached)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(imageFetcher.ResolveContextCall.Receives.Ref.String()).To(Equal("docker.io/paketobuildpacks/go:latest"))
+			})
+		})
+
+		context("when given a URL", func() {
+			var server *httptest.Server
+
+			it.Before(func() {
+				server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					w.Write([]byte("some-cnb"))
+				}))
+
+				fetcher = fetcher.WithHTTPClient(server.Client())
+			})
+
+			it.After(func() {
+				server.Close()
+			})
+
+			it("downloads the buildpack", func() {
+				uri, err := fetcher.Get(server.URL+"/some-buildpack.cnb", freezer.Uncached)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(uri).To(HavePrefix(filepath.Join(cacheDir, "url")))
+				Expect(uri).To(BeAnExistingFile())
+				Expect(packager.ExecuteContextCall.CallCount).To(Equal(0))
+			})
+
+			it("verifies the checksum given in the fragment", func() {
+				_, err := fetcher.Get(server.URL+"/some-buildpack.cnb#sha256:"+strings.Repeat("0", 64), freezer.Uncached)
+				Expect(err).To(MatchError(freezer.ErrChecksumMismatch))
+			})
+		})
+
+		context("when given the path to a source archive", func() {
+			var archivePath string
+
+			it.Before(func() {
+				archive, err := os.CreateTemp("", "some-buildpack-*.tgz")
+				Expect(err).NotTo(HaveOccurred())
+
+				gw := gzip.NewWriter(archive)
+				tw := tar.NewWriter(gw)
+				Expect(tw.WriteHeader(&tar.Header{Name: "buildpack.toml", Mode: 0644, Size: int64(len(`api = "0.2"`))})).To(Succeed())
+				_, err = tw.Write([]byte(`api = "0.2"`))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(tw.Close()).To(Succeed())
+				Expect(gw.Close()).To(Succeed())
+				Expect(archive.Close()).To(Succeed())
+
+				archivePath = archive.Name()
+			})
+
+			it.After(func() {
+				Expect(os.Remove(archivePath)).To(Succeed())
+			})
+
+			it("packages the buildpack within it", func() {
+				uri, err := fetcher.Get(archivePath, freezer.Cached)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(packager.ExecuteContextCall.Receives.Version).To(Equal("testing"))
+				Expect(packager.ExecuteContextCall.Receives.Cached).To(BeTrue())
+				Expect(uri).To(BeAnExistingFile())
+			})
+		})
+
+		context("failure cases", func() {
+			context("when the image reference is malformed", func() {
+				it("returns an error", func() {
+					_, err := fetcher.Get("gcr.io/Some-Org/some-image", freezer.Uncached)
+					Expect(err).To(MatchError(`invalid image reference "gcr.io/Some-Org/some-image": invalid repository "Some-Org/some-image"`))
+				})
+			})
+
+			context("when the checksum of a URL is malformed", func() {
+				it("returns an error", func() {
+					_, err := fetcher.Get("https://example.com/some-buildpack.cnb#md5:1234", freezer.Uncached)
+					Expect(err).To(MatchError(`invalid buildpack URL "https://example.com/some-buildpack.cnb#md5:1234": expected a checksum of the form #sha256:<hex>`))
+				})
+			})
+
+			context("when the github reference is malformed", func() {
+				it("returns an error", func() {
+					_, err := fetcher.Get("github.com/some-org", freezer.Uncached)
+					Expect(err).To(MatchError(`invalid remote buildpack reference "github.com/some-org": expected github.com/<org>/<repo>[@<version>]`))
+				})
+			})
+
+			context("when a github reference has a nested org", func() {
+				it("returns an error", func() {
+					_, err := fetcher.Get("github.com/some-org/some-team/some-repo", freezer.Uncached)
+					Expect(err).To(MatchError(`invalid remote buildpack reference "github.com/some-org/some-team/some-repo": expected github.com/<org>/<repo>[@<version>]`))
+				})
+			})
+
+			context("when the packager fails", func() {
+				it.Before(func() {
+					packager.ExecuteContextCall.Stub = nil
+					packager.ExecuteContextCall.Returns.Error = fmt.Errorf("execution failed")
+				})
+
+				it("returns an error", func() {
+					_, err := fetcher.Get(buildpackDir, freezer.Uncached)
+					Expect(err).To(MatchError("failed to package buildpack: execution failed"))
+				})
+			})
+		})
+	})
+}
@@ -0,0 +1,30 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+// +build linux darwin dragonfly freebsd netbsd openbsd
+
+package freezer
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile takes an exclusive advisory lock on the file at path, creating it
+// if necessary, and blocks until the lock is acquired. The returned function
+// releases the lock.
+func lockFile(path string) (func() error, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return func() error {
+		defer file.Close()
+		return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+	}, nil
+}
@@ -0,0 +1,18 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd
+
+package freezer
+
+import "os"
+
+// lockFile creates the file at path but does not lock it as advisory file
+// locks are not supported on Windows, Plan 9, WebAssembly and the other
+// platforms without flock; only in-process locking applies there.
+func lockFile(path string) (func() error, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return file.Close, nil
+}
@@ -0,0 +1,47 @@
+package freezer
+
+import (
+	"fmt"
+	"time"
+)
+
+// freshness holds the settings that decide whether a cached buildpack is used
+// without asking the forge or registry it came from whether it has changed.
+type freshness struct {
+	offlineMode  bool
+	ttl          time.Duration
+	forceRefresh bool
+}
+
+// offline returns the URI of the cached entry, and true, in offline mode, in
+// which a missing entry is an error.
+func (f freshness) offline(key string, cachedEntry CacheEntry, exist bool) (string, bool, error) {
+	if !f.offlineMode {
+		return "", false, nil
+	}
+
+	if !exist {
+		return "", false, fmt.Errorf("%w: no cache entry for key %q", ErrNotCached, key)
+	}
+
+	return cachedEntry.URI, true, nil
+}
+
+// fresh reports whether the cached entry is within the freshness window, and
+// so can be used without asking whether it has changed.
+func (f freshness) fresh(cachedEntry CacheEntry) bool {
+	return f.ttl > 0 && !f.forceRefresh && time.Since(cachedEntry.CheckedAt) < f.ttl
+}
+
+// checked records that the source of the buildpack has just confirmed that the
+// cached entry is current. The cache is only written when there is a freshness
+// window that depends on it.
+func (f freshness) checked(buildpackCache BuildpackCache, key string, cachedEntry CacheEntry) error {
+	if f.ttl <= 0 {
+		return nil
+	}
+
+	cachedEntry.CheckedAt = time.Now()
+
+	return buildpackCache.Set(key, cachedEntry)
+}
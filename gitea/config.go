@@ -0,0 +1,74 @@
+package gitea
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ForestEckhardt/freezer/github"
+)
+
+type Config struct {
+	// Endpoint is the API root of the Gitea instance, such as
+	// https://codeberg.org/api/v1.
+	Endpoint string
+
+	// Token is an access token. Requests are made anonymously when it is
+	// empty.
+	Token string
+
+	// Client is used to make every request. When it is nil httpclient.Default
+	// is used.
+	Client *http.Client
+
+	// Retry determines how requests that fail with a transient error are
+	// retried. Its zero value disables retries.
+	Retry github.RetryPolicy
+
+	// RateLimitWait is the longest a request waits, in total, for a rate
+	// limit to reset before failing with a github.RateLimitError. Its zero
+	// value fails as soon as the limit is hit.
+	RateLimitWait time.Duration
+}
+
+func NewConfig(endpoint, token string) Config {
+	return Config{
+		Endpoint: endpoint,
+		Token:    token,
+		Retry:    github.DefaultRetryPolicy,
+	}
+}
+
+func (c Config) WithRetryPolicy(retry github.RetryPolicy) Config {
+	c.Retry = retry
+	return c
+}
+
+func (c Config) WithRateLimitWait(wait time.Duration) Config {
+	c.RateLimitWait = wait
+	return c
+}
+
+func (c Config) WithClient(client *http.Client) Config {
+	c.Client = client
+	return c
+}
+
+// WithTransport uses transport, for example one with a custom CA bundle or one
+// that records requests in tests, to make every request.
+func (c Config) WithTransport(transport http.RoundTripper) Config {
+	c.Client = &http.Client{Transport: transport}
+	return c
+}
+
+// github returns the configuration of the GitHub release service that makes
+// the requests, as Gitea's API for releases follows GitHub's.
+func (c Config) github() github.Config {
+	return github.Config{
+		Endpoint:      c.Endpoint,
+		Token:         c.Token,
+		Client:        c.Client,
+		Retry:         c.Retry,
+		RateLimitWait: c.RateLimitWait,
+		Provider:      "Gitea",
+	}
+}
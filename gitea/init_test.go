@@ -0,0 +1,25 @@
+package gitea_test
+
+import (
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestGitea(t *testing.T) {
+	suite := spec.New("gitea", spec.Report(report.Terminal{}))
+	suite("ReleaseService", testReleaseService)
+
+	suite.Before(func(t *testing.T) {
+		RegisterTestingT(t)
+	})
+
+	suite.Run(t)
+}
+
+func Fail(message string) {
+	panic(message)
+}
@@ -0,0 +1,92 @@
+package gitea
+
+import (
+	"context"
+	"io"
+
+	"github.com/ForestEckhardt/freezer/github"
+)
+
+// ReleaseService fetches releases from Gitea, or a fork of it such as
+// Forgejo. Its API for releases follows GitHub's, so requests are made, and
+// retried, the same way; the configuration's endpoint is the API root of the
+// instance, such as https://codeberg.org/api/v1.
+type ReleaseService struct {
+	service github.ReleaseService
+}
+
+func NewReleaseService(config Config) ReleaseService {
+	return ReleaseService{
+		service: github.NewReleaseService(config.github()),
+	}
+}
+
+func (rs ReleaseService) Get(org, repo string) (github.Release, error) {
+	return rs.GetContext(context.Background(), org, repo)
+}
+
+func (rs ReleaseService) GetContext(ctx context.Context, org, repo string) (github.Release, error) {
+	release, err := rs.service.GetContext(ctx, org, repo)
+	return downloadable(release), err
+}
+
+func (rs ReleaseService) GetIfModifiedContext(ctx context.Context, org, repo, etag, lastModified string) (github.Release, error) {
+	release, err := rs.service.GetIfModifiedContext(ctx, org, repo, etag, lastModified)
+	return downloadable(release), err
+}
+
+func (rs ReleaseService) GetReleaseByTag(org, repo, tag string) (github.Release, error) {
+	return rs.GetReleaseByTagContext(context.Background(), org, repo, tag)
+}
+
+func (rs ReleaseService) GetReleaseByTagContext(ctx context.Context, org, repo, tag string) (github.Release, error) {
+	release, err := rs.service.GetReleaseByTagContext(ctx, org, repo, tag)
+	return downloadable(release), err
+}
+
+func (rs ReleaseService) List(org, repo string) ([]github.Release, error) {
+	return rs.ListContext(context.Background(), org, repo)
+}
+
+func (rs ReleaseService) ListContext(ctx context.Context, org, repo string) ([]github.Release, error) {
+	releases, err := rs.service.ListContext(ctx, org, repo)
+	for i := range releases {
+		releases[i] = downloadable(releases[i])
+	}
+
+	return releases, err
+}
+
+func (rs ReleaseService) GetReleaseAsset(asset github.ReleaseAsset) (io.ReadCloser, error) {
+	return rs.GetReleaseAssetContext(context.Background(), asset)
+}
+
+func (rs ReleaseService) GetReleaseAssetContext(ctx context.Context, asset github.ReleaseAsset) (io.ReadCloser, error) {
+	return rs.service.GetReleaseAssetContext(ctx, asset)
+}
+
+func (rs ReleaseService) GetReleaseTarball(url string) (io.ReadCloser, error) {
+	return rs.GetReleaseTarballContext(context.Background(), url)
+}
+
+func (rs ReleaseService) GetReleaseTarballContext(ctx context.Context, url string) (io.ReadCloser, error) {
+	return rs.service.GetReleaseTarballContext(ctx, url)
+}
+
+// downloadable points the assets of release at their download URL, as unlike
+// GitHub, Gitea does not serve the content of an asset from its API URL.
+func downloadable(release github.Release) github.Release {
+	assets := make([]github.ReleaseAsset, 0, len(release.Assets))
+	for _, asset := range release.Assets {
+		if asset.BrowserDownloadURL != "" {
+			asset.URL = asset.BrowserDownloadURL
+		}
+		assets = append(assets, asset)
+	}
+
+	if release.Assets != nil {
+		release.Assets = assets
+	}
+
+	return release
+}
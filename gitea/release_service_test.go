@@ -0,0 +1,129 @@
+package gitea_test
+
+import (
+	gocontext "context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"testing"
+
+	"github.com/ForestEckhardt/freezer/gitea"
+	"github.com/ForestEckhardt/freezer/github"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testReleaseService(t *testing.T, context spec.G, it spec.S) {
+	var (
+		service gitea.ReleaseService
+		api     *httptest.Server
+		ctx     gocontext.Context
+	)
+
+	it.Before(func() {
+		ctx = gocontext.Background()
+
+		api = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			dump, _ := httputil.DumpRequest(req, true)
+
+			if req.Header.Get("Authorization") != "token some-gitea-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			release := fmt.Sprintf(`{
+  "tag_name": "v1.2.3",
+  "tarball_url": "%[1]s/archive/v1.2.3.tar.gz",
+  "assets": [
+    {"name": "some-repo-1.2.3.cnb", "browser_download_url": "%[1]s/attachments/some-uuid"}
+  ]
+}`, api.URL)
+
+			switch req.URL.Path {
+			case "/api/v1/repos/some-org/some-repo/releases/latest", "/api/v1/repos/some-org/some-repo/releases/tags/v1.2.3":
+				fmt.Fprint(w, release)
+
+			case "/api/v1/repos/some-org/some-repo/releases":
+				fmt.Fprintf(w, "[%s]", release)
+
+			case "/attachments/some-uuid":
+				fmt.Fprint(w, "some-asset")
+
+			case "/api/v1/repos/some-org/some-limited-repo/releases/latest":
+				w.Header().Set("X-RateLimit-Remaining", "0")
+				w.Header().Set("X-RateLimit-Reset", "4102444800")
+				w.WriteHeader(http.StatusForbidden)
+
+			default:
+				Fail(fmt.Sprintf("unexpected request:\n%s", dump))
+			}
+		}))
+
+		service = gitea.NewReleaseService(gitea.NewConfig(api.URL+"/api/v1", "some-gitea-token"))
+	})
+
+	it.After(func() {
+		api.Close()
+	})
+
+	context("GetContext", func() {
+		it("fetches the latest release with downloadable assets", func() {
+			release, err := service.GetContext(ctx, "some-org", "some-repo")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(release).To(Equal(github.Release{
+				TagName: "v1.2.3",
+				Assets: []github.ReleaseAsset{
+					{
+						URL:                api.URL + "/attachments/some-uuid",
+						Name:               "some-repo-1.2.3.cnb",
+						BrowserDownloadURL: api.URL + "/attachments/some-uuid",
+					},
+				},
+				TarballURL: api.URL + "/archive/v1.2.3.tar.gz",
+			}))
+		})
+
+		context("when the rate limit has been exceeded", func() {
+			it("returns a rate limit error naming Gitea", func() {
+				_, err := service.GetContext(ctx, "some-org", "some-limited-repo")
+				Expect(err).To(BeAssignableToTypeOf(&github.RateLimitError{}))
+				Expect(err).To(MatchError(ContainSubstring("Gitea API rate limit exceeded: 403 Forbidden (resets at")))
+			})
+		})
+	})
+
+	context("GetReleaseByTagContext", func() {
+		it("fetches the release with the tag", func() {
+			release, err := service.GetReleaseByTagContext(ctx, "some-org", "some-repo", "v1.2.3")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(release.Assets[0].URL).To(Equal(api.URL + "/attachments/some-uuid"))
+		})
+	})
+
+	context("ListContext", func() {
+		it("fetches the releases with downloadable assets", func() {
+			releases, err := service.ListContext(ctx, "some-org", "some-repo")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(releases).To(HaveLen(1))
+			Expect(releases[0].Assets[0].URL).To(Equal(api.URL + "/attachments/some-uuid"))
+		})
+	})
+
+	context("GetReleaseAssetContext", func() {
+		it("downloads the asset", func() {
+			release, err := service.GetContext(ctx, "some-org", "some-repo")
+			Expect(err).NotTo(HaveOccurred())
+
+			bundle, err := service.GetReleaseAssetContext(ctx, release.Assets[0])
+			Expect(err).NotTo(HaveOccurred())
+			defer bundle.Close()
+
+			content, err := io.ReadAll(bundle)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(Equal("some-asset"))
+		})
+	})
+}
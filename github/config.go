@@ -1,13 +1,106 @@
 package github
 
+import (
+	"net/http"
+	"time"
+
+	"github.com/ForestEckhardt/freezer/internal/httpclient"
+)
+
+const (
+	// DefaultDialTimeout bounds how long establishing a connection may take.
+	DefaultDialTimeout = httpclient.DialTimeout
+
+	// DefaultTLSHandshakeTimeout bounds how long the TLS handshake may take.
+	DefaultTLSHandshakeTimeout = httpclient.TLSHandshakeTimeout
+
+	// DefaultResponseHeaderTimeout bounds how long to wait for the headers of
+	// a response once the request has been sent. Reading the body is not
+	// bounded, as release assets can be large; use a context to bound it.
+	DefaultResponseHeaderTimeout = httpclient.ResponseHeaderTimeout
+)
+
 type Config struct {
 	Endpoint string
 	Token    string
+
+	// Client is used to make every request. When it is nil httpclient.Default
+	// is used.
+	Client *http.Client
+
+	// Retry determines how requests that fail with a transient error are
+	// retried. Its zero value disables retries.
+	Retry RetryPolicy
+
+	// RateLimitWait is the longest a request waits, in total, for a rate
+	// limit to reset before failing with a RateLimitError. Its zero value
+	// fails as soon as the limit is hit.
+	RateLimitWait time.Duration
+
+	// Provider names the forge in errors, such as rate limit errors. When it
+	// is empty GitHub is used. Release services of forges whose API follows
+	// GitHub's set it to their own name.
+	Provider string
 }
 
 func NewConfig(endpoint, token string) Config {
 	return Config{
 		Endpoint: endpoint,
 		Token:    token,
+		Retry:    DefaultRetryPolicy,
+	}
+}
+
+func (c Config) WithRetryPolicy(retry RetryPolicy) Config {
+	c.Retry = retry
+	return c
+}
+
+func (c Config) WithRateLimitWait(wait time.Duration) Config {
+	c.RateLimitWait = wait
+	return c
+}
+
+func (c Config) WithClient(client *http.Client) Config {
+	c.Client = client
+	return c
+}
+
+// WithTransport uses transport, for example one with a custom CA bundle or one
+// that records requests in tests, to make every request.
+func (c Config) WithTransport(transport http.RoundTripper) Config {
+	c.Client = &http.Client{Transport: transport}
+	return c
+}
+
+// NewDefaultClient returns a client configured like the one used when Client
+// is nil. It is a starting point for clients that only need to change some of
+// its settings.
+func NewDefaultClient() *http.Client {
+	return httpclient.New()
+}
+
+func (c Config) client() *http.Client {
+	if c.Client != nil {
+		return c.Client
+	}
+
+	return httpclient.Default
+}
+
+func (c Config) provider() string {
+	if c.Provider != "" {
+		return c.Provider
+	}
+
+	return "GitHub"
+}
+
+func (c Config) sender() httpclient.Sender {
+	return httpclient.Sender{
+		Client:        c.client(),
+		Retry:         c.Retry,
+		RateLimitWait: c.RateLimitWait,
+		Provider:      c.provider(),
 	}
 }
@@ -0,0 +1,74 @@
+package github_test
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ForestEckhardt/freezer/github"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+type roundTripper struct {
+	requests []*http.Request
+}
+
+func (r *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.requests = append(r.requests, req)
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(`{"tag_name": "some-tag"}`)),
+		Request:    req,
+	}, nil
+}
+
+func testConfig(t *testing.T, context spec.G, it spec.S) {
+	context("NewDefaultClient", func() {
+		it("returns a client with timeouts that honours the proxy environment variables", func() {
+			client := github.NewDefaultClient()
+
+			transport, ok := client.Transport.(*http.Transport)
+			Expect(ok).To(BeTrue())
+
+			Expect(transport.Proxy).NotTo(BeNil())
+			Expect(transport.DialContext).NotTo(BeNil())
+			Expect(transport.TLSHandshakeTimeout).To(Equal(github.DefaultTLSHandshakeTimeout))
+			Expect(transport.ResponseHeaderTimeout).To(Equal(github.DefaultResponseHeaderTimeout))
+		})
+	})
+
+	context("WithTransport", func() {
+		it("makes every request with the given transport", func() {
+			transport := &roundTripper{}
+			service := github.NewReleaseService(github.NewConfig("https://api.example.com", "some-github-token").WithTransport(transport))
+
+			release, err := service.Get("some-org", "some-repo")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(release.TagName).To(Equal("some-tag"))
+
+			Expect(transport.requests).To(HaveLen(1))
+			Expect(transport.requests[0].URL.String()).To(Equal("https://api.example.com/repos/some-org/some-repo/releases/latest"))
+			Expect(transport.requests[0].Header.Get("Authorization")).To(Equal("token some-github-token"))
+		})
+	})
+
+	context("WithClient", func() {
+		it("makes every request with the given client", func() {
+			transport := &roundTripper{}
+			client := &http.Client{Transport: transport}
+			service := github.NewReleaseService(github.NewConfig("https://api.example.com", "").WithClient(client))
+
+			body, err := service.GetReleaseTarball("https://codeload.example.com/some-tarball")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(body.Close()).To(Succeed())
+
+			Expect(transport.requests).To(HaveLen(1))
+			Expect(transport.requests[0].URL.Path).To(Equal("/some-tarball"))
+		})
+	})
+}
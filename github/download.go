@@ -0,0 +1,144 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/ForestEckhardt/freezer/internal/httpclient"
+)
+
+// resumableBody is the body of a download that, when reading fails part way
+// through, requests the remainder of the file with a Range request instead of
+// failing or starting over.
+type resumableBody struct {
+	ctx        context.Context
+	service    ReleaseService
+	newRequest func() (*http.Request, error)
+
+	body      io.ReadCloser
+	offset    int64
+	validator string
+}
+
+func newResumableBody(ctx context.Context, service ReleaseService, newRequest func() (*http.Request, error), resp *http.Response) *resumableBody {
+	//If-Range only accepts a strong entity tag or a date
+	validator := resp.Header.Get("ETag")
+	if validator == "" || strings.HasPrefix(validator, "W/") {
+		validator = resp.Header.Get("Last-Modified")
+	}
+
+	return &resumableBody{
+		ctx:        ctx,
+		service:    service,
+		newRequest: newRequest,
+		body:       resp.Body,
+		validator:  validator,
+	}
+}
+
+func (b *resumableBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	b.offset += int64(n)
+
+	if err == nil || errors.Is(err, io.EOF) || b.ctx.Err() != nil {
+		return n, err
+	}
+
+	resumeErr := b.resume()
+	if resumeErr != nil {
+		return n, fmt.Errorf("%w (resuming the download failed: %s)", err, resumeErr)
+	}
+
+	return n, nil
+}
+
+func (b *resumableBody) Close() error {
+	return b.body.Close()
+}
+
+// resume replaces the failed body with the remainder of the file, retrying
+// according to the retry policy of the service.
+func (b *resumableBody) resume() error {
+	b.body.Close()
+
+	policy := b.service.config.Retry
+
+	var err error
+	for retry := 0; retry < policy.Attempts-1; retry++ {
+		err = httpclient.Sleep(b.ctx, policy.Backoff(retry))
+		if err != nil {
+			return err
+		}
+
+		var retryable bool
+		retryable, err = b.request()
+		if err == nil || !retryable {
+			return err
+		}
+	}
+
+	if err == nil {
+		err = errors.New("retries are disabled")
+	}
+
+	return err
+}
+
+// request asks for the remainder of the file, reporting whether a failure is
+// worth retrying.
+func (b *resumableBody) request() (bool, error) {
+	req, err := b.newRequest()
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-", b.offset))
+	if b.validator != "" {
+		req.Header.Set("If-Range", b.validator)
+	}
+
+	resp, err := b.service.config.client().Do(req)
+	if err != nil {
+		return b.ctx.Err() == nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusPartialContent:
+		if !strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", b.offset)) {
+			httpclient.Discard(resp)
+			return false, fmt.Errorf("unexpected content range: %q", resp.Header.Get("Content-Range"))
+		}
+
+	case resp.StatusCode == http.StatusOK:
+		//The server sends the whole file when it has changed since the download
+		//started, in which case the parts cannot be stitched together, or when
+		//it does not support ranges, in which case the part that was already
+		//read is skipped
+		if b.validator != "" {
+			httpclient.Discard(resp)
+			return false, errors.New("the file changed while it was being downloaded")
+		}
+
+		_, err = io.CopyN(io.Discard, resp.Body, b.offset)
+		if err != nil {
+			resp.Body.Close()
+			return b.ctx.Err() == nil, err
+		}
+
+	default:
+		if limitErr := httpclient.RateLimit(resp, b.service.config.provider()); limitErr != nil {
+			httpclient.Discard(resp)
+			return false, limitErr
+		}
+
+		return httpclient.RetryableStatus(resp.StatusCode), NewHTTPStatusError(resp, req.URL.String(), ErrAssetNotFound)
+	}
+
+	b.body = resp.Body
+
+	return false, nil
+}
@@ -0,0 +1,29 @@
+package github
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ForestEckhardt/freezer/internal/httpclient"
+)
+
+// ErrReleaseNotFound is matched, with errors.Is, by the errors returned when
+// a release, or the repository it would belong to, does not exist.
+var ErrReleaseNotFound = errors.New("release not found")
+
+// ErrAssetNotFound is matched, with errors.Is, by the errors returned when a
+// release asset or tarball does not exist.
+var ErrAssetNotFound = errors.New("asset not found")
+
+// HTTPStatusError is returned when GitHub responds with an unexpected status.
+// For 404 responses it also matches ErrReleaseNotFound or ErrAssetNotFound,
+// depending on what was requested.
+type HTTPStatusError = httpclient.StatusError
+
+// NewHTTPStatusError keeps the start of the body of resp, which was returned
+// for a request for uri, and discards the rest. A 404 response matches
+// notFound. It allows release services for other forges to report errors the
+// same way.
+func NewHTTPStatusError(resp *http.Response, uri string, notFound error) *HTTPStatusError {
+	return httpclient.NewStatusError(resp, uri, notFound)
+}
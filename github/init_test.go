@@ -11,7 +11,10 @@ import (
 
 func TestGithub(t *testing.T) {
 	suite := spec.New("github", spec.Report(report.Terminal{}))
+	suite("Config", testConfig)
+	suite("RateLimit", testRateLimit)
 	suite("ReleaseService", testReleaseService)
+	suite("Retry", testRetry)
 
 	suite.Before(func(t *testing.T) {
 		RegisterTestingT(t)
@@ -0,0 +1,9 @@
+package github
+
+import "github.com/ForestEckhardt/freezer/internal/httpclient"
+
+// RateLimitError is returned when a forge refuses a request because the rate
+// limit of its API has been exceeded. Unauthenticated GitHub clients are
+// limited to 60 requests an hour, so setting a token is the usual way to
+// avoid it.
+type RateLimitError = httpclient.RateLimitError
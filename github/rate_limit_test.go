@@ -0,0 +1,129 @@
+package github_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ForestEckhardt/freezer/github"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testRateLimit(t *testing.T, context spec.G, it spec.S) {
+	var (
+		api      *httptest.Server
+		config   github.Config
+		requests int
+		handler  http.HandlerFunc
+	)
+
+	it.Before(func() {
+		requests = 0
+
+		api = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			requests++
+			handler(w, req)
+		}))
+
+		config = github.NewConfig(api.URL, "").WithRetryPolicy(github.RetryPolicy{
+			Attempts:       3,
+			InitialBackoff: time.Millisecond,
+			MaxBackoff:     time.Millisecond,
+		})
+	})
+
+	it.After(func() {
+		api.Close()
+	})
+
+	context("when the rate limit has been exceeded", func() {
+		var reset time.Time
+
+		it.Before(func() {
+			reset = time.Now().Add(time.Hour).Truncate(time.Second)
+
+			handler = func(w http.ResponseWriter, req *http.Request) {
+				w.Header().Set("X-RateLimit-Limit", "60")
+				w.Header().Set("X-RateLimit-Remaining", "0")
+				w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+				w.WriteHeader(http.StatusForbidden)
+			}
+		})
+
+		it("returns a RateLimitError without retrying", func() {
+			_, err := github.NewReleaseService(config).Get("some-org", "some-repo")
+
+			var limitErr *github.RateLimitError
+			Expect(errors.As(err, &limitErr)).To(BeTrue())
+			Expect(limitErr.Status).To(Equal("403 Forbidden"))
+			Expect(limitErr.Limit).To(Equal(60))
+			Expect(limitErr.Reset).To(BeTemporally("==", reset))
+
+			Expect(err).To(MatchError(ContainSubstring("GitHub API rate limit exceeded: 403 Forbidden (resets at")))
+
+			Expect(requests).To(Equal(1))
+		})
+
+		context("when the limit resets after the wait budget", func() {
+			it("does not wait for it", func() {
+				start := time.Now()
+
+				_, err := github.NewReleaseService(config.WithRateLimitWait(time.Minute)).Get("some-org", "some-repo")
+				Expect(err).To(BeAssignableToTypeOf(&github.RateLimitError{}))
+
+				Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+				Expect(requests).To(Equal(1))
+			})
+		})
+	})
+
+	context("when a secondary rate limit has been exceeded", func() {
+		it.Before(func() {
+			handler = func(w http.ResponseWriter, req *http.Request) {
+				if requests == 1 {
+					w.Header().Set("Retry-After", "1")
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+
+				w.Write([]byte(`{"tag_name": "some-tag"}`))
+			}
+		})
+
+		it("returns a RateLimitError", func() {
+			_, err := github.NewReleaseService(config).Get("some-org", "some-repo")
+			Expect(err).To(MatchError(ContainSubstring("GitHub API rate limit exceeded: 429 Too Many Requests")))
+
+			Expect(requests).To(Equal(1))
+		})
+
+		context("when the limit resets within the wait budget", func() {
+			it("waits for it and tries again", func() {
+				release, err := github.NewReleaseService(config.WithRateLimitWait(5*time.Second)).Get("some-org", "some-repo")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(release.TagName).To(Equal("some-tag"))
+
+				Expect(requests).To(Equal(2))
+			})
+		})
+	})
+
+	context("when a request is forbidden for another reason", func() {
+		it.Before(func() {
+			handler = func(w http.ResponseWriter, req *http.Request) {
+				w.Header().Set("X-RateLimit-Remaining", "59")
+				w.WriteHeader(http.StatusForbidden)
+			}
+		})
+
+		it("returns the usual error", func() {
+			_, err := github.NewReleaseService(config).Get("some-org", "some-repo")
+			Expect(err).To(MatchError("unexpected response status: 403 Forbidden"))
+		})
+	})
+}
@@ -1,11 +1,15 @@
 package github
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
+
+	"github.com/ForestEckhardt/freezer/internal/httpclient"
 )
 
 type ReleaseService struct {
@@ -13,16 +17,32 @@ type ReleaseService struct {
 }
 
 type ReleaseAsset struct {
-	URL  string `json:"url"`
-	Name string `json:"name"`
+	URL         string `json:"url"`
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+
+	// BrowserDownloadURL is where the asset can be downloaded from without
+	// going through the API.
+	BrowserDownloadURL string `json:"browser_download_url"`
 }
 
 type Release struct {
 	TagName    string         `json:"tag_name"`
 	Assets     []ReleaseAsset `json:"assets"`
 	TarballURL string         `json:"tarball_url"`
+	Draft      bool           `json:"draft"`
+	Prerelease bool           `json:"prerelease"`
+
+	// ETag and LastModified are the validators of the response the release
+	// was read from, for use with GetIfModifiedContext.
+	ETag         string `json:"-"`
+	LastModified string `json:"-"`
 }
 
+// ErrNotModified is returned by GetIfModifiedContext when the release has not
+// changed.
+var ErrNotModified = errors.New("release has not been modified")
+
 func NewReleaseService(config Config) ReleaseService {
 	return ReleaseService{
 		config: config,
@@ -30,82 +50,191 @@ func NewReleaseService(config Config) ReleaseService {
 }
 
 func (rs ReleaseService) Get(org, repo string) (Release, error) {
-	uri, err := url.Parse(rs.config.Endpoint)
-	if err != nil {
-		return Release{}, err
+	return rs.GetContext(context.Background(), org, repo)
+}
+
+// GetContext is like Get but aborts the request when ctx is done.
+func (rs ReleaseService) GetContext(ctx context.Context, org, repo string) (Release, error) {
+	return rs.getRelease(ctx, fmt.Sprintf("/repos/%s/%s/releases/latest", org, repo), nil)
+}
+
+// GetIfModifiedContext is like GetContext but makes a conditional request with
+// the ETag and Last-Modified of a release fetched earlier, returning
+// ErrNotModified if the latest release has not changed since. Requests that
+// are answered this way do not count against the rate limit.
+func (rs ReleaseService) GetIfModifiedContext(ctx context.Context, org, repo, etag, lastModified string) (Release, error) {
+	header := http.Header{}
+	if etag != "" {
+		header.Set("If-None-Match", etag)
+	}
+
+	if lastModified != "" {
+		header.Set("If-Modified-Since", lastModified)
 	}
 
-	uri.Path = fmt.Sprintf("/repos/%s/%s/releases/latest", org, repo)
+	return rs.getRelease(ctx, fmt.Sprintf("/repos/%s/%s/releases/latest", org, repo), header)
+}
+
+func (rs ReleaseService) GetReleaseByTag(org, repo, tag string) (Release, error) {
+	return rs.GetReleaseByTagContext(context.Background(), org, repo, tag)
+}
+
+// GetReleaseByTagContext is like GetReleaseByTag but aborts the request when
+// ctx is done.
+func (rs ReleaseService) GetReleaseByTagContext(ctx context.Context, org, repo, tag string) (Release, error) {
+	return rs.getRelease(ctx, fmt.Sprintf("/repos/%s/%s/releases/tags/%s", org, repo, tag), nil)
+}
+
+// List returns every release of the given repository, following the
+// pagination links returned by the API until the last page is reached.
+func (rs ReleaseService) List(org, repo string) ([]Release, error) {
+	return rs.ListContext(context.Background(), org, repo)
+}
 
-	req, err := http.NewRequest("GET", uri.String(), nil)
+// ListContext is like List but aborts the requests when ctx is done.
+func (rs ReleaseService) ListContext(ctx context.Context, org, repo string) ([]Release, error) {
+	uri, err := rs.url(fmt.Sprintf("/repos/%s/%s/releases", org, repo))
 	if err != nil {
-		return Release{}, err
+		return nil, err
 	}
 
-	if rs.config.Token != "" {
-		req.Header.Set("Authorization", fmt.Sprintf("token %s", rs.config.Token))
+	uri.RawQuery = url.Values{"per_page": []string{"100"}}.Encode()
+
+	var releases []Release
+	next := uri.String()
+	for next != "" {
+		var page []Release
+		header, err := rs.getJSON(ctx, next, nil, &page)
+		if err != nil {
+			return nil, err
+		}
+
+		releases = append(releases, page...)
+		next = httpclient.NextPageLink(header.Get("Link"))
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	return releases, nil
+}
+
+func (rs ReleaseService) getRelease(ctx context.Context, path string, header http.Header) (Release, error) {
+	uri, err := rs.url(path)
 	if err != nil {
 		return Release{}, err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return Release{}, fmt.Errorf("unexpected response status: %s", resp.Status)
-	}
-
 	var release Release
-	err = json.NewDecoder(resp.Body).Decode(&release)
+	header, err = rs.getJSON(ctx, uri.String(), header, &release)
 	if err != nil {
 		return Release{}, err
 	}
 
+	release.ETag = header.Get("ETag")
+	release.LastModified = header.Get("Last-Modified")
+
 	return release, nil
 }
 
-func (rs ReleaseService) GetReleaseAsset(asset ReleaseAsset) (io.ReadCloser, error) {
-	req, err := http.NewRequest("GET", asset.URL, nil)
+// url returns the URL of the API path relative to the endpoint, keeping any
+// path the endpoint has, such as the /api/v3 of GitHub Enterprise Server.
+func (rs ReleaseService) url(path string) (*url.URL, error) {
+	uri, err := url.Parse(rs.config.Endpoint)
 	if err != nil {
 		return nil, err
 	}
 
-	if rs.config.Token != "" {
-		req.Header.Set("Authorization", fmt.Sprintf("token %s", rs.config.Token))
+	uri.Path = strings.TrimSuffix(uri.Path, "/") + path
+
+	return uri, nil
+}
+
+// getJSON decodes the response to a request for uri, sent with the given
+// additional headers, into v and returns the headers of the response.
+func (rs ReleaseService) getJSON(ctx context.Context, uri string, header http.Header, v interface{}) (http.Header, error) {
+	resp, err := rs.do(ctx, func() (*http.Request, error) {
+		req, err := rs.newRequest(ctx, uri)
+		if err != nil {
+			return nil, err
+		}
+
+		for name, values := range header {
+			req.Header[name] = values
+		}
+
+		return req, nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	req.Header.Add("Accept", "application/octet-stream")
+	return httpclient.DecodeJSON(resp, uri, v, ErrReleaseNotFound, ErrNotModified)
+}
+
+func (rs ReleaseService) GetReleaseAsset(asset ReleaseAsset) (io.ReadCloser, error) {
+	return rs.GetReleaseAssetContext(context.Background(), asset)
+}
+
+// GetReleaseAssetContext is like GetReleaseAsset but aborts the download, both
+// the request and the reading of the returned body, when ctx is done.
+func (rs ReleaseService) GetReleaseAssetContext(ctx context.Context, asset ReleaseAsset) (io.ReadCloser, error) {
+	newRequest := func() (*http.Request, error) {
+		req, err := rs.newRequest(ctx, asset.URL)
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Add("Accept", "application/octet-stream")
+
+		return req, nil
+	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := rs.do(ctx, newRequest)
 	if err != nil {
 		return nil, err
 	}
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusFound {
-		return nil, fmt.Errorf("unexpected response status: %s", resp.Status)
+		return nil, NewHTTPStatusError(resp, asset.URL, ErrAssetNotFound)
+	}
+
+	if resp.StatusCode == http.StatusOK {
+		return newResumableBody(ctx, rs, newRequest, resp), nil
 	}
 
 	return resp.Body, nil
 }
 
 func (rs ReleaseService) GetReleaseTarball(url string) (io.ReadCloser, error) {
-	req, err := http.NewRequest("GET", url, nil)
+	return rs.GetReleaseTarballContext(context.Background(), url)
+}
+
+// GetReleaseTarballContext is like GetReleaseTarball but aborts the download,
+// both the request and the reading of the returned body, when ctx is done.
+func (rs ReleaseService) GetReleaseTarballContext(ctx context.Context, url string) (io.ReadCloser, error) {
+	newRequest := func() (*http.Request, error) {
+		return rs.newRequest(ctx, url)
+	}
+
+	resp, err := rs.do(ctx, newRequest)
 	if err != nil {
 		return nil, err
 	}
 
-	if rs.config.Token != "" {
-		req.Header.Set("Authorization", fmt.Sprintf("token %s", rs.config.Token))
+	if resp.StatusCode != http.StatusOK {
+		return nil, NewHTTPStatusError(resp, url, ErrAssetNotFound)
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	return newResumableBody(ctx, rs, newRequest, resp), nil
+}
+
+func (rs ReleaseService) newRequest(ctx context.Context, uri string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", uri, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected response status: %s", resp.Status)
+	if rs.config.Token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("token %s", rs.config.Token))
 	}
 
-	return resp.Body, nil
+	return req, nil
 }
@@ -1,11 +1,14 @@
 package github_test
 
 import (
+	gocontext "context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/http/httputil"
+	"strings"
 	"testing"
 
 	"github.com/ForestEckhardt/freezer/github"
@@ -14,6 +17,31 @@ import (
 	. "github.com/onsi/gomega"
 )
 
+type trackedBody struct {
+	*strings.Reader
+	closed bool
+}
+
+func (b *trackedBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+type bodyTransport struct {
+	status int
+	body   *trackedBody
+}
+
+func (t *bodyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: t.status,
+		Status:     fmt.Sprintf("%d %s", t.status, http.StatusText(t.status)),
+		Header:     http.Header{},
+		Body:       t.body,
+		Request:    req,
+	}, nil
+}
+
 func testReleaseService(t *testing.T, context spec.G, it spec.S) {
 	var (
 		service github.ReleaseService
@@ -31,7 +59,7 @@ func testReleaseService(t *testing.T, context spec.G, it spec.S) {
 				}
 
 				switch req.URL.Path {
-				case "/repos/some-org/some-repo/releases/latest":
+				case "/repos/some-org/some-repo/releases/latest", "/api/v3/repos/some-org/some-repo/releases/latest":
 					w.Write([]byte(`{
   "tag_name": "some-tag",
   "assets": [
@@ -43,8 +71,12 @@ func testReleaseService(t *testing.T, context spec.G, it spec.S) {
 					}`))
 				case "/repos/some-org/missing-repo/releases/latest":
 					w.WriteHeader(http.StatusNotFound)
+					w.Write([]byte(`{"message": "Not Found"}`))
 				case "/repos/some-org/malformed-repo/releases/latest":
 					w.Write([]byte("%%%"))
+				case "/repos/some-org/unavailable-repo/releases/latest":
+					w.WriteHeader(http.StatusUnprocessableEntity)
+					w.Write([]byte("{\n  \"message\": \"" + strings.Repeat("x", 1000) + "\"\n}"))
 				default:
 					Fail(fmt.Sprintf("unexpected request:\n%s", dump))
 				}
@@ -116,6 +148,18 @@ func testReleaseService(t *testing.T, context spec.G, it spec.S) {
 			})
 		})
 
+		context("when the endpoint has a base path", func() {
+			it.Before(func() {
+				service = github.NewReleaseService(github.NewConfig(api.URL+"/api/v3/", "some-github-token"))
+			})
+
+			it("requests paths under it", func() {
+				release, err := service.Get("some-org", "some-repo")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(release.TagName).To(Equal("some-tag"))
+			})
+		})
+
 		context("failure cases", func() {
 			context("when the request url is malformed", func() {
 				it.Before(func() {
@@ -130,10 +174,40 @@ func testReleaseService(t *testing.T, context spec.G, it spec.S) {
 				})
 			})
 
+			context("when the context is done", func() {
+				it("returns an error", func() {
+					ctx, cancel := gocontext.WithCancel(gocontext.Background())
+					cancel()
+
+					_, err := service.GetContext(ctx, "some-org", "some-repo")
+					Expect(err).To(MatchError(gocontext.Canceled))
+				})
+			})
+
 			context("when the response status is not 200 OK", func() {
 				it("returns an error", func() {
 					_, err := service.Get("some-org", "missing-repo")
-					Expect(err).To(MatchError("unexpected response status: 404 Not Found"))
+					Expect(err).To(MatchError(`unexpected response status: 404 Not Found: {"message": "Not Found"}`))
+					Expect(err).To(MatchError(github.ErrReleaseNotFound))
+					Expect(err).NotTo(MatchError(github.ErrAssetNotFound))
+
+					var statusErr *github.HTTPStatusError
+					Expect(errors.As(err, &statusErr)).To(BeTrue())
+					Expect(statusErr.StatusCode).To(Equal(http.StatusNotFound))
+					Expect(statusErr.URL).To(Equal(api.URL + "/repos/some-org/missing-repo/releases/latest"))
+					Expect(statusErr.Body).To(Equal(`{"message": "Not Found"}`))
+				})
+			})
+
+			context("when the response body is long", func() {
+				it("only keeps the beginning of it", func() {
+					_, err := service.Get("some-org", "unavailable-repo")
+
+					var statusErr *github.HTTPStatusError
+					Expect(errors.As(err, &statusErr)).To(BeTrue())
+					Expect(statusErr.Body).To(HavePrefix(`{ "message": "xxx`))
+					Expect(statusErr.Body).To(HaveSuffix("xxx..."))
+					Expect(len(statusErr.Body)).To(BeNumerically("<=", 515))
 				})
 			})
 
@@ -146,6 +220,237 @@ func testReleaseService(t *testing.T, context spec.G, it spec.S) {
 		})
 	})
 
+	context("GetIfModifiedContext", func() {
+		var header http.Header
+
+		it.Before(func() {
+			api = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				dump, _ := httputil.DumpRequest(req, true)
+				header = req.Header
+
+				switch req.URL.Path {
+				case "/repos/some-org/some-repo/releases/latest":
+					if req.Header.Get("If-None-Match") == `"some-etag"` {
+						w.WriteHeader(http.StatusNotModified)
+						return
+					}
+
+					w.Header().Set("ETag", `"some-other-etag"`)
+					w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+					w.Write([]byte(`{"tag_name": "some-tag"}`))
+				default:
+					Fail(fmt.Sprintf("unexpected request:\n%s", dump))
+				}
+			}))
+
+			service = github.NewReleaseService(github.Config{
+				Endpoint: api.URL,
+			})
+		})
+
+		it("sends the validators with the request", func() {
+			_, err := service.GetIfModifiedContext(gocontext.Background(), "some-org", "some-repo", `"some-etag"`, "Sun, 01 Jan 2006 15:04:05 GMT")
+			Expect(err).To(MatchError(github.ErrNotModified))
+
+			Expect(header.Get("If-None-Match")).To(Equal(`"some-etag"`))
+			Expect(header.Get("If-Modified-Since")).To(Equal("Sun, 01 Jan 2006 15:04:05 GMT"))
+		})
+
+		context("when the release has changed", func() {
+			it("returns the release along with its validators", func() {
+				release, err := service.GetIfModifiedContext(gocontext.Background(), "some-org", "some-repo", `"stale-etag"`, "")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(release).To(Equal(github.Release{
+					TagName:      "some-tag",
+					ETag:         `"some-other-etag"`,
+					LastModified: "Mon, 02 Jan 2006 15:04:05 GMT",
+				}))
+
+				Expect(header).NotTo(HaveKey("If-Modified-Since"))
+			})
+		})
+	})
+
+	context("GetReleaseByTag", func() {
+		it.Before(func() {
+			api = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				dump, _ := httputil.DumpRequest(req, true)
+
+				if req.Header.Get("Authorization") != "token some-github-token" {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+
+				switch req.URL.Path {
+				case "/repos/some-org/some-repo/releases/tags/v1.2.3":
+					w.Write([]byte(`{
+  "tag_name": "v1.2.3",
+  "assets": [
+    {
+      "url": "some-url"
+    }
+  ],
+  "tarball_url": "some-tarball-url"
+					}`))
+				case "/repos/some-org/some-repo/releases/tags/missing-tag":
+					w.WriteHeader(http.StatusNotFound)
+				case "/repos/some-org/malformed-repo/releases/tags/v1.2.3":
+					w.Write([]byte("%%%"))
+				default:
+					Fail(fmt.Sprintf("unexpected request:\n%s", dump))
+				}
+			}))
+
+			service = github.NewReleaseService(github.Config{
+				Endpoint: api.URL,
+				Token:    "some-github-token",
+			})
+		})
+
+		it("fetches the release with the given tag", func() {
+			release, err := service.GetReleaseByTag("some-org", "some-repo", "v1.2.3")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(release).To(Equal(github.Release{
+				TagName: "v1.2.3",
+				Assets: []github.ReleaseAsset{
+					{
+						URL: "some-url",
+					},
+				},
+				TarballURL: "some-tarball-url",
+			}))
+		})
+
+		context("failure cases", func() {
+			context("when the request url is malformed", func() {
+				it.Before(func() {
+					service = github.NewReleaseService(github.Config{
+						Endpoint: "%%%",
+					})
+				})
+
+				it("returns an error", func() {
+					_, err := service.GetReleaseByTag("some-org", "some-repo", "v1.2.3")
+					Expect(err).To(MatchError(ContainSubstring("invalid URL escape \"%%%\"")))
+				})
+			})
+
+			context("when the response status is not 200 OK", func() {
+				it("returns an error", func() {
+					_, err := service.GetReleaseByTag("some-org", "some-repo", "missing-tag")
+					Expect(err).To(MatchError("unexpected response status: 404 Not Found"))
+				})
+			})
+
+			context("when the response JSON is malformed", func() {
+				it("returns an error", func() {
+					_, err := service.GetReleaseByTag("some-org", "malformed-repo", "v1.2.3")
+					Expect(err).To(MatchError(ContainSubstring("invalid character '%'")))
+				})
+			})
+		})
+	})
+
+	context("List", func() {
+		it.Before(func() {
+			api = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				dump, _ := httputil.DumpRequest(req, true)
+
+				if req.Header.Get("Authorization") != "token some-github-token" {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+
+				switch req.URL.Path {
+				case "/repos/some-org/some-repo/releases":
+					if req.URL.Query().Get("per_page") != "100" {
+						Fail(fmt.Sprintf("unexpected request:\n%s", dump))
+					}
+
+					switch req.URL.Query().Get("page") {
+					case "":
+						w.Header().Set("Link", fmt.Sprintf(`<%s/repos/some-org/some-repo/releases?per_page=100&page=2>; rel="next", <%s/repos/some-org/some-repo/releases?per_page=100&page=2>; rel="last"`, api.URL, api.URL))
+						w.Write([]byte(`[
+  {
+    "tag_name": "v2.0.0",
+    "tarball_url": "some-tarball-url",
+    "prerelease": true
+  }
+]`))
+					case "2":
+						w.Header().Set("Link", fmt.Sprintf(`<%s/repos/some-org/some-repo/releases?per_page=100&page=1>; rel="prev"`, api.URL))
+						w.Write([]byte(`[
+  {
+    "tag_name": "v1.0.0",
+    "tarball_url": "other-tarball-url",
+    "draft": true
+  }
+]`))
+					default:
+						Fail(fmt.Sprintf("unexpected request:\n%s", dump))
+					}
+				case "/repos/some-org/missing-repo/releases":
+					w.WriteHeader(http.StatusNotFound)
+				case "/repos/some-org/malformed-repo/releases":
+					w.Write([]byte("%%%"))
+				default:
+					Fail(fmt.Sprintf("unexpected request:\n%s", dump))
+				}
+			}))
+
+			service = github.NewReleaseService(github.Config{
+				Endpoint: api.URL,
+				Token:    "some-github-token",
+			})
+		})
+
+		it("fetches every page of releases", func() {
+			releases, err := service.List("some-org", "some-repo")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(releases).To(Equal([]github.Release{
+				{
+					TagName:    "v2.0.0",
+					TarballURL: "some-tarball-url",
+					Prerelease: true,
+				},
+				{
+					TagName:    "v1.0.0",
+					TarballURL: "other-tarball-url",
+					Draft:      true,
+				},
+			}))
+		})
+
+		context("failure cases", func() {
+			context("when the request url is malformed", func() {
+				it.Before(func() {
+					service = github.NewReleaseService(github.Config{
+						Endpoint: "%%%",
+					})
+				})
+
+				it("returns an error", func() {
+					_, err := service.List("some-org", "some-repo")
+					Expect(err).To(MatchError(ContainSubstring("invalid URL escape \"%%%\"")))
+				})
+			})
+
+			context("when the response status is not 200 OK", func() {
+				it("returns an error", func() {
+					_, err := service.List("some-org", "missing-repo")
+					Expect(err).To(MatchError("unexpected response status: 404 Not Found"))
+				})
+			})
+
+			context("when the response JSON is malformed", func() {
+				it("returns an error", func() {
+					_, err := service.List("some-org", "malformed-repo")
+					Expect(err).To(MatchError(ContainSubstring("invalid character '%'")))
+				})
+			})
+		})
+	})
+
 	context("GetReleaseAsset", func() {
 		it.Before(func() {
 			api = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
@@ -161,6 +466,8 @@ func testReleaseService(t *testing.T, context spec.G, it spec.S) {
 					w.Write([]byte(`some-asset`))
 				case "/not-found":
 					w.WriteHeader(http.StatusForbidden)
+				case "/missing-asset":
+					w.WriteHeader(http.StatusNotFound)
 				default:
 					Fail(fmt.Sprintf("unexpected request:\n%s", dump))
 				}
@@ -224,6 +531,17 @@ func testReleaseService(t *testing.T, context spec.G, it spec.S) {
 		})
 
 		context("failure cases", func() {
+			context("when the asset does not exist", func() {
+				it("returns an error", func() {
+					_, err := service.GetReleaseAsset(github.ReleaseAsset{
+						URL: fmt.Sprintf("%s/missing-asset", api.URL),
+					})
+					Expect(err).To(MatchError("unexpected response status: 404 Not Found"))
+					Expect(err).To(MatchError(github.ErrAssetNotFound))
+					Expect(err).NotTo(MatchError(github.ErrReleaseNotFound))
+				})
+			})
+
 			context("when the url is malformed", func() {
 				it("returns an error", func() {
 					_, err := service.GetReleaseAsset(github.ReleaseAsset{
@@ -246,6 +564,18 @@ func testReleaseService(t *testing.T, context spec.G, it spec.S) {
 				})
 			})
 
+			context("when the context is done", func() {
+				it("returns an error", func() {
+					ctx, cancel := gocontext.WithCancel(gocontext.Background())
+					cancel()
+
+					_, err := service.GetReleaseAssetContext(ctx, github.ReleaseAsset{
+						URL: fmt.Sprintf("%s/some-url", api.URL),
+					})
+					Expect(err).To(MatchError(gocontext.Canceled))
+				})
+			})
+
 			context("when the status code is not ok", func() {
 				it("returns an error", func() {
 					_, err := service.GetReleaseAsset(github.ReleaseAsset{
@@ -349,6 +679,16 @@ func testReleaseService(t *testing.T, context spec.G, it spec.S) {
 				})
 			})
 
+			context("when the context is done", func() {
+				it("returns an error", func() {
+					ctx, cancel := gocontext.WithCancel(gocontext.Background())
+					cancel()
+
+					_, err := service.GetReleaseTarballContext(ctx, fmt.Sprintf("%s/some-url", api.URL))
+					Expect(err).To(MatchError(gocontext.Canceled))
+				})
+			})
+
 			context("when the status code is not ok", func() {
 				it("returns an error", func() {
 					_, err := service.GetReleaseTarball(fmt.Sprintf("%s/not-found", api.URL))
@@ -357,4 +697,67 @@ func testReleaseService(t *testing.T, context spec.G, it spec.S) {
 			})
 		})
 	})
+
+	context("response bodies", func() {
+		var transport *bodyTransport
+
+		it.Before(func() {
+			transport = &bodyTransport{
+				status: http.StatusOK,
+				body:   &trackedBody{Reader: strings.NewReader(`{"tag_name": "some-tag"} `)},
+			}
+
+			service = github.NewReleaseService(github.NewConfig("https://api.example.com", "").WithTransport(transport))
+		})
+
+		it("drains and closes them after decoding", func() {
+			_, err := service.Get("some-org", "some-repo")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(transport.body.Len()).To(Equal(0))
+			Expect(transport.body.closed).To(BeTrue())
+		})
+
+		context("when the JSON is malformed", func() {
+			it.Before(func() {
+				transport.body = &trackedBody{Reader: strings.NewReader("%%%")}
+			})
+
+			it("closes them", func() {
+				_, err := service.Get("some-org", "some-repo")
+				Expect(err).To(HaveOccurred())
+
+				Expect(transport.body.closed).To(BeTrue())
+			})
+		})
+
+		context("when the response status is unexpected", func() {
+			it.Before(func() {
+				transport.status = http.StatusNotFound
+				transport.body = &trackedBody{Reader: strings.NewReader(`{"message": "Not Found"}`)}
+			})
+
+			it("drains and closes them", func() {
+				_, err := service.GetReleaseTarball("https://api.example.com/some-tarball")
+				Expect(err).To(MatchError(github.ErrAssetNotFound))
+
+				Expect(transport.body.Len()).To(Equal(0))
+				Expect(transport.body.closed).To(BeTrue())
+			})
+		})
+
+		context("when the release has not been modified", func() {
+			it.Before(func() {
+				transport.status = http.StatusNotModified
+				transport.body = &trackedBody{Reader: strings.NewReader("")}
+			})
+
+			it("closes them", func() {
+				_, err := service.GetIfModifiedContext(gocontext.Background(), "some-org", "some-repo", `"some-etag"`, "")
+				Expect(err).To(MatchError(github.ErrNotModified))
+
+				Expect(transport.body.closed).To(BeTrue())
+			})
+		})
+	})
 }
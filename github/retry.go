@@ -0,0 +1,22 @@
+package github
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ForestEckhardt/freezer/internal/httpclient"
+)
+
+// RetryPolicy determines how requests that fail with a transient error, a
+// network error or a 5xx or 429 response, are retried.
+type RetryPolicy = httpclient.RetryPolicy
+
+// DefaultRetryPolicy is the policy used by configurations created with
+// NewConfig.
+var DefaultRetryPolicy = httpclient.DefaultRetryPolicy
+
+// do sends the request built by newRequest, retrying it and waiting for rate
+// limits to reset according to the configuration.
+func (rs ReleaseService) do(ctx context.Context, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	return rs.config.sender().Do(ctx, newRequest)
+}
@@ -0,0 +1,207 @@
+package github_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ForestEckhardt/freezer/github"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testRetry(t *testing.T, context spec.G, it spec.S) {
+	var (
+		api     *httptest.Server
+		service github.ReleaseService
+
+		mutex    sync.Mutex
+		requests []*http.Request
+		handlers []http.HandlerFunc
+	)
+
+	const content = "some-asset-content-that-is-long-enough-to-be-split"
+
+	it.Before(func() {
+		requests = nil
+		handlers = nil
+
+		api = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			mutex.Lock()
+			requests = append(requests, req)
+			handler := handlers[0]
+			if len(handlers) > 1 {
+				handlers = handlers[1:]
+			}
+			mutex.Unlock()
+
+			handler(w, req)
+		}))
+
+		service = github.NewReleaseService(github.NewConfig(api.URL, "some-github-token").WithRetryPolicy(github.RetryPolicy{
+			Attempts:       3,
+			InitialBackoff: time.Millisecond,
+			MaxBackoff:     time.Millisecond,
+		}))
+	})
+
+	it.After(func() {
+		api.Close()
+	})
+
+	status := func(code int) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(code)
+		}
+	}
+
+	release := func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`{"tag_name": "some-tag"}`))
+	}
+
+	context("when a request fails with a transient error", func() {
+		it.Before(func() {
+			handlers = []http.HandlerFunc{status(http.StatusBadGateway), status(http.StatusTooManyRequests), release}
+		})
+
+		it("retries it", func() {
+			release, err := service.Get("some-org", "some-repo")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(release.TagName).To(Equal("some-tag"))
+
+			Expect(requests).To(HaveLen(3))
+		})
+	})
+
+	context("when a request keeps failing with a transient error", func() {
+		it.Before(func() {
+			handlers = []http.HandlerFunc{status(http.StatusServiceUnavailable)}
+		})
+
+		it("gives up once the attempts are exhausted", func() {
+			_, err := service.Get("some-org", "some-repo")
+			Expect(err).To(MatchError("unexpected response status: 503 Service Unavailable"))
+
+			Expect(requests).To(HaveLen(3))
+		})
+	})
+
+	context("when a request fails with an error that is not transient", func() {
+		it.Before(func() {
+			handlers = []http.HandlerFunc{status(http.StatusNotFound)}
+		})
+
+		it("does not retry it", func() {
+			_, err := service.Get("some-org", "some-repo")
+			Expect(err).To(MatchError("unexpected response status: 404 Not Found"))
+
+			Expect(requests).To(HaveLen(1))
+		})
+	})
+
+	context("when retries are disabled", func() {
+		it.Before(func() {
+			handlers = []http.HandlerFunc{status(http.StatusBadGateway), release}
+			service = github.NewReleaseService(github.Config{Endpoint: api.URL})
+		})
+
+		it("makes a single request", func() {
+			_, err := service.Get("some-org", "some-repo")
+			Expect(err).To(MatchError("unexpected response status: 502 Bad Gateway"))
+
+			Expect(requests).To(HaveLen(1))
+		})
+	})
+
+	context("when a download is interrupted", func() {
+		truncated := func(w http.ResponseWriter, req *http.Request) {
+			//Declaring more content than is written makes the server close the
+			//connection part way through the body
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.Header().Set("ETag", `"some-etag"`)
+			w.Write([]byte(content[:10]))
+		}
+
+		it.Before(func() {
+			handlers = []http.HandlerFunc{truncated, func(w http.ResponseWriter, req *http.Request) {
+				w.Header().Set("Content-Range", fmt.Sprintf("bytes 10-%d/%d", len(content)-1, len(content)))
+				w.WriteHeader(http.StatusPartialContent)
+				w.Write([]byte(content[10:]))
+			}}
+		})
+
+		it("resumes it from where it stopped", func() {
+			body, err := service.GetReleaseAsset(github.ReleaseAsset{URL: api.URL + "/some-asset"})
+			Expect(err).NotTo(HaveOccurred())
+
+			received, err := io.ReadAll(body)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(received)).To(Equal(content))
+			Expect(body.Close()).To(Succeed())
+
+			Expect(requests).To(HaveLen(2))
+			Expect(requests[1].Header.Get("Range")).To(Equal("bytes=10-"))
+			Expect(requests[1].Header.Get("If-Range")).To(Equal(`"some-etag"`))
+			Expect(requests[1].Header.Get("Accept")).To(Equal("application/octet-stream"))
+		})
+
+		context("and the server does not support ranges", func() {
+			it.Before(func() {
+				handlers = []http.HandlerFunc{func(w http.ResponseWriter, req *http.Request) {
+					w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+					w.Write([]byte(content[:10]))
+				}, func(w http.ResponseWriter, req *http.Request) {
+					w.Write([]byte(content))
+				}}
+			})
+
+			it("skips the part that was already read", func() {
+				body, err := service.GetReleaseTarball(api.URL + "/some-tarball")
+				Expect(err).NotTo(HaveOccurred())
+
+				received, err := io.ReadAll(body)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(received)).To(Equal(content))
+			})
+		})
+
+		context("and the file has changed since the download started", func() {
+			it.Before(func() {
+				handlers = []http.HandlerFunc{truncated, func(w http.ResponseWriter, req *http.Request) {
+					w.Write([]byte("some-other-content"))
+				}}
+			})
+
+			it("returns an error", func() {
+				body, err := service.GetReleaseAsset(github.ReleaseAsset{URL: api.URL + "/some-asset"})
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = io.ReadAll(body)
+				Expect(err).To(MatchError(ContainSubstring("the file changed while it was being downloaded")))
+			})
+		})
+
+		context("and it cannot be resumed", func() {
+			it.Before(func() {
+				handlers = []http.HandlerFunc{truncated, status(http.StatusBadGateway)}
+			})
+
+			it("returns an error once the attempts are exhausted", func() {
+				body, err := service.GetReleaseAsset(github.ReleaseAsset{URL: api.URL + "/some-asset"})
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = io.ReadAll(body)
+				Expect(err).To(MatchError(io.ErrUnexpectedEOF))
+				Expect(err).To(MatchError(ContainSubstring("unexpected response status: 502 Bad Gateway")))
+
+				Expect(requests).To(HaveLen(3))
+			})
+		})
+	})
+}
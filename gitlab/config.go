@@ -0,0 +1,72 @@
+package gitlab
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ForestEckhardt/freezer/github"
+	"github.com/ForestEckhardt/freezer/internal/httpclient"
+)
+
+type Config struct {
+	// Endpoint is the API root of the GitLab instance, such as
+	// https://gitlab.com/api/v4.
+	Endpoint string
+
+	// Token is a personal, group or project access token. Requests are made
+	// anonymously when it is empty.
+	Token string
+
+	// Client is used to make every request. When it is nil httpclient.Default
+	// is used.
+	Client *http.Client
+
+	// Retry determines how requests that fail with a transient error are
+	// retried. Its zero value disables retries.
+	Retry github.RetryPolicy
+
+	// RateLimitWait is the longest a request waits, in total, for a rate
+	// limit to reset before failing with a github.RateLimitError. Its zero
+	// value fails as soon as the limit is hit.
+	RateLimitWait time.Duration
+}
+
+func NewConfig(endpoint, token string) Config {
+	return Config{
+		Endpoint: endpoint,
+		Token:    token,
+		Retry:    github.DefaultRetryPolicy,
+	}
+}
+
+func (c Config) WithRetryPolicy(retry github.RetryPolicy) Config {
+	c.Retry = retry
+	return c
+}
+
+func (c Config) WithRateLimitWait(wait time.Duration) Config {
+	c.RateLimitWait = wait
+	return c
+}
+
+func (c Config) WithClient(client *http.Client) Config {
+	c.Client = client
+	return c
+}
+
+func (c Config) client() *http.Client {
+	if c.Client != nil {
+		return c.Client
+	}
+
+	return httpclient.Default
+}
+
+func (c Config) sender() httpclient.Sender {
+	return httpclient.Sender{
+		Client:        c.client(),
+		Retry:         c.Retry,
+		RateLimitWait: c.RateLimitWait,
+		Provider:      "GitLab",
+	}
+}
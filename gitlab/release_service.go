@@ -0,0 +1,227 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ForestEckhardt/freezer/github"
+	"github.com/ForestEckhardt/freezer/internal/httpclient"
+)
+
+// ReleaseService fetches releases from GitLab and presents them the way
+// github.ReleaseService does, so that the two can be used interchangeably.
+// Projects in subgroups are addressed with the full path of their group as
+// the org.
+type ReleaseService struct {
+	config Config
+}
+
+// release is a release as GitLab describes it.
+type release struct {
+	TagName         string `json:"tag_name"`
+	UpcomingRelease bool   `json:"upcoming_release"`
+	Assets          struct {
+		Sources []struct {
+			Format string `json:"format"`
+			URL    string `json:"url"`
+		} `json:"sources"`
+		Links []struct {
+			Name           string `json:"name"`
+			URL            string `json:"url"`
+			DirectAssetURL string `json:"direct_asset_url"`
+		} `json:"links"`
+	} `json:"assets"`
+}
+
+func NewReleaseService(config Config) ReleaseService {
+	return ReleaseService{
+		config: config,
+	}
+}
+
+// Get returns the latest release of the project.
+func (rs ReleaseService) Get(org, repo string) (github.Release, error) {
+	return rs.GetContext(context.Background(), org, repo)
+}
+
+// GetContext is like Get but aborts the request when ctx is done.
+func (rs ReleaseService) GetContext(ctx context.Context, org, repo string) (github.Release, error) {
+	return rs.getRelease(ctx, rs.projectURL(org, repo, "/releases/permalink/latest"), nil)
+}
+
+// GetIfModifiedContext is like GetContext but makes a conditional request,
+// returning github.ErrNotModified if the latest release has not changed.
+func (rs ReleaseService) GetIfModifiedContext(ctx context.Context, org, repo, etag, lastModified string) (github.Release, error) {
+	header := http.Header{}
+	if etag != "" {
+		header.Set("If-None-Match", etag)
+	}
+
+	if lastModified != "" {
+		header.Set("If-Modified-Since", lastModified)
+	}
+
+	return rs.getRelease(ctx, rs.projectURL(org, repo, "/releases/permalink/latest"), header)
+}
+
+func (rs ReleaseService) GetReleaseByTag(org, repo, tag string) (github.Release, error) {
+	return rs.GetReleaseByTagContext(context.Background(), org, repo, tag)
+}
+
+// GetReleaseByTagContext is like GetReleaseByTag but aborts the request when
+// ctx is done.
+func (rs ReleaseService) GetReleaseByTagContext(ctx context.Context, org, repo, tag string) (github.Release, error) {
+	return rs.getRelease(ctx, rs.projectURL(org, repo, "/releases/"+url.PathEscape(tag)), nil)
+}
+
+// List returns every release of the project, following the pagination links
+// returned by the API until the last page is reached.
+func (rs ReleaseService) List(org, repo string) ([]github.Release, error) {
+	return rs.ListContext(context.Background(), org, repo)
+}
+
+// ListContext is like List but aborts the requests when ctx is done.
+func (rs ReleaseService) ListContext(ctx context.Context, org, repo string) ([]github.Release, error) {
+	var releases []github.Release
+	next := rs.projectURL(org, repo, "/releases?per_page=100")
+	for next != "" {
+		var page []release
+		header, err := rs.getJSON(ctx, next, nil, &page)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range page {
+			releases = append(releases, r.convert())
+		}
+
+		next = httpclient.NextPageLink(header.Get("Link"))
+	}
+
+	return releases, nil
+}
+
+func (rs ReleaseService) GetReleaseAsset(asset github.ReleaseAsset) (io.ReadCloser, error) {
+	return rs.GetReleaseAssetContext(context.Background(), asset)
+}
+
+// GetReleaseAssetContext is like GetReleaseAsset but aborts the download when
+// ctx is done.
+func (rs ReleaseService) GetReleaseAssetContext(ctx context.Context, asset github.ReleaseAsset) (io.ReadCloser, error) {
+	return rs.download(ctx, asset.URL)
+}
+
+func (rs ReleaseService) GetReleaseTarball(url string) (io.ReadCloser, error) {
+	return rs.GetReleaseTarballContext(context.Background(), url)
+}
+
+// GetReleaseTarballContext is like GetReleaseTarball but aborts the download
+// when ctx is done.
+func (rs ReleaseService) GetReleaseTarballContext(ctx context.Context, url string) (io.ReadCloser, error) {
+	return rs.download(ctx, url)
+}
+
+func (rs ReleaseService) getRelease(ctx context.Context, uri string, header http.Header) (github.Release, error) {
+	var r release
+	header, err := rs.getJSON(ctx, uri, header, &r)
+	if err != nil {
+		return github.Release{}, err
+	}
+
+	converted := r.convert()
+	converted.ETag = header.Get("ETag")
+	converted.LastModified = header.Get("Last-Modified")
+
+	return converted, nil
+}
+
+// getJSON decodes the response to a request for uri, sent with the given
+// additional headers, into v and returns the headers of the response.
+func (rs ReleaseService) getJSON(ctx context.Context, uri string, header http.Header, v interface{}) (http.Header, error) {
+	resp, err := rs.config.sender().Do(ctx, func() (*http.Request, error) {
+		req, err := rs.newRequest(ctx, uri)
+		if err != nil {
+			return nil, err
+		}
+
+		for name, values := range header {
+			req.Header[name] = values
+		}
+
+		return req, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return httpclient.DecodeJSON(resp, uri, v, github.ErrReleaseNotFound, github.ErrNotModified)
+}
+
+func (rs ReleaseService) download(ctx context.Context, uri string) (io.ReadCloser, error) {
+	resp, err := rs.config.sender().Do(ctx, func() (*http.Request, error) {
+		return rs.newRequest(ctx, uri)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, httpclient.NewStatusError(resp, uri, github.ErrAssetNotFound)
+	}
+
+	return resp.Body, nil
+}
+
+func (rs ReleaseService) newRequest(ctx context.Context, uri string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", uri, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if rs.config.Token != "" {
+		req.Header.Set("PRIVATE-TOKEN", rs.config.Token)
+	}
+
+	return req, nil
+}
+
+// projectURL returns the URL of an API path of the project org/repo, which
+// GitLab expects as a single, encoded path segment.
+func (rs ReleaseService) projectURL(org, repo, path string) string {
+	return fmt.Sprintf("%s/projects/%s%s", strings.TrimSuffix(rs.config.Endpoint, "/"), url.PathEscape(org+"/"+repo), path)
+}
+
+// convert describes the release the way GitHub would. The links of a release
+// are its assets and upcoming releases, which have not been released yet, are
+// treated as prereleases.
+func (r release) convert() github.Release {
+	converted := github.Release{
+		TagName:    r.TagName,
+		Prerelease: r.UpcomingRelease,
+	}
+
+	for _, link := range r.Assets.Links {
+		uri := link.DirectAssetURL
+		if uri == "" {
+			uri = link.URL
+		}
+
+		converted.Assets = append(converted.Assets, github.ReleaseAsset{
+			URL:                uri,
+			Name:               link.Name,
+			BrowserDownloadURL: link.URL,
+		})
+	}
+
+	for _, source := range r.Assets.Sources {
+		if source.Format == "tar.gz" {
+			converted.TarballURL = source.URL
+		}
+	}
+
+	return converted
+}
@@ -0,0 +1,208 @@
+package gitlab_test
+
+import (
+	gocontext "context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"testing"
+	"time"
+
+	"github.com/ForestEckhardt/freezer/github"
+	"github.com/ForestEckhardt/freezer/gitlab"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testReleaseService(t *testing.T, context spec.G, it spec.S) {
+	var (
+		service gitlab.ReleaseService
+		api     *httptest.Server
+		ctx     gocontext.Context
+
+		flakyRequests int
+	)
+
+	it.Before(func() {
+		ctx = gocontext.Background()
+		flakyRequests = 0
+
+		api = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			dump, _ := httputil.DumpRequest(req, true)
+
+			if req.Header.Get("PRIVATE-TOKEN") != "some-gitlab-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			switch req.URL.EscapedPath() {
+			case "/api/v4/projects/some-group%2Fsome-subgroup%2Fsome-project/releases/permalink/latest":
+				http.Redirect(w, req, "/api/v4/projects/some-group%2Fsome-subgroup%2Fsome-project/releases/v1.2.3", http.StatusFound)
+
+			case "/api/v4/projects/some-group%2Fsome-subgroup%2Fsome-project/releases/v1.2.3":
+				if req.Header.Get("If-None-Match") == `"some-etag"` {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+
+				w.Header().Set("ETag", `"some-etag"`)
+				fmt.Fprintf(w, `{
+  "tag_name": "v1.2.3",
+  "assets": {
+    "sources": [
+      {"format": "zip", "url": "%[1]s/some-project-v1.2.3.zip"},
+      {"format": "tar.gz", "url": "%[1]s/some-project-v1.2.3.tar.gz"}
+    ],
+    "links": [
+      {"name": "some-project-1.2.3.cnb", "url": "%[1]s/some-link", "direct_asset_url": "%[1]s/some-direct-link"}
+    ]
+  }
+}`, api.URL)
+
+			case "/api/v4/projects/some-group%2Fsome-subgroup%2Fsome-project/releases":
+				if req.URL.Query().Get("page") == "2" {
+					fmt.Fprint(w, `[{"tag_name": "v1.0.0", "assets": {}}]`)
+					return
+				}
+
+				w.Header().Set("Link", fmt.Sprintf(`<%s/api/v4/projects/some-group%%2Fsome-subgroup%%2Fsome-project/releases?per_page=100&page=2>; rel="next"`, api.URL))
+				fmt.Fprint(w, `[{"tag_name": "v2.0.0", "upcoming_release": true, "assets": {}}]`)
+
+			case "/api/v4/projects/some-group%2Fsome-subgroup%2Fmissing-project/releases/permalink/latest":
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `{"message": "404 Project Not Found"}`)
+
+			case "/api/v4/projects/some-group%2Fsome-subgroup%2Fflaky-project/releases/permalink/latest":
+				flakyRequests++
+				if flakyRequests == 1 {
+					w.WriteHeader(http.StatusBadGateway)
+					return
+				}
+
+				fmt.Fprint(w, `{"tag_name": "v1.2.3", "assets": {}}`)
+
+			case "/api/v4/projects/some-group%2Fsome-subgroup%2Flimited-project/releases/permalink/latest":
+				w.Header().Set("RateLimit-Limit", "2000")
+				w.Header().Set("RateLimit-Remaining", "0")
+				w.Header().Set("RateLimit-Reset", "4102444800")
+				w.WriteHeader(http.StatusTooManyRequests)
+
+			case "/some-direct-link":
+				fmt.Fprint(w, "some-asset")
+
+			case "/missing-link":
+				w.WriteHeader(http.StatusNotFound)
+
+			default:
+				Fail(fmt.Sprintf("unexpected request:\n%s", dump))
+			}
+		}))
+
+		service = gitlab.NewReleaseService(gitlab.NewConfig(api.URL+"/api/v4", "some-gitlab-token").WithRetryPolicy(github.RetryPolicy{
+			Attempts:       2,
+			InitialBackoff: time.Millisecond,
+			MaxBackoff:     time.Millisecond,
+		}))
+	})
+
+	it.After(func() {
+		api.Close()
+	})
+
+	context("GetContext", func() {
+		it("fetches the latest release", func() {
+			release, err := service.GetContext(ctx, "some-group/some-subgroup", "some-project")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(release).To(Equal(github.Release{
+				TagName: "v1.2.3",
+				Assets: []github.ReleaseAsset{
+					{
+						URL:                api.URL + "/some-direct-link",
+						Name:               "some-project-1.2.3.cnb",
+						BrowserDownloadURL: api.URL + "/some-link",
+					},
+				},
+				TarballURL: api.URL + "/some-project-v1.2.3.tar.gz",
+				ETag:       `"some-etag"`,
+			}))
+		})
+
+		context("when a request fails with a transient error", func() {
+			it("retries it", func() {
+				release, err := service.GetContext(ctx, "some-group/some-subgroup", "flaky-project")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(release.TagName).To(Equal("v1.2.3"))
+				Expect(flakyRequests).To(Equal(2))
+			})
+		})
+
+		context("failure cases", func() {
+			context("when the project does not exist", func() {
+				it("returns an error", func() {
+					_, err := service.GetContext(ctx, "some-group/some-subgroup", "missing-project")
+					Expect(err).To(MatchError(github.ErrReleaseNotFound))
+					Expect(err).To(MatchError(`unexpected response status: 404 Not Found: {"message": "404 Project Not Found"}`))
+				})
+			})
+
+			context("when the rate limit has been exceeded", func() {
+				it("returns a rate limit error naming GitLab", func() {
+					_, err := service.GetContext(ctx, "some-group/some-subgroup", "limited-project")
+					Expect(err).To(BeAssignableToTypeOf(&github.RateLimitError{}))
+					Expect(err.(*github.RateLimitError).Limit).To(Equal(2000))
+					Expect(err).To(MatchError(ContainSubstring("GitLab API rate limit exceeded: 429 Too Many Requests (resets at")))
+				})
+			})
+		})
+	})
+
+	context("GetIfModifiedContext", func() {
+		it("returns ErrNotModified when the release has not changed", func() {
+			_, err := service.GetIfModifiedContext(ctx, "some-group/some-subgroup", "some-project", `"some-etag"`, "")
+			Expect(err).To(MatchError(github.ErrNotModified))
+		})
+	})
+
+	context("GetReleaseByTagContext", func() {
+		it("fetches the release with the tag", func() {
+			release, err := service.GetReleaseByTagContext(ctx, "some-group/some-subgroup", "some-project", "v1.2.3")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(release.TagName).To(Equal("v1.2.3"))
+		})
+	})
+
+	context("ListContext", func() {
+		it("fetches every page of releases", func() {
+			releases, err := service.ListContext(ctx, "some-group/some-subgroup", "some-project")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(releases).To(Equal([]github.Release{
+				{TagName: "v2.0.0", Prerelease: true},
+				{TagName: "v1.0.0"},
+			}))
+		})
+	})
+
+	context("GetReleaseAssetContext", func() {
+		it("downloads the asset", func() {
+			bundle, err := service.GetReleaseAssetContext(ctx, github.ReleaseAsset{URL: api.URL + "/some-direct-link"})
+			Expect(err).NotTo(HaveOccurred())
+			defer bundle.Close()
+
+			content, err := io.ReadAll(bundle)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(Equal("some-asset"))
+		})
+
+		context("failure cases", func() {
+			context("when the asset does not exist", func() {
+				it("returns an error", func() {
+					_, err := service.GetReleaseAssetContext(ctx, github.ReleaseAsset{URL: api.URL + "/missing-link"})
+					Expect(err).To(MatchError(github.ErrAssetNotFound))
+				})
+			})
+		})
+	})
+}
@@ -3,6 +3,8 @@ module github.com/ForestEckhardt/freezer
 go 1.16
 
 require (
+	github.com/BurntSushi/toml v1.2.0
+	github.com/Masterminds/semver/v3 v3.1.1
 	github.com/oklog/ulid v1.3.1
 	github.com/onsi/gomega v1.20.2
 	github.com/paketo-buildpacks/packit/v2 v2.6.1
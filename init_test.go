@@ -11,10 +11,16 @@ var gitToken string
 
 func TestFreezer(t *testing.T) {
 	suite := spec.New("freezer", spec.Report(report.Terminal{}))
+	suite("CacheDir", testCacheDir)
 	suite("CacheManager", testCacheManager)
+	suite("CommandExecutable", testCommandExecutable)
+	suite("Fetcher", testFetcher)
 	suite("LocalFetcher", testLocalFetcher)
 	suite("PackingTools", testPackingTools)
+	suite("PatternAssetMatcher", testPatternAssetMatcher)
 	suite("RandomName", testRandomName)
+	suite("RegistryFetcher", testRegistryFetcher)
 	suite("RemoteFetcher", testRemoteFetcher)
+	suite("URLFetcher", testURLFetcher)
 	suite.Run(t)
 }
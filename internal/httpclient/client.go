@@ -0,0 +1,44 @@
+package httpclient
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	// DialTimeout bounds how long establishing a connection may take.
+	DialTimeout = 30 * time.Second
+
+	// TLSHandshakeTimeout bounds how long the TLS handshake may take.
+	TLSHandshakeTimeout = 10 * time.Second
+
+	// ResponseHeaderTimeout bounds how long to wait for the headers of a
+	// response once the request has been sent. Reading the body is not
+	// bounded, as downloads can be large; use a context to bound it.
+	ResponseHeaderTimeout = 60 * time.Second
+)
+
+// Default is the client used when a configuration does not name one. It has
+// the default timeouts and honours the HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+// environment variables.
+var Default = New()
+
+// New returns a client configured like Default.
+func New() *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   DialTimeout,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   TLSHandshakeTimeout,
+			ResponseHeaderTimeout: ResponseHeaderTimeout,
+			ExpectContinueTimeout: 1 * time.Second,
+		},
+	}
+}
@@ -0,0 +1,26 @@
+package httpclient_test
+
+import (
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestHTTPClient(t *testing.T) {
+	suite := spec.New("httpclient", spec.Report(report.Terminal{}))
+	suite("JSON", testJSON)
+	suite("Response", testResponse)
+
+	suite.Before(func(t *testing.T) {
+		RegisterTestingT(t)
+	})
+
+	suite.Run(t)
+}
+
+func Fail(message string) {
+	panic(message)
+}
@@ -0,0 +1,51 @@
+package httpclient
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// DecodeJSON decodes the body of resp, the response to a request for uri, into
+// v and returns the headers of the response. Any status but 200 is returned as
+// a StatusError matching notFound, except that a 304 response is returned as
+// notModified when it is not nil. The body is always drained and closed.
+func DecodeJSON(resp *http.Response, uri string, v interface{}, notFound, notModified error) (http.Header, error) {
+	if resp.StatusCode == http.StatusNotModified && notModified != nil {
+		Discard(resp)
+		return nil, notModified
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, NewStatusError(resp, uri, notFound)
+	}
+
+	//Draining the body lets the connection be reused
+	defer Discard(resp)
+
+	err := json.NewDecoder(resp.Body).Decode(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Header, nil
+}
+
+// NextPageLink extracts the rel="next" URL from a Link header such as
+// <https://api.example.com/...&page=2>; rel="next", <...>; rel="last"
+func NextPageLink(header string) string {
+	for _, link := range strings.Split(header, ",") {
+		segments := strings.Split(link, ";")
+		if len(segments) < 2 {
+			continue
+		}
+
+		for _, param := range segments[1:] {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(segments[0]), "<>")
+			}
+		}
+	}
+
+	return ""
+}
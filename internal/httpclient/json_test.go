@@ -0,0 +1,82 @@
+package httpclient_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ForestEckhardt/freezer/internal/httpclient"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testJSON(t *testing.T, context spec.G, it spec.S) {
+	var (
+		errNotFound    = errors.New("some-thing not found")
+		errNotModified = errors.New("some-thing not modified")
+	)
+
+	newResponse := func(statusCode int, body string) *http.Response {
+		return &http.Response{
+			StatusCode: statusCode,
+			Status:     http.StatusText(statusCode),
+			Header:     http.Header{"Etag": []string{`"some-etag"`}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}
+	}
+
+	context("DecodeJSON", func() {
+		it("decodes the body and returns the headers", func() {
+			var v struct {
+				Name string `json:"name"`
+			}
+
+			header, err := httpclient.DecodeJSON(newResponse(http.StatusOK, `{"name": "some-name"}`), "https://example.com/some-uri", &v, errNotFound, errNotModified)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(v.Name).To(Equal("some-name"))
+			Expect(header.Get("ETag")).To(Equal(`"some-etag"`))
+		})
+
+		context("when the status is 304", func() {
+			it("returns the not modified error", func() {
+				_, err := httpclient.DecodeJSON(newResponse(http.StatusNotModified, ""), "https://example.com/some-uri", &struct{}{}, errNotFound, errNotModified)
+				Expect(err).To(MatchError(errNotModified))
+			})
+
+			context("when there is no not modified error", func() {
+				it("returns a status error", func() {
+					_, err := httpclient.DecodeJSON(newResponse(http.StatusNotModified, ""), "https://example.com/some-uri", &struct{}{}, errNotFound, nil)
+
+					var statusErr *httpclient.StatusError
+					Expect(errors.As(err, &statusErr)).To(BeTrue())
+					Expect(statusErr.StatusCode).To(Equal(http.StatusNotModified))
+				})
+			})
+		})
+
+		context("when the status is 404", func() {
+			it("returns a status error matching the not found error", func() {
+				_, err := httpclient.DecodeJSON(newResponse(http.StatusNotFound, `{"message": "Not Found"}`), "https://example.com/some-uri", &struct{}{}, errNotFound, errNotModified)
+				Expect(errors.Is(err, errNotFound)).To(BeTrue())
+				Expect(err).To(MatchError(`unexpected response status: Not Found: {"message": "Not Found"}`))
+			})
+		})
+	})
+
+	context("NextPageLink", func() {
+		it("returns the next link", func() {
+			Expect(httpclient.NextPageLink(`<https://example.com/some-uri?page=2>; rel="next", <https://example.com/some-uri?page=5>; rel="last"`)).To(Equal("https://example.com/some-uri?page=2"))
+		})
+
+		context("when there is no next link", func() {
+			it("returns an empty string", func() {
+				Expect(httpclient.NextPageLink(`<https://example.com/some-uri?page=1>; rel="prev"`)).To(BeEmpty())
+				Expect(httpclient.NextPageLink("")).To(BeEmpty())
+			})
+		})
+	})
+}
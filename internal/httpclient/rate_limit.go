@@ -0,0 +1,77 @@
+package httpclient
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateLimitError is returned when a forge refuses a request because the rate
+// limit of its API has been exceeded. Unauthenticated clients have much lower
+// limits, so setting a token is the usual way to avoid it.
+type RateLimitError struct {
+	// Provider names the forge that refused the request, such as GitHub.
+	Provider string
+
+	// Status is the status of the response, either 403 Forbidden or 429 Too
+	// Many Requests.
+	Status string
+
+	// Limit is the number of requests allowed in the current window, or 0 if
+	// the forge did not say.
+	Limit int
+
+	// Reset is when requests will be allowed again, or the zero time if the
+	// forge did not say.
+	Reset time.Time
+}
+
+func (e *RateLimitError) Error() string {
+	message := "API rate limit exceeded"
+	if e.Provider != "" {
+		message = fmt.Sprintf("%s %s", e.Provider, message)
+	}
+
+	if e.Reset.IsZero() {
+		return fmt.Sprintf("%s: %s", message, e.Status)
+	}
+
+	return fmt.Sprintf("%s: %s (resets at %s)", message, e.Status, e.Reset.Format(time.RFC3339))
+}
+
+// RateLimit returns the error describing resp if it is a rate limit response
+// from provider, or nil if it is not. GitHub and Gitea answer with a 403 and
+// no remaining requests when the primary limit is exceeded, and with a 403 or
+// 429 and a Retry-After header when a secondary limit is. GitLab answers with
+// a 429 and names its headers without the X- prefix.
+func RateLimit(resp *http.Response, provider string) *RateLimitError {
+	header := func(name string) string {
+		if value := resp.Header.Get("X-" + name); value != "" {
+			return value
+		}
+
+		return resp.Header.Get(name)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusForbidden:
+		if header("RateLimit-Remaining") != "0" && resp.Header.Get("Retry-After") == "" {
+			return nil
+		}
+	case http.StatusTooManyRequests:
+	default:
+		return nil
+	}
+
+	err := &RateLimitError{Provider: provider, Status: resp.Status}
+	err.Limit, _ = strconv.Atoi(header("RateLimit-Limit"))
+
+	if after := RetryAfter(resp); after > 0 {
+		err.Reset = time.Now().Add(after)
+	} else if reset, parseErr := strconv.ParseInt(header("RateLimit-Reset"), 10, 64); parseErr == nil {
+		err.Reset = time.Unix(reset, 0)
+	}
+
+	return err
+}
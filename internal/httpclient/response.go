@@ -0,0 +1,71 @@
+package httpclient
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxErrorBodySize bounds how much of the body of an unexpected response is
+// kept in a StatusError.
+const maxErrorBodySize = 512
+
+// StatusError is returned when a server responds with an unexpected status.
+// For 404 responses it also matches the error it was created with, such as
+// one saying that a release or image does not exist.
+type StatusError struct {
+	StatusCode int
+	Status     string
+	URL        string
+
+	// Body is the beginning of the response body, which usually explains the
+	// status, with its whitespace collapsed.
+	Body string
+
+	notFound error
+}
+
+func (e *StatusError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("unexpected response status: %s", e.Status)
+	}
+
+	return fmt.Sprintf("unexpected response status: %s: %s", e.Status, e.Body)
+}
+
+func (e *StatusError) Unwrap() error {
+	if e.StatusCode == http.StatusNotFound {
+		return e.notFound
+	}
+
+	return nil
+}
+
+// NewStatusError keeps the start of the body of resp, which was returned for a
+// request for uri, and discards the rest. A 404 response matches notFound.
+func NewStatusError(resp *http.Response, uri string, notFound error) *StatusError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize+1))
+	Discard(resp)
+
+	//JSON error responses are usually indented over several lines
+	excerpt := strings.Join(strings.Fields(string(body)), " ")
+	if len(body) > maxErrorBodySize {
+		excerpt = strings.Join(strings.Fields(string(body[:maxErrorBodySize])), " ") + "..."
+	}
+
+	return &StatusError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		URL:        uri,
+		Body:       excerpt,
+		notFound:   notFound,
+	}
+}
+
+// Discard drains and closes the body of a response that will not be used so
+// that its connection can be reused.
+func Discard(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+}
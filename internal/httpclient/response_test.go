@@ -0,0 +1,55 @@
+package httpclient_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ForestEckhardt/freezer/internal/httpclient"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testResponse(t *testing.T, context spec.G, it spec.S) {
+	var errNotFound = errors.New("some-thing not found")
+
+	newResponse := func(statusCode int, body string) *http.Response {
+		return &http.Response{
+			StatusCode: statusCode,
+			Status:     http.StatusText(statusCode),
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}
+	}
+
+	context("NewStatusError", func() {
+		it("keeps the status and the start of the body with its whitespace collapsed", func() {
+			err := httpclient.NewStatusError(newResponse(http.StatusForbidden, "{\n  \"message\": \"some-message\"\n}"), "https://example.com/some-uri", errNotFound)
+
+			Expect(err.StatusCode).To(Equal(http.StatusForbidden))
+			Expect(err.URL).To(Equal("https://example.com/some-uri"))
+			Expect(err.Body).To(Equal(`{ "message": "some-message" }`))
+			Expect(err).To(MatchError(`unexpected response status: Forbidden: { "message": "some-message" }`))
+			Expect(errors.Is(err, errNotFound)).To(BeFalse())
+		})
+
+		context("when the body is long", func() {
+			it("truncates it", func() {
+				err := httpclient.NewStatusError(newResponse(http.StatusInternalServerError, strings.Repeat("a", 1024)), "https://example.com/some-uri", errNotFound)
+
+				Expect(err.Body).To(Equal(strings.Repeat("a", 512) + "..."))
+			})
+		})
+
+		context("when the status is 404", func() {
+			it("matches the not found error", func() {
+				err := httpclient.NewStatusError(newResponse(http.StatusNotFound, ""), "https://example.com/some-uri", errNotFound)
+
+				Expect(errors.Is(err, errNotFound)).To(BeTrue())
+				Expect(err).To(MatchError("unexpected response status: Not Found"))
+			})
+		})
+	})
+}
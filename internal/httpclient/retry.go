@@ -0,0 +1,173 @@
+package httpclient
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy determines how requests that fail with a transient error, a
+// network error or a 5xx or 429 response, are retried.
+type RetryPolicy struct {
+	// Attempts is the maximum number of times a request is made, including the
+	// first. A value of 0 or 1 disables retries.
+	Attempts int
+
+	// InitialBackoff is the delay before the first retry. It doubles with every
+	// retry up to MaxBackoff, and up to half of each delay is random jitter so
+	// that concurrent clients do not retry in lockstep.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// DefaultRetryPolicy is the policy used by configurations created with
+// NewConfig.
+var DefaultRetryPolicy = RetryPolicy{
+	Attempts:       4,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     10 * time.Second,
+}
+
+// Backoff returns the delay before the given retry, counting from 0.
+func (p RetryPolicy) Backoff(retry int) time.Duration {
+	backoff := p.InitialBackoff
+	for i := 0; i < retry && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+
+	half := backoff / 2
+	return half + time.Duration(rand.Int63n(int64(backoff-half)+1))
+}
+
+// RetryableStatus reports whether a response with the given status is worth
+// retrying.
+func RetryableStatus(status int) bool {
+	return status >= 500 || status == http.StatusTooManyRequests
+}
+
+// RetryAfter returns the delay requested by the Retry-After header of resp,
+// which may be given in seconds or as a date, or 0 if there is none.
+func RetryAfter(resp *http.Response) time.Duration {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+
+	return 0
+}
+
+// Sleep waits for d, or until ctx is done.
+func Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Sender sends the requests of a forge API, retrying transient failures and
+// waiting for rate limits to reset.
+type Sender struct {
+	Client        *http.Client
+	Retry         RetryPolicy
+	RateLimitWait time.Duration
+
+	// Provider names the forge in rate limit errors, such as GitHub.
+	Provider string
+}
+
+// Do sends the request built by newRequest, retrying it according to the
+// retry policy. It returns the first response that is not retryable or, once
+// the attempts are exhausted, the last response or error. Rate limit
+// responses are returned as a RateLimitError unless the limit resets within
+// the rate limit wait, in which case the request is sent again once it has.
+func (s Sender) Do(ctx context.Context, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	policy := s.Retry
+
+	var waited time.Duration
+	for attempt := 1; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+
+		var limitErr *RateLimitError
+		resp, err := s.Client.Do(req)
+		if err == nil {
+			limitErr = RateLimit(resp, s.Provider)
+			if limitErr == nil && !RetryableStatus(resp.StatusCode) {
+				return resp, nil
+			}
+
+			//A limit without a reset time is retried like any other transient
+			//failure
+			if limitErr != nil && !limitErr.Reset.IsZero() {
+				Discard(resp)
+
+				//The reset time only has a resolution of seconds
+				wait := time.Until(limitErr.Reset)
+				if wait < time.Second {
+					wait = time.Second
+				}
+
+				if waited+wait > s.RateLimitWait {
+					return nil, limitErr
+				}
+				waited += wait
+
+				err = Sleep(ctx, wait)
+				if err != nil {
+					return nil, err
+				}
+
+				//Waiting for the limit to reset does not use up an attempt
+				attempt--
+				continue
+			}
+		}
+
+		if ctx.Err() != nil || attempt >= policy.Attempts {
+			if limitErr != nil {
+				Discard(resp)
+				return nil, limitErr
+			}
+
+			return resp, err
+		}
+
+		delay := policy.Backoff(attempt - 1)
+		if resp != nil {
+			if after := RetryAfter(resp); after > delay {
+				delay = after
+				if delay > policy.MaxBackoff {
+					delay = policy.MaxBackoff
+				}
+			}
+
+			Discard(resp)
+		}
+
+		err = Sleep(ctx, delay)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
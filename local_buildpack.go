@@ -1,6 +1,17 @@
 package freezer
 
-import "fmt"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/BurntSushi/toml"
+)
 
 type LocalBuildpack struct {
 	Path        string
@@ -19,3 +30,113 @@ func NewLocalBuildpack(path, name string) LocalBuildpack {
 		CachedKey:   fmt.Sprintf("%s:cached", name),
 	}
 }
+
+type buildpackConfig struct {
+	Metadata struct {
+		IncludeFiles           []string `toml:"include-files"`
+		IncludeFilesUnderscore []string `toml:"include_files"`
+		PrePackage             string   `toml:"pre-package"`
+		PrePackageUnderscore   string   `toml:"pre_package"`
+	} `toml:"metadata"`
+}
+
+// sourceDigest computes a digest over everything that affects the packaged
+// buildpack: the files listed in include-files of its buildpack.toml, the
+// requested version and whether it is packaged for offline use. When the
+// buildpack has a pre-package script, or does not list its files, the script
+// may read anything in the source tree so the whole tree is included instead.
+func (l LocalBuildpack) sourceDigest() (string, error) {
+	var config buildpackConfig
+	_, err := toml.DecodeFile(filepath.Join(l.Path, "buildpack.toml"), &config)
+	if err != nil {
+		return "", err
+	}
+
+	includeFiles := append(config.Metadata.IncludeFiles, config.Metadata.IncludeFilesUnderscore...)
+	prePackage := config.Metadata.PrePackage + config.Metadata.PrePackageUnderscore
+
+	roots := append([]string{"buildpack.toml"}, includeFiles...)
+	if prePackage != "" || len(includeFiles) == 0 {
+		roots = []string{"."}
+	}
+
+	var files []string
+	for _, root := range roots {
+		err = filepath.Walk(filepath.Join(l.Path, root), func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				//Files generated by the pre-package script need not exist yet
+				if errors.Is(err, os.ErrNotExist) {
+					files = append(files, root)
+					return nil
+				}
+				return err
+			}
+
+			if info.IsDir() {
+				if info.Name() == ".git" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			rel, err := filepath.Rel(l.Path, path)
+			if err != nil {
+				return err
+			}
+			files = append(files, rel)
+
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	sort.Strings(files)
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "version=%s\x00offline=%t\x00", l.Version, l.Offline)
+
+	for i, file := range files {
+		if i > 0 && files[i-1] == file {
+			continue
+		}
+
+		path := filepath.Join(l.Path, file)
+		fmt.Fprintf(hash, "%s\x00", filepath.ToSlash(file))
+
+		info, err := os.Lstat(path)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				fmt.Fprint(hash, "missing\x00")
+				continue
+			}
+			return "", err
+		}
+
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return "", err
+			}
+			fmt.Fprintf(hash, "symlink=%s\x00", target)
+
+		case info.Mode().IsRegular():
+			fmt.Fprintf(hash, "mode=%o size=%d\x00", info.Mode().Perm(), info.Size())
+
+			f, err := os.Open(path)
+			if err != nil {
+				return "", err
+			}
+
+			_, err = io.Copy(hash, f)
+			f.Close()
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+
+	return fmt.Sprintf("sha256:%s", hex.EncodeToString(hash.Sum(nil))), nil
+}
@@ -1,6 +1,7 @@
 package freezer
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -13,24 +14,39 @@ type Namer interface {
 
 type LocalFetcher struct {
 	buildpackCache BuildpackCache
-	packager       Packager
+	packager       PackagerContext
 	namer          Namer
 }
 
 func NewLocalFetcher(buildpackCache BuildpackCache, packager Packager, namer Namer) LocalFetcher {
 	return LocalFetcher{
 		buildpackCache: buildpackCache,
-		packager:       packager,
+		packager:       packagerContext(packager),
 		namer:          namer,
 	}
 }
 
 func (l LocalFetcher) WithPackager(packager Packager) LocalFetcher {
-	l.packager = packager
+	l.packager = packagerContext(packager)
 	return l
 }
 
 func (l LocalFetcher) Get(buildpack LocalBuildpack) (string, error) {
+	return l.GetContext(context.Background(), buildpack)
+}
+
+// GetContext is like Get but abandons packaging when ctx is done, returning an
+// error that names the buildpack.
+func (l LocalFetcher) GetContext(ctx context.Context, buildpack LocalBuildpack) (string, error) {
+	path, err := l.get(ctx, buildpack)
+	if err != nil {
+		return "", contextError(ctx, buildpack.Name, err)
+	}
+
+	return path, nil
+}
+
+func (l LocalFetcher) get(ctx context.Context, buildpack LocalBuildpack) (string, error) {
 	buildpackCacheDir := filepath.Join(l.buildpackCache.Dir(), buildpack.Name)
 	if buildpack.Offline {
 		buildpackCacheDir = filepath.Join(buildpackCacheDir, "cached")
@@ -41,39 +57,55 @@ func (l LocalFetcher) Get(buildpack LocalBuildpack) (string, error) {
 		key = buildpack.CachedKey
 	}
 
-	name, err := l.namer.RandomName(buildpack.Name)
+	err := l.buildpackCache.Lock(key)
 	if err != nil {
-		return "", fmt.Errorf("random name generation failed: %w", err)
+		return "", err
 	}
-
-	path := filepath.Join(buildpackCacheDir, fmt.Sprintf("%s.cnb", name))
+	defer l.buildpackCache.Unlock(key)
 
 	cachedEntry, exist, err := l.buildpackCache.Get(key)
 	if err != nil {
 		return "", err
 	}
 
+	sourceDigest, err := buildpack.sourceDigest()
+	if err != nil {
+		return "", fmt.Errorf("failed to compute source digest: %w", err)
+	}
+
+	//Nothing that goes into the package has changed since it was last built
+	if exist && cachedEntry.SourceDigest == sourceDigest {
+		return cachedEntry.URI, nil
+	}
+
+	name, err := l.namer.RandomName(buildpack.Name)
+	if err != nil {
+		return "", fmt.Errorf("random name generation failed: %w", err)
+	}
+
+	path := filepath.Join(buildpackCacheDir, fmt.Sprintf("%s.cnb", name))
+
 	if !exist {
 		err := os.MkdirAll(buildpackCacheDir, os.ModePerm)
 		if err != nil {
 			return "", err
 		}
 	} else {
-		//Add locking logic or override logic
 		err := os.RemoveAll(cachedEntry.URI)
 		if err != nil {
 			return "", err
 		}
 	}
 
-	err = l.packager.Execute(buildpack.Path, path, buildpack.Version, buildpack.Offline)
+	err = l.packager.ExecuteContext(ctx, buildpack.Path, path, buildpack.Version, buildpack.Offline)
 	if err != nil {
 		return "", fmt.Errorf("failed to package buildpack: %w", err)
 	}
 
 	err = l.buildpackCache.Set(key, CacheEntry{
-		Version: "testing",
-		URI:     path,
+		Version:      buildpack.Version,
+		URI:          path,
+		SourceDigest: sourceDigest,
 	})
 
 	if err != nil {
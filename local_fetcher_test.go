@@ -1,6 +1,7 @@
 package freezer_test
 
 import (
+	gocontext "context"
 	"errors"
 	"fmt"
 	"os"
@@ -18,10 +19,11 @@ func testLocalFetcher(t *testing.T, context spec.G, it spec.S) {
 	var (
 		Expect = NewWithT(t).Expect
 
-		cacheDir string
+		cacheDir     string
+		buildpackDir string
 
 		buildpackCache *fakes.BuildpackCache
-		packager       *fakes.Packager
+		packager       *fakes.PackagerContext
 		namer          *fakes.Namer
 
 		localBuildpack freezer.LocalBuildpack
@@ -34,7 +36,18 @@ func testLocalFetcher(t *testing.T, context spec.G, it spec.S) {
 		cacheDir, err = os.MkdirTemp("", "cache")
 		Expect(err).NotTo(HaveOccurred())
 
-		packager = &fakes.Packager{}
+		buildpackDir, err = os.MkdirTemp("", "buildpack")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.WriteFile(filepath.Join(buildpackDir, "buildpack.toml"), []byte(`
+[metadata]
+  include-files = ["bin/build", "buildpack.toml"]
+`), 0644)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(buildpackDir, "bin"), os.ModePerm)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(buildpackDir, "bin", "build"), []byte("some-build"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(buildpackDir, "README.md"), []byte("some-readme"), 0644)).To(Succeed())
+
+		packager = &fakes.PackagerContext{}
 
 		buildpackCache = &fakes.BuildpackCache{}
 		buildpackCache.DirCall.Stub = func() string {
@@ -47,7 +60,7 @@ func testLocalFetcher(t *testing.T, context spec.G, it spec.S) {
 			return fmt.Sprintf("%s-random-string", name), nil
 		}
 
-		localBuildpack = freezer.NewLocalBuildpack("path/to/buildpack", "some-buildpack")
+		localBuildpack = freezer.NewLocalBuildpack(buildpackDir, "some-buildpack")
 		localBuildpack.Offline = false
 		localBuildpack.Version = "some-version"
 
@@ -57,6 +70,7 @@ func testLocalFetcher(t *testing.T, context spec.G, it spec.S) {
 
 	it.After(func() {
 		Expect(os.RemoveAll(cacheDir)).To(Succeed())
+		Expect(os.RemoveAll(buildpackDir)).To(Succeed())
 	})
 
 	context("Get", func() {
@@ -65,21 +79,134 @@ func testLocalFetcher(t *testing.T, context spec.G, it spec.S) {
 				uri, err := localFetcher.Get(localBuildpack)
 				Expect(err).ToNot(HaveOccurred())
 
+				Expect(buildpackCache.LockCall.Receives.Key).To(Equal("some-buildpack"))
 				Expect(buildpackCache.GetCall.CallCount).To(Equal(1))
 
 				Expect(namer.RandomNameCall.Receives.Name).To(Equal("some-buildpack"))
 
-				Expect(packager.ExecuteCall.Receives.BuildpackDir).To(Equal("path/to/buildpack"))
-				Expect(packager.ExecuteCall.Receives.Output).To(Equal(filepath.Join(cacheDir, "some-buildpack", "some-buildpack-random-string.cnb")))
-				Expect(packager.ExecuteCall.Receives.Version).To(Equal("some-version"))
-				Expect(packager.ExecuteCall.Receives.Cached).To(BeFalse())
+				Expect(packager.ExecuteContextCall.Receives.BuildpackDir).To(Equal(buildpackDir))
+				Expect(packager.ExecuteContextCall.Receives.Output).To(Equal(filepath.Join(cacheDir, "some-buildpack", "some-buildpack-random-string.cnb")))
+				Expect(packager.ExecuteContextCall.Receives.Version).To(Equal("some-version"))
+				Expect(packager.ExecuteContextCall.Receives.Cached).To(BeFalse())
 
 				Expect(buildpackCache.SetCall.CallCount).To(Equal(1))
+				Expect(buildpackCache.SetCall.Receives.CachedEntry.Version).To(Equal("some-version"))
+				Expect(buildpackCache.SetCall.Receives.CachedEntry.URI).To(Equal(filepath.Join(cacheDir, "some-buildpack", "some-buildpack-random-string.cnb")))
+				Expect(buildpackCache.SetCall.Receives.CachedEntry.SourceDigest).To(HavePrefix("sha256:"))
+
+				Expect(buildpackCache.UnlockCall.Receives.Key).To(Equal("some-buildpack"))
 
 				Expect(uri).To(Equal(filepath.Join(cacheDir, "some-buildpack", "some-buildpack-random-string.cnb")))
 			})
 		})
 
+		context("when the buildpack has already been packaged", func() {
+			var entries freezer.CacheDB
+
+			it.Before(func() {
+				entries = freezer.CacheDB{}
+				buildpackCache.SetCall.Stub = func(key string, entry freezer.CacheEntry) error {
+					entries[key] = entry
+					return nil
+				}
+				buildpackCache.GetCall.Stub = func(key string) (freezer.CacheEntry, bool, error) {
+					entry, ok := entries[key]
+					return entry, ok, nil
+				}
+
+				_, err := localFetcher.Get(localBuildpack)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(packager.ExecuteContextCall.CallCount).To(Equal(1))
+			})
+
+			context("and nothing has changed", func() {
+				it("returns the existing package without packaging it again", func() {
+					uri, err := localFetcher.Get(localBuildpack)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(packager.ExecuteContextCall.CallCount).To(Equal(1))
+					Expect(namer.RandomNameCall.CallCount).To(Equal(1))
+					Expect(uri).To(Equal(filepath.Join(cacheDir, "some-buildpack", "some-buildpack-random-string.cnb")))
+				})
+			})
+
+			context("and a file that is not included in the package has changed", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(filepath.Join(buildpackDir, "README.md"), []byte("other-readme"), 0644)).To(Succeed())
+				})
+
+				it("returns the existing package without packaging it again", func() {
+					_, err := localFetcher.Get(localBuildpack)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(packager.ExecuteContextCall.CallCount).To(Equal(1))
+				})
+			})
+
+			context("and an included file has changed", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(filepath.Join(buildpackDir, "bin", "build"), []byte("other-build"), 0755)).To(Succeed())
+				})
+
+				it("packages the buildpack again", func() {
+					_, err := localFetcher.Get(localBuildpack)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(packager.ExecuteContextCall.CallCount).To(Equal(2))
+				})
+			})
+
+			context("and the mode of an included file has changed", func() {
+				it.Before(func() {
+					Expect(os.Chmod(filepath.Join(buildpackDir, "bin", "build"), 0644)).To(Succeed())
+				})
+
+				it("packages the buildpack again", func() {
+					_, err := localFetcher.Get(localBuildpack)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(packager.ExecuteContextCall.CallCount).To(Equal(2))
+				})
+			})
+
+			context("and the requested version has changed", func() {
+				it.Before(func() {
+					localBuildpack.Version = "other-version"
+				})
+
+				it("packages the buildpack again", func() {
+					_, err := localFetcher.Get(localBuildpack)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(packager.ExecuteContextCall.CallCount).To(Equal(2))
+					Expect(packager.ExecuteContextCall.Receives.Version).To(Equal("other-version"))
+				})
+			})
+
+			context("and the buildpack has a pre-package script", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(filepath.Join(buildpackDir, "buildpack.toml"), []byte(`
+[metadata]
+  include_files = ["bin/build", "buildpack.toml"]
+  pre_package = "./scripts/build.sh"
+`), 0644)).To(Succeed())
+
+					_, err := localFetcher.Get(localBuildpack)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(packager.ExecuteContextCall.CallCount).To(Equal(2))
+
+					Expect(os.WriteFile(filepath.Join(buildpackDir, "README.md"), []byte("other-readme"), 0644)).To(Succeed())
+				})
+
+				it("packages the buildpack again when any file in the source tree changes", func() {
+					_, err := localFetcher.Get(localBuildpack)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(packager.ExecuteContextCall.CallCount).To(Equal(3))
+				})
+			})
+		})
+
 		context("failure cases", func() {
 			context("when the namer fails to generate a random name", func() {
 				it.Before(func() {
@@ -93,6 +220,32 @@ func testLocalFetcher(t *testing.T, context spec.G, it spec.S) {
 				})
 			})
 
+			context("when the cache key cannot be locked", func() {
+				it.Before(func() {
+					buildpackCache.LockCall.Returns.Error = errors.New("failed lock")
+				})
+
+				it("returns an error", func() {
+					_, err := localFetcher.Get(localBuildpack)
+					Expect(err).To(MatchError("failed lock"))
+
+					Expect(buildpackCache.GetCall.CallCount).To(Equal(0))
+				})
+			})
+
+			context("when the buildpack.toml cannot be parsed", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(filepath.Join(buildpackDir, "buildpack.toml"), []byte("%%%"), 0644)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := localFetcher.Get(localBuildpack)
+					Expect(err).To(MatchError(ContainSubstring("failed to compute source digest")))
+
+					Expect(packager.ExecuteContextCall.CallCount).To(Equal(0))
+				})
+			})
+
 			context("cache get fails", func() {
 				it.Before(func() {
 					buildpackCache.GetCall.Returns.Error = errors.New("failed get")
@@ -123,7 +276,7 @@ func testLocalFetcher(t *testing.T, context spec.G, it spec.S) {
 
 			context("the packager fails to package the buildpack", func() {
 				it.Before(func() {
-					packager.ExecuteCall.Returns.Error = errors.New("execution failed")
+					packager.ExecuteContextCall.Returns.Error = errors.New("execution failed")
 				})
 
 				it("returns an error", func() {
@@ -132,6 +285,17 @@ func testLocalFetcher(t *testing.T, context spec.G, it spec.S) {
 				})
 			})
 
+			context("the packager is not installed", func() {
+				it.Before(func() {
+					localFetcher = freezer.NewLocalFetcher(buildpackCache, freezer.NewPackingTools().WithExecutable(freezer.NewCommandExecutable("some-missing-jam")), namer)
+				})
+
+				it("returns an error", func() {
+					_, err := localFetcher.Get(localBuildpack)
+					Expect(err).To(MatchError(freezer.ErrPackagerMissing))
+				})
+			})
+
 			context("when setting the new buildpack information failes", func() {
 				it.Before(func() {
 					buildpackCache.SetCall.Returns.Error = errors.New("failed to set new cache entry")
@@ -146,4 +310,46 @@ func testLocalFetcher(t *testing.T, context spec.G, it spec.S) {
 			})
 		})
 	})
+
+	context("GetContext", func() {
+		var (
+			ctx    gocontext.Context
+			cancel gocontext.CancelFunc
+		)
+
+		it.Before(func() {
+			ctx, cancel = gocontext.WithCancel(gocontext.Background())
+			buildpackCache.GetCall.Returns.Bool = false
+		})
+
+		it.After(func() {
+			cancel()
+		})
+
+		it("passes the context to the packager", func() {
+			_, err := localFetcher.GetContext(ctx, localBuildpack)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(packager.ExecuteContextCall.Receives.Ctx).To(BeIdenticalTo(ctx))
+		})
+
+		context("failure cases", func() {
+			context("when the context is done while the buildpack is being packaged", func() {
+				it.Before(func() {
+					packager.ExecuteContextCall.Stub = func(ctx gocontext.Context, _, _, _ string, _ bool) error {
+						cancel()
+						return ctx.Err()
+					}
+				})
+
+				it("returns an error that names the buildpack", func() {
+					_, err := localFetcher.GetContext(ctx, localBuildpack)
+					Expect(err).To(MatchError("fetching buildpack some-buildpack: failed to package buildpack: context canceled"))
+					Expect(err).To(MatchError(gocontext.Canceled))
+
+					Expect(buildpackCache.SetCall.CallCount).To(Equal(0))
+				})
+			})
+		})
+	})
 }
@@ -0,0 +1,21 @@
+package freezer
+
+import (
+	"errors"
+	"os"
+	"strconv"
+)
+
+// OfflineEnv names the environment variable that, when set to a true value
+// such as 1, puts fetchers in offline mode so that remote buildpacks are only
+// ever resolved from the cache.
+const OfflineEnv = "FREEZER_OFFLINE"
+
+// ErrNotCached is returned in offline mode when a remote buildpack is not in
+// the cache. The returned error names the cache key that was looked up.
+var ErrNotCached = errors.New("buildpack is not cached and offline mode is enabled")
+
+func offlineModeFromEnv() bool {
+	offline, _ := strconv.ParseBool(os.Getenv(OfflineEnv))
+	return offline
+}
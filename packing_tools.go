@@ -1,6 +1,7 @@
 package freezer
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -14,27 +15,38 @@ type Executable interface {
 	Execute(pexec.Execution) error
 }
 
+// ExecutableContext is an optional interface that may be implemented by an
+// Executable whose executions are killed when their context is done, such as
+// CommandExecutable. When an Executable does not implement it, Execute is
+// used instead.
+//
+//go:generate faux --interface ExecutableContext --output fakes/executable_context.go
+type ExecutableContext interface {
+	Executable
+	ExecuteContext(context.Context, pexec.Execution) error
+}
+
 type PackingTools struct {
-	jam        Executable
-	pack       Executable
+	jam        ExecutableContext
+	pack       ExecutableContext
 	tempOutput func(dir string, pattern string) (string, error)
 }
 
 func NewPackingTools() PackingTools {
 	return PackingTools{
-		jam:        pexec.NewExecutable("jam"),
-		pack:       pexec.NewExecutable("pack"),
+		jam:        NewCommandExecutable("jam"),
+		pack:       NewCommandExecutable("pack"),
 		tempOutput: os.MkdirTemp,
 	}
 }
 
 func (p PackingTools) WithExecutable(executable Executable) PackingTools {
-	p.jam = executable
+	p.jam = executableContext(executable)
 	return p
 }
 
 func (p PackingTools) WithPack(pack Executable) PackingTools {
-	p.pack = pack
+	p.pack = executableContext(pack)
 	return p
 }
 
@@ -44,6 +56,12 @@ func (p PackingTools) WithTempOutput(tempOutput func(string, string) (string, er
 }
 
 func (p PackingTools) Execute(buildpackDir, output, version string, cached bool) error {
+	return p.ExecuteContext(context.Background(), buildpackDir, output, version, cached)
+}
+
+// ExecuteContext is like Execute but kills jam or pack if ctx is done before
+// packaging finishes.
+func (p PackingTools) ExecuteContext(ctx context.Context, buildpackDir, output, version string, cached bool) error {
 	jamOutput, err := p.tempOutput("", "")
 	if err != nil {
 		return err
@@ -61,7 +79,7 @@ func (p PackingTools) Execute(buildpackDir, output, version string, cached bool)
 		args = append(args, "--offline")
 	}
 
-	err = p.jam.Execute(pexec.Execution{
+	err = p.jam.ExecuteContext(ctx, pexec.Execution{
 		Args:   args,
 		Stdout: os.Stdout,
 		Stderr: os.Stderr,
@@ -78,7 +96,7 @@ func (p PackingTools) Execute(buildpackDir, output, version string, cached bool)
 		"--target", fmt.Sprintf("%s/%s", runtime.GOOS, runtime.GOARCH),
 	}
 
-	return p.pack.Execute(pexec.Execution{
+	return p.pack.ExecuteContext(ctx, pexec.Execution{
 		Args:   args,
 		Stdout: os.Stdout,
 		Stderr: os.Stderr,
@@ -1,6 +1,7 @@
 package freezer_test
 
 import (
+	gocontext "context"
 	"errors"
 	"fmt"
 	"path/filepath"
@@ -18,15 +19,15 @@ func testPackingTools(t *testing.T, context spec.G, it spec.S) {
 	var (
 		Expect = NewWithT(t).Expect
 
-		executable   *fakes.Executable
-		pack         *fakes.Executable
+		executable   *fakes.ExecutableContext
+		pack         *fakes.ExecutableContext
 		tempOutput   func(string, string) (string, error)
 		packingTools freezer.PackingTools
 	)
 
 	it.Before(func() {
-		executable = &fakes.Executable{}
-		pack = &fakes.Executable{}
+		executable = &fakes.ExecutableContext{}
+		pack = &fakes.ExecutableContext{}
 
 		tempOutput = func(string, string) (string, error) {
 			return "some-jam-output", nil
@@ -41,14 +42,14 @@ func testPackingTools(t *testing.T, context spec.G, it spec.S) {
 			err := packingTools.Execute("some-buildpack-dir", "some-output", "some-version", false)
 			Expect(err).NotTo(HaveOccurred())
 
-			Expect(executable.ExecuteCall.Receives.Execution.Args).To(Equal([]string{
+			Expect(executable.ExecuteContextCall.Receives.Execution.Args).To(Equal([]string{
 				"pack",
 				"--buildpack", filepath.Join("some-buildpack-dir", "buildpack.toml"),
 				"--output", filepath.Join("some-jam-output", "some-version.tgz"),
 				"--version", "some-version",
 			}))
 
-			Expect(pack.ExecuteCall.Receives.Execution.Args).To(Equal([]string{
+			Expect(pack.ExecuteContextCall.Receives.Execution.Args).To(Equal([]string{
 				"buildpack", "package",
 				"some-output",
 				"--path", filepath.Join("some-jam-output", "some-version.tgz"),
@@ -62,7 +63,7 @@ func testPackingTools(t *testing.T, context spec.G, it spec.S) {
 				err := packingTools.Execute("some-buildpack-dir", "some-output", "some-version", true)
 				Expect(err).NotTo(HaveOccurred())
 
-				Expect(executable.ExecuteCall.Receives.Execution.Args).To(Equal([]string{
+				Expect(executable.ExecuteContextCall.Receives.Execution.Args).To(Equal([]string{
 					"pack",
 					"--buildpack", filepath.Join("some-buildpack-dir", "buildpack.toml"),
 					"--output", filepath.Join("some-jam-output", "some-version.tgz"),
@@ -70,7 +71,7 @@ func testPackingTools(t *testing.T, context spec.G, it spec.S) {
 					"--offline",
 				}))
 
-				Expect(pack.ExecuteCall.Receives.Execution.Args).To(Equal([]string{
+				Expect(pack.ExecuteContextCall.Receives.Execution.Args).To(Equal([]string{
 					"buildpack", "package",
 					"some-output",
 					"--path", filepath.Join("some-jam-output", "some-version.tgz"),
@@ -97,7 +98,7 @@ func testPackingTools(t *testing.T, context spec.G, it spec.S) {
 
 			context("when the jam execution returns an error", func() {
 				it.Before(func() {
-					executable.ExecuteCall.Returns.Error = errors.New("some jam error")
+					executable.ExecuteContextCall.Returns.Error = errors.New("some jam error")
 				})
 				it("returns an error", func() {
 					err := packingTools.Execute("some-buildpack-dir", "some-output", "some-version", true)
@@ -107,7 +108,7 @@ func testPackingTools(t *testing.T, context spec.G, it spec.S) {
 
 			context("when the pack execution returns an error", func() {
 				it.Before(func() {
-					pack.ExecuteCall.Returns.Error = errors.New("some pack error")
+					pack.ExecuteContextCall.Returns.Error = errors.New("some pack error")
 				})
 				it("returns an error", func() {
 					err := packingTools.Execute("some-buildpack-dir", "some-output", "some-version", true)
@@ -116,4 +117,41 @@ func testPackingTools(t *testing.T, context spec.G, it spec.S) {
 			})
 		})
 	})
+
+	context("ExecuteContext", func() {
+		it("passes the context to jam and pack", func() {
+			ctx, cancel := gocontext.WithCancel(gocontext.Background())
+			defer cancel()
+
+			err := packingTools.ExecuteContext(ctx, "some-buildpack-dir", "some-output", "some-version", false)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(executable.ExecuteContextCall.Receives.Context).To(BeIdenticalTo(ctx))
+			Expect(pack.ExecuteContextCall.Receives.Context).To(BeIdenticalTo(ctx))
+		})
+
+		context("when the executables cannot be given a context", func() {
+			var (
+				plainExecutable *fakes.Executable
+				plainPack       *fakes.Executable
+			)
+
+			it.Before(func() {
+				plainExecutable = &fakes.Executable{}
+				plainPack = &fakes.Executable{}
+
+				packingTools = packingTools.WithExecutable(plainExecutable).WithPack(plainPack)
+			})
+
+			it("executes them without it", func() {
+				err := packingTools.ExecuteContext(gocontext.Background(), "some-buildpack-dir", "some-output", "some-version", false)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(plainExecutable.ExecuteCall.CallCount).To(Equal(1))
+				Expect(plainExecutable.ExecuteCall.Receives.Execution.Args).To(ContainElement("--buildpack"))
+				Expect(plainPack.ExecuteCall.CallCount).To(Equal(1))
+				Expect(plainPack.ExecuteCall.Receives.Execution.Args).To(ContainElement("--path"))
+			})
+		})
+	})
 }
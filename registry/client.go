@@ -0,0 +1,453 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/ForestEckhardt/freezer/internal/httpclient"
+)
+
+const (
+	MediaTypeOCIManifest        = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeOCIIndex           = "application/vnd.oci.image.index.v1+json"
+	MediaTypeDockerManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+	MediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+
+	// maxManifestSize bounds how much of a manifest is read, as registries
+	// themselves refuse manifests larger than this.
+	maxManifestSize = 4 << 20
+)
+
+var manifestMediaTypes = []string{
+	MediaTypeOCIManifest,
+	MediaTypeOCIIndex,
+	MediaTypeDockerManifest,
+	MediaTypeDockerManifestList,
+}
+
+var challengeParamPattern = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+type Platform struct {
+	OS           string `json:"os"`
+	Architecture string `json:"architecture"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+// Descriptor describes content stored in a registry, as it appears in
+// manifests and indexes.
+type Descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Platform    *Platform         `json:"platform,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Image is the manifest of an image for a single platform along with the
+// descriptors of the blobs it refers to.
+type Image struct {
+	// Digest is the digest the reference resolved to, which is the digest of
+	// the index rather than of Manifest when the image has several platforms.
+	Digest string
+
+	Manifest        Descriptor
+	ManifestContent []byte
+
+	Config Descriptor
+	Layers []Descriptor
+}
+
+// manifest is the union of the fields of image manifests and indexes that are
+// needed to resolve an image.
+type manifest struct {
+	MediaType string       `json:"mediaType"`
+	Config    Descriptor   `json:"config"`
+	Layers    []Descriptor `json:"layers"`
+	Manifests []Descriptor `json:"manifests"`
+}
+
+// Client pulls images using the OCI distribution API, authenticating with
+// bearer tokens where registries ask for them.
+type Client struct {
+	config Config
+	tokens *tokenCache
+}
+
+type tokenCache struct {
+	mutex  sync.Mutex
+	tokens map[string]string
+}
+
+func NewClient(config Config) Client {
+	return Client{
+		config: config,
+		tokens: &tokenCache{
+			tokens: map[string]string{},
+		},
+	}
+}
+
+// DigestContext returns the digest the reference currently resolves to
+// without downloading the image. For a multi-platform image that is the digest
+// of its index.
+func (c Client) DigestContext(ctx context.Context, ref Reference) (string, error) {
+	uri := c.url(ref, "manifests", ref.Identifier())
+
+	resp, err := c.do(ctx, ref, http.MethodHead, uri, manifestHeader())
+	if err != nil {
+		return "", err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", newHTTPStatusError(resp, uri)
+	}
+	httpclient.Discard(resp)
+
+	//Registries are not required to send the digest, in which case it has to
+	//be computed from the manifest itself
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		_, _, digest, err = c.getManifest(ctx, ref, ref.Identifier())
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return digest, nil
+}
+
+// ResolveContext returns the image the reference refers to. When it refers to
+// an index, the image for the given platform and architecture is returned; an
+// image with a single manifest is returned whatever its platform.
+func (c Client) ResolveContext(ctx context.Context, ref Reference, platform, arch string) (Image, error) {
+	content, mediaType, digest, err := c.getManifest(ctx, ref, ref.Identifier())
+	if err != nil {
+		return Image{}, err
+	}
+
+	image := Image{Digest: digest}
+
+	if mediaType == MediaTypeOCIIndex || mediaType == MediaTypeDockerManifestList {
+		var index manifest
+		err = json.Unmarshal(content, &index)
+		if err != nil {
+			return Image{}, fmt.Errorf("failed to parse index of %s: %w", ref, err)
+		}
+
+		descriptor, err := selectPlatform(ref, index.Manifests, platform, arch)
+		if err != nil {
+			return Image{}, err
+		}
+
+		content, mediaType, digest, err = c.getManifest(ctx, ref, descriptor.Digest)
+		if err != nil {
+			return Image{}, err
+		}
+	}
+
+	if mediaType != MediaTypeOCIManifest && mediaType != MediaTypeDockerManifest {
+		return Image{}, fmt.Errorf("unsupported manifest media type %q for %s", mediaType, ref)
+	}
+
+	var m manifest
+	err = json.Unmarshal(content, &m)
+	if err != nil {
+		return Image{}, fmt.Errorf("failed to parse manifest of %s: %w", ref, err)
+	}
+
+	image.Manifest = Descriptor{
+		MediaType: mediaType,
+		Digest:    digest,
+		Size:      int64(len(content)),
+	}
+	image.ManifestContent = content
+	image.Config = m.Config
+	image.Layers = m.Layers
+
+	return image, nil
+}
+
+// BlobContext returns the content of the blob described by blob. Reading it
+// fails with an error matching ErrDigestMismatch if the content does not match
+// the digest or size of the descriptor.
+func (c Client) BlobContext(ctx context.Context, ref Reference, blob Descriptor) (io.ReadCloser, error) {
+	if !digestPattern.MatchString(blob.Digest) {
+		return nil, fmt.Errorf("unsupported digest %q", blob.Digest)
+	}
+
+	uri := c.url(ref, "blobs", blob.Digest)
+
+	resp, err := c.do(ctx, ref, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newHTTPStatusError(resp, uri)
+	}
+
+	return &verifiedBody{
+		body:       resp.Body,
+		hash:       sha256.New(),
+		descriptor: blob,
+	}, nil
+}
+
+// getManifest returns the manifest with the given tag or digest along with its
+// media type and digest.
+func (c Client) getManifest(ctx context.Context, ref Reference, identifier string) ([]byte, string, string, error) {
+	uri := c.url(ref, "manifests", identifier)
+
+	resp, err := c.do(ctx, ref, http.MethodGet, uri, manifestHeader())
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", "", newHTTPStatusError(resp, uri)
+	}
+	defer httpclient.Discard(resp)
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestSize+1))
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	if len(content) > maxManifestSize {
+		return nil, "", "", fmt.Errorf("manifest of %s is larger than %d bytes", ref, maxManifestSize)
+	}
+
+	sum := sha256.Sum256(content)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	if digestPattern.MatchString(identifier) && digest != identifier {
+		return nil, "", "", fmt.Errorf("%w: manifest %s of %s has digest %s", ErrDigestMismatch, identifier, ref.Name(), digest)
+	}
+
+	//The media type in the manifest itself is authoritative when it is given
+	var m manifest
+	_ = json.Unmarshal(content, &m)
+
+	mediaType := m.MediaType
+	if mediaType == "" {
+		mediaType, _, _ = mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	}
+
+	return content, mediaType, digest, nil
+}
+
+// do sends a request for uri, answering an authentication challenge from the
+// registry once if it responds with one.
+func (c Client) do(ctx context.Context, ref Reference, method, uri string, header http.Header) (*http.Response, error) {
+	scope := fmt.Sprintf("repository:%s:pull", ref.Repository)
+	tokenKey := fmt.Sprintf("%s %s", ref.Registry, scope)
+
+	newRequest := func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, method, uri, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		for name, values := range header {
+			req.Header[name] = values
+		}
+
+		return req, nil
+	}
+
+	req, err := newRequest()
+	if err != nil {
+		return nil, err
+	}
+
+	if token, ok := c.tokens.get(tokenKey); ok {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	}
+
+	resp, err := c.config.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusUnauthorized {
+		return resp, nil
+	}
+
+	challenge := resp.Header.Get("WWW-Authenticate")
+	httpclient.Discard(resp)
+
+	req, err = newRequest()
+	if err != nil {
+		return nil, err
+	}
+
+	credentials, hasCredentials := c.config.Credentials[ref.Registry]
+
+	scheme := strings.ToLower(strings.SplitN(challenge, " ", 2)[0])
+	switch {
+	case scheme == "bearer":
+		params := map[string]string{}
+		for _, match := range challengeParamPattern.FindAllStringSubmatch(challenge, -1) {
+			params[strings.ToLower(match[1])] = match[2]
+		}
+
+		if params["scope"] == "" {
+			params["scope"] = scope
+		}
+
+		token, err := c.token(ctx, params, credentials, hasCredentials)
+		if err != nil {
+			return nil, fmt.Errorf("failed to authenticate with %s: %w", ref.Registry, err)
+		}
+		c.tokens.set(tokenKey, token)
+
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+	case scheme == "basic" && hasCredentials:
+		req.SetBasicAuth(credentials.Username, credentials.Password)
+
+	default:
+		return nil, fmt.Errorf("failed to authenticate with %s: unsupported challenge %q", ref.Registry, challenge)
+	}
+
+	return c.config.client().Do(req)
+}
+
+// token requests a bearer token from the authorization service described by
+// the parameters of a challenge.
+func (c Client) token(ctx context.Context, params map[string]string, credentials Credentials, hasCredentials bool) (string, error) {
+	realm, err := url.Parse(params["realm"])
+	if err != nil || realm.Scheme == "" {
+		return "", fmt.Errorf("invalid realm %q", params["realm"])
+	}
+
+	query := realm.Query()
+	if params["service"] != "" {
+		query.Set("service", params["service"])
+	}
+	query.Set("scope", params["scope"])
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+
+	if hasCredentials {
+		req.SetBasicAuth(credentials.Username, credentials.Password)
+	}
+
+	resp, err := c.config.client().Do(req)
+	if err != nil {
+		return "", err
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	_, err = httpclient.DecodeJSON(resp, realm.String(), &body, ErrImageNotFound, nil)
+	if err != nil {
+		return "", err
+	}
+
+	if body.Token != "" {
+		return body.Token, nil
+	}
+
+	if body.AccessToken != "" {
+		return body.AccessToken, nil
+	}
+
+	return "", errors.New("the response did not contain a token")
+}
+
+func (c Client) url(ref Reference, kind, identifier string) string {
+	return fmt.Sprintf("%s://%s/v2/%s/%s/%s", c.config.scheme(ref.Registry), ref.host(), ref.Repository, kind, identifier)
+}
+
+func (t *tokenCache) get(key string) (string, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	token, ok := t.tokens[key]
+	return token, ok
+}
+
+func (t *tokenCache) set(key, token string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.tokens[key] = token
+}
+
+func manifestHeader() http.Header {
+	return http.Header{"Accept": []string{strings.Join(manifestMediaTypes, ", ")}}
+}
+
+// selectPlatform returns the manifest of an index for the given platform and
+// architecture.
+func selectPlatform(ref Reference, manifests []Descriptor, platform, arch string) (Descriptor, error) {
+	var available []string
+	for _, descriptor := range manifests {
+		if descriptor.Platform == nil {
+			continue
+		}
+
+		if descriptor.Platform.OS == platform && descriptor.Platform.Architecture == arch {
+			return descriptor, nil
+		}
+
+		available = append(available, fmt.Sprintf("%s/%s", descriptor.Platform.OS, descriptor.Platform.Architecture))
+	}
+
+	list := "none"
+	if len(available) > 0 {
+		list = strings.Join(available, ", ")
+	}
+
+	return Descriptor{}, fmt.Errorf("%w: %s has no image for %s/%s (available: %s)", ErrImageNotFound, ref, platform, arch, list)
+}
+
+// verifiedBody checks that the content of a blob matches its descriptor once
+// it has been read in full.
+type verifiedBody struct {
+	body       io.ReadCloser
+	hash       hash.Hash
+	size       int64
+	descriptor Descriptor
+}
+
+func (b *verifiedBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	b.hash.Write(p[:n])
+	b.size += int64(n)
+
+	if b.size > b.descriptor.Size {
+		return n, fmt.Errorf("%w: blob %s is larger than %d bytes", ErrDigestMismatch, b.descriptor.Digest, b.descriptor.Size)
+	}
+
+	if errors.Is(err, io.EOF) {
+		digest := "sha256:" + hex.EncodeToString(b.hash.Sum(nil))
+		if b.size != b.descriptor.Size || digest != b.descriptor.Digest {
+			return n, fmt.Errorf("%w: blob %s has digest %s and size %d", ErrDigestMismatch, b.descriptor.Digest, digest, b.size)
+		}
+	}
+
+	return n, err
+}
+
+func (b *verifiedBody) Close() error {
+	return b.body.Close()
+}
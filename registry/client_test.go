@@ -0,0 +1,319 @@
+package registry_test
+
+import (
+	gocontext "context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ForestEckhardt/freezer/registry"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func digestOf(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func testClient(t *testing.T, context spec.G, it spec.S) {
+	var (
+		server *httptest.Server
+		client registry.Client
+
+		blobs     map[string]string
+		manifests map[string]string
+
+		indexDigest, amd64Digest, singleDigest string
+		tokenRequests                          []*http.Request
+		sendDigest                             bool
+	)
+
+	it.Before(func() {
+		blobs = map[string]string{}
+		manifests = map[string]string{}
+		tokenRequests = nil
+		sendDigest = true
+
+		config := `{"os": "linux"}`
+		layer := "some-layer"
+		blobs[digestOf(config)] = config
+		blobs[digestOf(layer)] = layer
+
+		amd64 := fmt.Sprintf(`{
+  "schemaVersion": 2,
+  "mediaType": %q,
+  "config": {"mediaType": "application/vnd.oci.image.config.v1+json", "digest": %q, "size": %d},
+  "layers": [{"mediaType": "application/vnd.oci.image.layer.v1.tar+gzip", "digest": %q, "size": %d}]
+}`, registry.MediaTypeOCIManifest, digestOf(config), len(config), digestOf(layer), len(layer))
+		amd64Digest = digestOf(amd64)
+		manifests[amd64Digest] = amd64
+
+		arm64 := strings.ReplaceAll(amd64, digestOf(layer), digestOf("some-arm64-layer"))
+		manifests[digestOf(arm64)] = arm64
+
+		index := fmt.Sprintf(`{
+  "schemaVersion": 2,
+  "mediaType": %q,
+  "manifests": [
+    {"mediaType": %q, "digest": %q, "size": %d, "platform": {"os": "linux", "architecture": "arm64"}},
+    {"mediaType": %q, "digest": %q, "size": %d, "platform": {"os": "linux", "architecture": "amd64"}}
+  ]
+}`, registry.MediaTypeOCIIndex, registry.MediaTypeOCIManifest, digestOf(arm64), len(arm64), registry.MediaTypeOCIManifest, amd64Digest, len(amd64))
+		indexDigest = digestOf(index)
+		manifests[indexDigest] = index
+		manifests["1.2.3"] = index
+
+		single := strings.Replace(amd64, registry.MediaTypeOCIManifest, registry.MediaTypeDockerManifest, 1)
+		singleDigest = digestOf(single)
+		manifests["single"] = single
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.URL.Path == "/token" {
+				tokenRequests = append(tokenRequests, req)
+				if req.URL.Query().Get("scope") != "repository:some-org/some-image:pull" || req.URL.Query().Get("service") != "some-registry" {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+
+				fmt.Fprint(w, `{"token": "some-token"}`)
+				return
+			}
+
+			if req.Header.Get("Authorization") != "Bearer some-token" {
+				repository := strings.SplitN(strings.TrimPrefix(req.URL.Path, "/v2/"), "/manifests/", 2)[0]
+				repository = strings.SplitN(repository, "/blobs/", 2)[0]
+
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="some-registry",scope="repository:%s:pull"`, server.URL, repository))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			switch {
+			case strings.HasPrefix(req.URL.Path, "/v2/some-org/some-image/manifests/"):
+				manifest, ok := manifests[strings.TrimPrefix(req.URL.Path, "/v2/some-org/some-image/manifests/")]
+				if !ok {
+					w.WriteHeader(http.StatusNotFound)
+					fmt.Fprint(w, `{"errors": [{"code": "MANIFEST_UNKNOWN"}]}`)
+					return
+				}
+
+				if sendDigest {
+					w.Header().Set("Docker-Content-Digest", digestOf(manifest))
+				}
+				if req.Method == http.MethodGet {
+					fmt.Fprint(w, manifest)
+				}
+
+			case strings.HasPrefix(req.URL.Path, "/v2/some-org/some-image/blobs/"):
+				blob, ok := blobs[strings.TrimPrefix(req.URL.Path, "/v2/some-org/some-image/blobs/")]
+				if !ok {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+
+				fmt.Fprint(w, blob)
+
+			default:
+				t.Fatalf("unknown path: %s", req.URL.Path)
+			}
+		}))
+
+		client = registry.NewClient(registry.NewConfig())
+	})
+
+	it.After(func() {
+		server.Close()
+	})
+
+	reference := func(identifier string) registry.Reference {
+		ref, err := registry.ParseReference(fmt.Sprintf("%s/some-org/some-image%s", strings.TrimPrefix(server.URL, "http://"), identifier))
+		Expect(err).NotTo(HaveOccurred())
+
+		return ref
+	}
+
+	context("DigestContext", func() {
+		it("returns the digest the tag resolves to", func() {
+			digest, err := client.DigestContext(gocontext.Background(), reference(":1.2.3"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(digest).To(Equal(indexDigest))
+		})
+
+		it("authenticates once for every request to the repository", func() {
+			_, err := client.DigestContext(gocontext.Background(), reference(":1.2.3"))
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = client.DigestContext(gocontext.Background(), reference(":single"))
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(tokenRequests).To(HaveLen(1))
+		})
+
+		context("when the registry does not send the digest", func() {
+			it.Before(func() {
+				sendDigest = false
+			})
+
+			it("computes it from the manifest", func() {
+				digest, err := client.DigestContext(gocontext.Background(), reference(":single"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(digest).To(Equal(singleDigest))
+			})
+		})
+
+		context("when credentials are given", func() {
+			it.Before(func() {
+				client = registry.NewClient(registry.NewConfig().WithCredentials(strings.TrimPrefix(server.URL, "http://"), "some-user", "some-password"))
+			})
+
+			it("uses them to request the token", func() {
+				_, err := client.DigestContext(gocontext.Background(), reference(":1.2.3"))
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(tokenRequests).To(HaveLen(1))
+				username, password, ok := tokenRequests[0].BasicAuth()
+				Expect(ok).To(BeTrue())
+				Expect(username).To(Equal("some-user"))
+				Expect(password).To(Equal("some-password"))
+			})
+		})
+	})
+
+	context("ResolveContext", func() {
+		it("returns the image for the platform", func() {
+			image, err := client.ResolveContext(gocontext.Background(), reference(":1.2.3"), "linux", "amd64")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(image.Digest).To(Equal(indexDigest))
+			Expect(image.Manifest).To(Equal(registry.Descriptor{
+				MediaType: registry.MediaTypeOCIManifest,
+				Digest:    amd64Digest,
+				Size:      int64(len(manifests[amd64Digest])),
+			}))
+			Expect(string(image.ManifestContent)).To(Equal(manifests[amd64Digest]))
+			Expect(image.Config.Digest).To(Equal(digestOf(`{"os": "linux"}`)))
+			Expect(image.Layers).To(HaveLen(1))
+			Expect(image.Layers[0].Digest).To(Equal(digestOf("some-layer")))
+		})
+
+		it("returns an image with a single manifest whatever the platform", func() {
+			image, err := client.ResolveContext(gocontext.Background(), reference(":single"), "linux", "s390x")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(image.Digest).To(Equal(singleDigest))
+			Expect(image.Manifest.MediaType).To(Equal(registry.MediaTypeDockerManifest))
+			Expect(image.Manifest.Digest).To(Equal(singleDigest))
+		})
+
+		it("resolves references by digest", func() {
+			image, err := client.ResolveContext(gocontext.Background(), reference("@"+indexDigest), "linux", "amd64")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(image.Manifest.Digest).To(Equal(amd64Digest))
+		})
+
+		context("failure cases", func() {
+			context("when the index has no image for the platform", func() {
+				it("returns an error listing the platforms it does have", func() {
+					_, err := client.ResolveContext(gocontext.Background(), reference(":1.2.3"), "linux", "s390x")
+					Expect(err).To(MatchError(registry.ErrImageNotFound))
+					Expect(err).To(MatchError(ContainSubstring("has no image for linux/s390x (available: linux/arm64, linux/amd64)")))
+				})
+			})
+
+			context("when the tag does not exist", func() {
+				it("returns an error", func() {
+					_, err := client.ResolveContext(gocontext.Background(), reference(":missing"), "linux", "amd64")
+					Expect(err).To(MatchError(registry.ErrImageNotFound))
+					Expect(err).To(MatchError(`unexpected response status: 404 Not Found: {"errors": [{"code": "MANIFEST_UNKNOWN"}]}`))
+				})
+			})
+
+			context("when the manifest does not match its digest", func() {
+				it.Before(func() {
+					manifests[indexDigest] = manifests["single"]
+				})
+
+				it("returns an error", func() {
+					_, err := client.ResolveContext(gocontext.Background(), reference("@"+indexDigest), "linux", "amd64")
+					Expect(err).To(MatchError(registry.ErrDigestMismatch))
+				})
+			})
+
+			context("when the token cannot be obtained", func() {
+				it("returns an error", func() {
+					ref := reference(":1.2.3")
+					ref.Repository = "some-org/other-image"
+
+					_, err := client.ResolveContext(gocontext.Background(), ref, "linux", "amd64")
+					Expect(err).To(MatchError(ContainSubstring("failed to authenticate with 127.0.0.1")))
+					Expect(err).To(MatchError(ContainSubstring("403 Forbidden")))
+				})
+			})
+		})
+	})
+
+	context("BlobContext", func() {
+		it("returns the content of the blob", func() {
+			blob, err := client.BlobContext(gocontext.Background(), reference(":1.2.3"), registry.Descriptor{
+				Digest: digestOf("some-layer"),
+				Size:   int64(len("some-layer")),
+			})
+			Expect(err).NotTo(HaveOccurred())
+			defer blob.Close()
+
+			content, err := io.ReadAll(blob)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(Equal("some-layer"))
+		})
+
+		context("failure cases", func() {
+			context("when the content does not match the digest", func() {
+				it.Before(func() {
+					blobs[digestOf("some-layer")] = "some-other"
+				})
+
+				it("fails once the blob has been read", func() {
+					blob, err := client.BlobContext(gocontext.Background(), reference(":1.2.3"), registry.Descriptor{
+						Digest: digestOf("some-layer"),
+						Size:   int64(len("some-layer")),
+					})
+					Expect(err).NotTo(HaveOccurred())
+					defer blob.Close()
+
+					_, err = io.ReadAll(blob)
+					Expect(err).To(MatchError(registry.ErrDigestMismatch))
+				})
+			})
+
+			context("when the content is larger than described", func() {
+				it("fails", func() {
+					blob, err := client.BlobContext(gocontext.Background(), reference(":1.2.3"), registry.Descriptor{
+						Digest: digestOf("some-layer"),
+						Size:   4,
+					})
+					Expect(err).NotTo(HaveOccurred())
+					defer blob.Close()
+
+					_, err = io.ReadAll(blob)
+					Expect(err).To(MatchError(ContainSubstring("is larger than 4 bytes")))
+				})
+			})
+
+			context("when the blob does not exist", func() {
+				it("returns an error", func() {
+					_, err := client.BlobContext(gocontext.Background(), reference(":1.2.3"), registry.Descriptor{
+						Digest: digestOf("missing"),
+					})
+					Expect(err).To(MatchError(registry.ErrImageNotFound))
+				})
+			})
+		})
+	})
+}
@@ -0,0 +1,89 @@
+package registry
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/ForestEckhardt/freezer/internal/httpclient"
+)
+
+type Config struct {
+	// Client is used to make every request. When it is nil httpclient.Default
+	// is used.
+	Client *http.Client
+
+	// PlainHTTP lists the registries, as host or host:port, that are contacted
+	// over HTTP rather than HTTPS. Registries on the loopback interface, such
+	// as localhost:5000, always are.
+	PlainHTTP []string
+
+	// Credentials are used to authenticate with the registries they are keyed
+	// by. Registries without credentials are accessed anonymously.
+	Credentials map[string]Credentials
+}
+
+type Credentials struct {
+	Username string
+	Password string
+}
+
+func NewConfig() Config {
+	return Config{
+		Credentials: map[string]Credentials{},
+	}
+}
+
+func (c Config) WithClient(client *http.Client) Config {
+	c.Client = client
+	return c
+}
+
+func (c Config) WithPlainHTTP(registries ...string) Config {
+	c.PlainHTTP = append(append([]string(nil), c.PlainHTTP...), registries...)
+	return c
+}
+
+// WithCredentials authenticates requests to registry, given as host or
+// host:port, with username and password, which for most registries may also be
+// an access token.
+func (c Config) WithCredentials(registry, username, password string) Config {
+	credentials := map[string]Credentials{}
+	for r, creds := range c.Credentials {
+		credentials[r] = creds
+	}
+	credentials[registry] = Credentials{Username: username, Password: password}
+
+	c.Credentials = credentials
+	return c
+}
+
+func (c Config) client() *http.Client {
+	if c.Client != nil {
+		return c.Client
+	}
+
+	return httpclient.Default
+}
+
+func (c Config) scheme(registry string) string {
+	for _, host := range c.PlainHTTP {
+		if host == registry {
+			return "http"
+		}
+	}
+
+	host := registry
+	if h, _, err := net.SplitHostPort(registry); err == nil {
+		host = h
+	}
+
+	if host == "localhost" {
+		return "http"
+	}
+
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return "http"
+	}
+
+	return "https"
+}
@@ -0,0 +1,26 @@
+package registry
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ForestEckhardt/freezer/internal/httpclient"
+)
+
+// ErrImageNotFound is matched, with errors.Is, by the errors returned when an
+// image, or an image for the requested platform, does not exist.
+var ErrImageNotFound = errors.New("image not found")
+
+// ErrDigestMismatch is matched, with errors.Is, by the errors returned when
+// content read from a registry does not match the digest it was requested by.
+var ErrDigestMismatch = errors.New("digest mismatch")
+
+// HTTPStatusError is returned when a registry responds with an unexpected
+// status. For 404 responses it also matches ErrImageNotFound.
+type HTTPStatusError = httpclient.StatusError
+
+// newHTTPStatusError keeps the start of the body of resp, which was returned
+// for a request for uri, and discards the rest.
+func newHTTPStatusError(resp *http.Response, uri string) *HTTPStatusError {
+	return httpclient.NewStatusError(resp, uri, ErrImageNotFound)
+}
@@ -0,0 +1,22 @@
+package registry_test
+
+import (
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestRegistry(t *testing.T) {
+	suite := spec.New("registry", spec.Report(report.Terminal{}))
+	suite("Client", testClient)
+	suite("Reference", testReference)
+
+	suite.Before(func(t *testing.T) {
+		RegisterTestingT(t)
+	})
+
+	suite.Run(t)
+}
@@ -0,0 +1,128 @@
+package registry
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// DockerHub is the registry of references that do not name one.
+	DockerHub = "docker.io"
+
+	dockerHubAPI = "registry-1.docker.io"
+	defaultTag   = "latest"
+)
+
+var (
+	repositoryPattern = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*$`)
+	tagPattern        = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+	digestPattern     = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+)
+
+// Reference identifies an image in a registry, either by tag or by digest.
+type Reference struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// ParseReference parses references such as gcr.io/paketo-buildpacks/go:1.2.3,
+// localhost:5000/some-buildpack@sha256:<hex> or docker://paketobuildpacks/go.
+// References without a registry are on Docker Hub, where repositories without
+// an organisation belong to library, and references without a tag or digest
+// refer to the latest tag.
+func ParseReference(reference string) (Reference, error) {
+	name := strings.TrimPrefix(reference, "docker://")
+
+	var ref Reference
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, ref.Digest = name[:i], name[i+1:]
+		if !digestPattern.MatchString(ref.Digest) {
+			return Reference{}, fmt.Errorf("invalid image reference %q: invalid digest %q", reference, ref.Digest)
+		}
+	}
+
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, ref.Tag = name[:i], name[i+1:]
+		if !tagPattern.MatchString(ref.Tag) {
+			return Reference{}, fmt.Errorf("invalid image reference %q: invalid tag %q", reference, ref.Tag)
+		}
+	}
+
+	ref.Registry = DockerHub
+	ref.Repository = name
+	if parts := strings.SplitN(name, "/", 2); len(parts) == 2 && isRegistry(parts[0]) {
+		ref.Registry, ref.Repository = parts[0], parts[1]
+	}
+
+	if ref.Registry == DockerHub && !strings.Contains(ref.Repository, "/") {
+		ref.Repository = "library/" + ref.Repository
+	}
+
+	if !repositoryPattern.MatchString(ref.Repository) {
+		return Reference{}, fmt.Errorf("invalid image reference %q: invalid repository %q", reference, ref.Repository)
+	}
+
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = defaultTag
+	}
+
+	return ref, nil
+}
+
+// IsReference reports whether reference names an image in a registry rather
+// than a path: it either starts with docker:// or its first element is a
+// registry such as gcr.io or localhost:5000.
+func IsReference(reference string) bool {
+	if strings.HasPrefix(reference, "docker://") {
+		return true
+	}
+
+	parts := strings.SplitN(reference, "/", 2)
+
+	return len(parts) == 2 && isRegistry(parts[0])
+}
+
+// isRegistry reports whether the first element of a reference is a registry
+// rather than part of the repository, using the same rule as Docker.
+func isRegistry(element string) bool {
+	if strings.HasPrefix(element, ".") {
+		return false
+	}
+
+	return strings.ContainsAny(element, ".:") || element == "localhost"
+}
+
+// Name returns the registry and repository of the reference.
+func (r Reference) Name() string {
+	return fmt.Sprintf("%s/%s", r.Registry, r.Repository)
+}
+
+// Identifier returns the digest of the reference or, if it does not have one,
+// its tag.
+func (r Reference) Identifier() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+
+	return r.Tag
+}
+
+func (r Reference) String() string {
+	if r.Digest != "" {
+		return fmt.Sprintf("%s@%s", r.Name(), r.Digest)
+	}
+
+	return fmt.Sprintf("%s:%s", r.Name(), r.Tag)
+}
+
+// host returns the host the API of the registry is served from.
+func (r Reference) host() string {
+	if r.Registry == DockerHub {
+		return dockerHubAPI
+	}
+
+	return r.Registry
+}
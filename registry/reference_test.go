@@ -0,0 +1,79 @@
+package registry_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ForestEckhardt/freezer/registry"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testReference(t *testing.T, context spec.G, it spec.S) {
+	digest := "sha256:" + strings.Repeat("a", 64)
+
+	context("ParseReference", func() {
+		it("parses a reference with a registry and tag", func() {
+			ref, err := registry.ParseReference("gcr.io/paketo-buildpacks/go:1.2.3")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ref).To(Equal(registry.Reference{
+				Registry:   "gcr.io",
+				Repository: "paketo-buildpacks/go",
+				Tag:        "1.2.3",
+			}))
+			Expect(ref.String()).To(Equal("gcr.io/paketo-buildpacks/go:1.2.3"))
+		})
+
+		it("parses a reference with a port and digest", func() {
+			ref, err := registry.ParseReference("localhost:5000/some-buildpack@" + digest)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ref).To(Equal(registry.Reference{
+				Registry:   "localhost:5000",
+				Repository: "some-buildpack",
+				Digest:     digest,
+			}))
+			Expect(ref.Identifier()).To(Equal(digest))
+			Expect(ref.String()).To(Equal("localhost:5000/some-buildpack@" + digest))
+		})
+
+		it("defaults to Docker Hub and the latest tag", func() {
+			ref, err := registry.ParseReference("docker://paketobuildpacks/go")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ref.String()).To(Equal("docker.io/paketobuildpacks/go:latest"))
+
+			ref, err = registry.ParseReference("docker://busybox:1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ref.String()).To(Equal("docker.io/library/busybox:1"))
+		})
+
+		context("failure cases", func() {
+			it("rejects invalid repositories, tags and digests", func() {
+				_, err := registry.ParseReference("gcr.io/Some-Org/go")
+				Expect(err).To(MatchError(`invalid image reference "gcr.io/Some-Org/go": invalid repository "Some-Org/go"`))
+
+				_, err = registry.ParseReference("gcr.io/some-org/go:-tag")
+				Expect(err).To(MatchError(`invalid image reference "gcr.io/some-org/go:-tag": invalid tag "-tag"`))
+
+				_, err = registry.ParseReference("gcr.io/some-org/go@sha256:abc")
+				Expect(err).To(MatchError(`invalid image reference "gcr.io/some-org/go@sha256:abc": invalid digest "sha256:abc"`))
+			})
+		})
+	})
+
+	context("IsReference", func() {
+		it("recognises references to images", func() {
+			Expect(registry.IsReference("gcr.io/paketo-buildpacks/go")).To(BeTrue())
+			Expect(registry.IsReference("localhost:5000/some-buildpack")).To(BeTrue())
+			Expect(registry.IsReference("localhost/some-buildpack")).To(BeTrue())
+			Expect(registry.IsReference("docker://paketobuildpacks/go")).To(BeTrue())
+		})
+
+		it("does not mistake paths for references", func() {
+			Expect(registry.IsReference("path/to/buildpack")).To(BeFalse())
+			Expect(registry.IsReference("../some-buildpack")).To(BeFalse())
+			Expect(registry.IsReference("/some.dir/buildpack")).To(BeFalse())
+			Expect(registry.IsReference("paketobuildpacks/go")).To(BeFalse())
+		})
+	})
+}
@@ -0,0 +1,29 @@
+package freezer
+
+import (
+	"fmt"
+
+	"github.com/ForestEckhardt/freezer/registry"
+)
+
+// RegistryBuildpack is a buildpack published as an image in a registry.
+type RegistryBuildpack struct {
+	Reference registry.Reference
+	Platform  string
+	Arch      string
+}
+
+func NewRegistryBuildpack(reference registry.Reference, platform, arch string) RegistryBuildpack {
+	return RegistryBuildpack{
+		Reference: reference,
+		Platform:  platform,
+		Arch:      arch,
+	}
+}
+
+// cacheKey returns the key the buildpack is stored under in the cache. Every
+// tag or digest of an image is stored separately, grouped by the image so that
+// an eviction policy can bound the number kept.
+func (r RegistryBuildpack) cacheKey() string {
+	return fmt.Sprintf("%s:%s:%s@%s", r.Reference.Name(), r.Platform, r.Arch, r.Reference.Identifier())
+}
@@ -0,0 +1,264 @@
+package freezer
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ForestEckhardt/freezer/registry"
+)
+
+const registryCacheDir = "registry"
+
+//go:generate faux --interface ImageFetcher --output fakes/image_fetcher.go
+type ImageFetcher interface {
+	DigestContext(ctx context.Context, ref registry.Reference) (string, error)
+	ResolveContext(ctx context.Context, ref registry.Reference, platform, arch string) (registry.Image, error)
+	BlobContext(ctx context.Context, ref registry.Reference, blob registry.Descriptor) (io.ReadCloser, error)
+}
+
+// RegistryFetcher fetches buildpacks that are published as images and stores
+// them in the cache as .cnb files, which are archives of an OCI image layout
+// that pack can use in place of the image.
+type RegistryFetcher struct {
+	buildpackCache BuildpackCache
+	imageFetcher   ImageFetcher
+
+	freshness
+}
+
+func NewRegistryFetcher(buildpackCache BuildpackCache, imageFetcher ImageFetcher) RegistryFetcher {
+	return RegistryFetcher{
+		buildpackCache: buildpackCache,
+		imageFetcher:   imageFetcher,
+		freshness:      freshness{offlineMode: offlineModeFromEnv()},
+	}
+}
+
+// WithOfflineMode determines whether buildpacks are only resolved from the
+// cache, without contacting the registry. It defaults to the value of
+// FREEZER_OFFLINE.
+func (r RegistryFetcher) WithOfflineMode(offlineMode bool) RegistryFetcher {
+	r.offlineMode = offlineMode
+	return r
+}
+
+// WithTTL sets the freshness window within which a cached tag is returned
+// without asking the registry whether it has moved. A TTL of zero, the
+// default, asks every time.
+func (r RegistryFetcher) WithTTL(ttl time.Duration) RegistryFetcher {
+	r.ttl = ttl
+	return r
+}
+
+// WithForceRefresh determines whether the registry is asked whether a tag has
+// moved even when the cached image is still within the freshness window.
+func (r RegistryFetcher) WithForceRefresh(forceRefresh bool) RegistryFetcher {
+	r.forceRefresh = forceRefresh
+	return r
+}
+
+func (r RegistryFetcher) Get(buildpack RegistryBuildpack) (string, error) {
+	return r.GetContext(context.Background(), buildpack)
+}
+
+// GetContext is like Get but abandons the fetch when ctx is done, returning an
+// error that names the buildpack.
+func (r RegistryFetcher) GetContext(ctx context.Context, buildpack RegistryBuildpack) (string, error) {
+	path, err := r.get(ctx, buildpack)
+	if err != nil {
+		return "", contextError(ctx, buildpack.Reference.String(), err)
+	}
+
+	return path, nil
+}
+
+func (r RegistryFetcher) get(ctx context.Context, buildpack RegistryBuildpack) (string, error) {
+	ref := buildpack.Reference
+	key := buildpack.cacheKey()
+
+	err := r.buildpackCache.Lock(key)
+	if err != nil {
+		return "", err
+	}
+	defer r.buildpackCache.Unlock(key)
+
+	cachedEntry, exist, err := r.buildpackCache.Get(key)
+	if err != nil {
+		return "", err
+	}
+
+	uri, ok, err := r.offline(key, cachedEntry, exist)
+	if err != nil || ok {
+		return uri, err
+	}
+
+	//The content of a digest never changes so there is no need to ask the
+	//registry about it once it has been cached
+	if exist && ref.Digest != "" {
+		return cachedEntry.URI, nil
+	}
+
+	if exist && r.fresh(cachedEntry) {
+		return cachedEntry.URI, nil
+	}
+
+	//Asking for the digest of a tag is cheap and, unlike pulling the image,
+	//does not count against the pull limits of registries such as Docker Hub
+	if exist && cachedEntry.ImageDigest != "" {
+		digest, err := r.imageFetcher.DigestContext(ctx, ref)
+		if err != nil {
+			return "", err
+		}
+
+		if digest == cachedEntry.ImageDigest {
+			err = r.checked(r.buildpackCache, key, cachedEntry)
+			if err != nil {
+				return "", err
+			}
+
+			return cachedEntry.URI, nil
+		}
+	}
+
+	image, err := r.imageFetcher.ResolveContext(ctx, ref, buildpack.Platform, buildpack.Arch)
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(r.buildpackCache.Dir(), registryCacheDir, strings.ReplaceAll(ref.Registry, ":", "_"), filepath.FromSlash(ref.Repository), buildpack.Platform, buildpack.Arch)
+	err = os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%s.cnb", strings.TrimPrefix(image.Digest, "sha256:")))
+
+	//The layout is streamed into place so that the image is never held in
+	//memory or written to disk twice
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(r.writeLayout(ctx, writer, ref, image))
+	}()
+
+	err = download(path, contextReader{ctx, reader})
+	reader.Close()
+	if err != nil {
+		return "", err
+	}
+
+	var checkedAt time.Time
+	if ref.Digest == "" {
+		checkedAt = time.Now()
+	}
+
+	err = r.buildpackCache.Set(key, CacheEntry{
+		Version:     ref.Identifier(),
+		URI:         path,
+		ImageDigest: image.Digest,
+		CheckedAt:   checkedAt,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// writeLayout writes image to w as a tar archive of an OCI image layout:
+//
+//	oci-layout
+//	index.json
+//	blobs/sha256/<hex>  the manifest, its config and its layers
+func (r RegistryFetcher) writeLayout(ctx context.Context, w io.Writer, ref registry.Reference, image registry.Image) error {
+	manifest := image.Manifest
+	if ref.Tag != "" {
+		manifest.Annotations = map[string]string{"org.opencontainers.image.ref.name": ref.Tag}
+	}
+
+	index, err := json.Marshal(struct {
+		SchemaVersion int                   `json:"schemaVersion"`
+		MediaType     string                `json:"mediaType"`
+		Manifests     []registry.Descriptor `json:"manifests"`
+	}{
+		SchemaVersion: 2,
+		MediaType:     registry.MediaTypeOCIIndex,
+		Manifests:     []registry.Descriptor{manifest},
+	})
+	if err != nil {
+		return err
+	}
+
+	archive := tar.NewWriter(w)
+
+	for _, dir := range []string{"blobs/", "blobs/sha256/"} {
+		err = archive.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: dir, Mode: 0755})
+		if err != nil {
+			return err
+		}
+	}
+
+	files := []struct {
+		name    string
+		content []byte
+	}{
+		{"oci-layout", []byte(`{"imageLayoutVersion":"1.0.0"}`)},
+		{"index.json", index},
+		{blobPath(image.Manifest.Digest), image.ManifestContent},
+	}
+
+	for _, file := range files {
+		err = archive.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: file.name, Mode: 0644, Size: int64(len(file.content))})
+		if err != nil {
+			return err
+		}
+
+		_, err = archive.Write(file.content)
+		if err != nil {
+			return err
+		}
+	}
+
+	//Layers may be repeated within a manifest but are only stored once
+	written := map[string]bool{}
+	for _, blob := range append([]registry.Descriptor{image.Config}, image.Layers...) {
+		if written[blob.Digest] {
+			continue
+		}
+		written[blob.Digest] = true
+
+		err = r.writeBlob(ctx, archive, ref, blob)
+		if err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+func (r RegistryFetcher) writeBlob(ctx context.Context, archive *tar.Writer, ref registry.Reference, blob registry.Descriptor) error {
+	content, err := r.imageFetcher.BlobContext(ctx, ref, blob)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	err = archive.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: blobPath(blob.Digest), Mode: 0644, Size: blob.Size})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(archive, contextReader{ctx, content})
+
+	return err
+}
+
+func blobPath(digest string) string {
+	return "blobs/" + strings.Replace(digest, ":", "/", 1)
+}
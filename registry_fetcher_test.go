@@ -0,0 +1,285 @@
+package freezer_test
+
+import (
+	"archive/tar"
+	gocontext "context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ForestEckhardt/freezer"
+	"github.com/ForestEckhardt/freezer/fakes"
+	"github.com/ForestEckhardt/freezer/registry"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testRegistryFetcher(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		cacheDir string
+
+		imageFetcher      *fakes.ImageFetcher
+		buildpackCache    *fakes.BuildpackCache
+		registryBuildpack freezer.RegistryBuildpack
+		registryFetcher   freezer.RegistryFetcher
+
+		indexDigest    = "sha256:" + strings.Repeat("1", 64)
+		manifestDigest = "sha256:" + strings.Repeat("2", 64)
+		configDigest   = "sha256:" + strings.Repeat("3", 64)
+		layerDigest    = "sha256:" + strings.Repeat("4", 64)
+	)
+
+	readLayout := func(path string) map[string]string {
+		file, err := os.Open(path)
+		Expect(err).NotTo(HaveOccurred())
+		defer file.Close()
+
+		files := map[string]string{}
+		archive := tar.NewReader(file)
+		for {
+			header, err := archive.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			Expect(err).NotTo(HaveOccurred())
+
+			content, err := io.ReadAll(archive)
+			Expect(err).NotTo(HaveOccurred())
+			files[header.Name] = string(content)
+		}
+
+		return files
+	}
+
+	it.Before(func() {
+		var err error
+		cacheDir, err = os.MkdirTemp("", "cache")
+		Expect(err).NotTo(HaveOccurred())
+
+		imageFetcher = &fakes.ImageFetcher{}
+		imageFetcher.ResolveContextCall.Returns.Image = registry.Image{
+			Digest: indexDigest,
+			Manifest: registry.Descriptor{
+				MediaType: registry.MediaTypeOCIManifest,
+				Digest:    manifestDigest,
+				Size:      int64(len("some-manifest")),
+			},
+			ManifestContent: []byte("some-manifest"),
+			Config:          registry.Descriptor{Digest: configDigest, Size: int64(len("some-config"))},
+			Layers: []registry.Descriptor{
+				{Digest: layerDigest, Size: int64(len("some-layer"))},
+				{Digest: layerDigest, Size: int64(len("some-layer"))},
+			},
+		}
+		imageFetcher.BlobContextCall.Stub = func(_ gocontext.Context, _ registry.Reference, blob registry.Descriptor) (io.ReadCloser, error) {
+			content := map[string]string{
+				configDigest: "some-config",
+				layerDigest:  "some-layer",
+			}[blob.Digest]
+
+			return io.NopCloser(strings.NewReader(content)), nil
+		}
+
+		buildpackCache = &fakes.BuildpackCache{}
+		buildpackCache.DirCall.Stub = func() string {
+			return cacheDir
+		}
+
+		ref, err := registry.ParseReference("gcr.io/some-org/some-image:1.2.3")
+		Expect(err).NotTo(HaveOccurred())
+
+		registryBuildpack = freezer.NewRegistryBuildpack(ref, "linux", "amd64")
+		registryFetcher = freezer.NewRegistryFetcher(buildpackCache, imageFetcher).WithOfflineMode(false)
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(cacheDir)).To(Succeed())
+	})
+
+	context("Get", func() {
+		context("when the image is not cached", func() {
+			it("pulls the image into an OCI layout archive", func() {
+				uri, err := registryFetcher.Get(registryBuildpack)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(uri).To(Equal(filepath.Join(cacheDir, "registry", "gcr.io", "some-org", "some-image", "linux", "amd64", strings.Repeat("1", 64)+".cnb")))
+
+				Expect(imageFetcher.ResolveContextCall.Receives.Ref).To(Equal(registryBuildpack.Reference))
+				Expect(imageFetcher.ResolveContextCall.Receives.Platform).To(Equal("linux"))
+				Expect(imageFetcher.ResolveContextCall.Receives.Arch).To(Equal("amd64"))
+				Expect(imageFetcher.BlobContextCall.CallCount).To(Equal(2))
+
+				files := readLayout(uri)
+				Expect(files).To(HaveLen(7))
+				Expect(files).To(HaveKeyWithValue("oci-layout", `{"imageLayoutVersion":"1.0.0"}`))
+				Expect(files).To(HaveKeyWithValue("blobs/sha256/"+strings.Repeat("2", 64), "some-manifest"))
+				Expect(files).To(HaveKeyWithValue("blobs/sha256/"+strings.Repeat("3", 64), "some-config"))
+				Expect(files).To(HaveKeyWithValue("blobs/sha256/"+strings.Repeat("4", 64), "some-layer"))
+				Expect(files["index.json"]).To(MatchJSON(`{
+					"schemaVersion": 2,
+					"mediaType": "application/vnd.oci.image.index.v1+json",
+					"manifests": [{
+						"mediaType": "application/vnd.oci.image.manifest.v1+json",
+						"digest": "` + manifestDigest + `",
+						"size": 13,
+						"annotations": {"org.opencontainers.image.ref.name": "1.2.3"}
+					}]
+				}`))
+
+				Expect(buildpackCache.SetCall.Receives.Key).To(Equal("gcr.io/some-org/some-image:linux:amd64@1.2.3"))
+				Expect(buildpackCache.SetCall.Receives.CachedEntry.CheckedAt).To(BeTemporally("~", time.Now(), time.Minute))
+				buildpackCache.SetCall.Receives.CachedEntry.CheckedAt = time.Time{}
+				Expect(buildpackCache.SetCall.Receives.CachedEntry).To(Equal(freezer.CacheEntry{
+					Version:     "1.2.3",
+					URI:         uri,
+					ImageDigest: indexDigest,
+				}))
+			})
+		})
+
+		context("when the image is cached", func() {
+			it.Before(func() {
+				buildpackCache.GetCall.Returns.Bool = true
+				buildpackCache.GetCall.Returns.CacheEntry = freezer.CacheEntry{
+					Version:     "1.2.3",
+					URI:         "some-uri",
+					ImageDigest: indexDigest,
+				}
+				imageFetcher.DigestContextCall.Returns.String = indexDigest
+			})
+
+			it("only asks the registry whether the tag has moved", func() {
+				uri, err := registryFetcher.Get(registryBuildpack)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(uri).To(Equal("some-uri"))
+
+				Expect(imageFetcher.DigestContextCall.CallCount).To(Equal(1))
+				Expect(imageFetcher.ResolveContextCall.CallCount).To(Equal(0))
+				Expect(buildpackCache.SetCall.CallCount).To(Equal(0))
+			})
+
+			context("when the tag has moved", func() {
+				it.Before(func() {
+					imageFetcher.DigestContextCall.Returns.String = "sha256:" + strings.Repeat("5", 64)
+				})
+
+				it("pulls the image again", func() {
+					_, err := registryFetcher.Get(registryBuildpack)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(imageFetcher.ResolveContextCall.CallCount).To(Equal(1))
+					Expect(buildpackCache.SetCall.CallCount).To(Equal(1))
+				})
+			})
+
+			context("when a TTL is set", func() {
+				it.Before(func() {
+					buildpackCache.GetCall.Returns.CacheEntry.CheckedAt = time.Now().Add(-time.Hour)
+				})
+
+				it("does not contact the registry within the window", func() {
+					uri, err := registryFetcher.WithTTL(2 * time.Hour).Get(registryBuildpack)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(uri).To(Equal("some-uri"))
+
+					Expect(imageFetcher.DigestContextCall.CallCount).To(Equal(0))
+				})
+
+				it("records when the tag was last checked once the window has passed", func() {
+					_, err := registryFetcher.WithTTL(30 * time.Minute).Get(registryBuildpack)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(imageFetcher.DigestContextCall.CallCount).To(Equal(1))
+					Expect(buildpackCache.SetCall.Receives.CachedEntry.CheckedAt).To(BeTemporally("~", time.Now(), time.Minute))
+				})
+
+				it("contacts the registry when a refresh is forced", func() {
+					_, err := registryFetcher.WithTTL(2 * time.Hour).WithForceRefresh(true).Get(registryBuildpack)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(imageFetcher.DigestContextCall.CallCount).To(Equal(1))
+				})
+			})
+
+			context("when the reference is a digest", func() {
+				it.Before(func() {
+					ref, err := registry.ParseReference("gcr.io/some-org/some-image@" + indexDigest)
+					Expect(err).NotTo(HaveOccurred())
+
+					registryBuildpack = freezer.NewRegistryBuildpack(ref, "linux", "amd64")
+				})
+
+				it("does not contact the registry", func() {
+					uri, err := registryFetcher.Get(registryBuildpack)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(uri).To(Equal("some-uri"))
+
+					Expect(buildpackCache.GetCall.Receives.Key).To(Equal("gcr.io/some-org/some-image:linux:amd64@" + indexDigest))
+					Expect(imageFetcher.DigestContextCall.CallCount).To(Equal(0))
+				})
+			})
+		})
+
+		context("when offline mode is enabled", func() {
+			it.Before(func() {
+				registryFetcher = registryFetcher.WithOfflineMode(true)
+			})
+
+			it("returns an error naming the key when the image is not cached", func() {
+				_, err := registryFetcher.Get(registryBuildpack)
+				Expect(err).To(MatchError(freezer.ErrNotCached))
+				Expect(err).To(MatchError(ContainSubstring(`no cache entry for key "gcr.io/some-org/some-image:linux:amd64@1.2.3"`)))
+
+				Expect(imageFetcher.ResolveContextCall.CallCount).To(Equal(0))
+			})
+		})
+
+		context("failure cases", func() {
+			context("when the image cannot be resolved", func() {
+				it.Before(func() {
+					imageFetcher.ResolveContextCall.Returns.Error = errors.New("failed to resolve")
+				})
+
+				it("returns an error", func() {
+					_, err := registryFetcher.Get(registryBuildpack)
+					Expect(err).To(MatchError("failed to resolve"))
+				})
+			})
+
+			context("when a blob cannot be read", func() {
+				it.Before(func() {
+					imageFetcher.BlobContextCall.Stub = nil
+					imageFetcher.BlobContextCall.Returns.Error = errors.New("failed to read blob")
+				})
+
+				it("returns an error and leaves nothing behind", func() {
+					_, err := registryFetcher.Get(registryBuildpack)
+					Expect(err).To(MatchError("failed to read blob"))
+
+					entries, err := os.ReadDir(filepath.Join(cacheDir, "registry", "gcr.io", "some-org", "some-image", "linux", "amd64"))
+					Expect(err).NotTo(HaveOccurred())
+					Expect(entries).To(BeEmpty())
+					Expect(buildpackCache.SetCall.CallCount).To(Equal(0))
+				})
+			})
+
+			context("when the fetch is cancelled", func() {
+				it("returns an error naming the image", func() {
+					ctx, cancel := gocontext.WithCancel(gocontext.Background())
+					cancel()
+
+					_, err := registryFetcher.GetContext(ctx, registryBuildpack)
+					Expect(err).To(MatchError(gocontext.Canceled))
+					Expect(err).To(MatchError(ContainSubstring("fetching buildpack gcr.io/some-org/some-image:1.2.3")))
+				})
+			})
+		})
+	})
+}
@@ -2,9 +2,15 @@ package freezer
 
 import (
 	"fmt"
+	"path/filepath"
+	"strings"
 )
 
 type RemoteBuildpack struct {
+	// Host is the forge the buildpack is released on, such as gitlab.com. It
+	// is empty for GitHub.
+	Host string
+
 	Org         string
 	Repo        string
 	Platform    string
@@ -13,6 +19,15 @@ type RemoteBuildpack struct {
 	CachedKey   string
 	Offline     bool
 	Version     string
+
+	// VersionConstraint is a semver constraint (e.g. "2.x" or "~1.4") used to
+	// select the highest matching release. It is ignored when Version is set.
+	VersionConstraint string
+
+	// AllowPrerelease lets the constraint select prereleases, including those
+	// tagged with a prerelease version such as 2.1.0-rc.1 for "2.x".
+	AllowPrerelease bool
+	AllowDraft      bool
 }
 
 func NewRemoteBuildpack(org, repo, platform, arch string) RemoteBuildpack {
@@ -25,3 +40,66 @@ func NewRemoteBuildpack(org, repo, platform, arch string) RemoteBuildpack {
 		CachedKey:   fmt.Sprintf("%s:%s:%s:%s:cached", org, repo, platform, arch),
 	}
 }
+
+// cacheKey returns the key the buildpack is stored under in the cache. Pinned
+// versions are stored separately from the latest release so that fetching one
+// does not invalidate the other.
+func (r RemoteBuildpack) cacheKey() string {
+	key := r.UncachedKey
+	if r.Offline {
+		key = r.CachedKey
+	}
+
+	//Buildpacks released on GitHub keep the keys they had before other forges
+	//were supported
+	if !r.onGitHub() {
+		key = fmt.Sprintf("%s/%s", r.Host, key)
+	}
+
+	switch {
+	case r.Version != "":
+		key = fmt.Sprintf("%s@%s", key, strings.TrimPrefix(r.Version, "v"))
+	case r.VersionConstraint != "":
+		key = fmt.Sprintf("%s@%s", key, r.VersionConstraint)
+	}
+
+	return key
+}
+
+// cacheDir returns the directory within cacheDir the buildpack is stored in.
+func (r RemoteBuildpack) cacheDir(cacheDir string) string {
+	dir := filepath.Join(cacheDir, filepath.FromSlash(r.Org), r.Repo, r.Platform, r.Arch)
+	if !r.onGitHub() {
+		dir = filepath.Join(cacheDir, r.Host, filepath.FromSlash(r.Org), r.Repo, r.Platform, r.Arch)
+	}
+
+	if r.Offline {
+		dir = filepath.Join(dir, "cached")
+	}
+
+	return dir
+}
+
+func (r RemoteBuildpack) onGitHub() bool {
+	return r.Host == "" || r.Host == githubHost
+}
+
+// reference returns the reference the buildpack would be fetched by, for use
+// in error messages.
+func (r RemoteBuildpack) reference() string {
+	host := r.Host
+	if host == "" {
+		host = githubHost
+	}
+
+	reference := fmt.Sprintf("%s/%s/%s", host, r.Org, r.Repo)
+
+	switch {
+	case r.Version != "":
+		reference = fmt.Sprintf("%s@%s", reference, r.Version)
+	case r.VersionConstraint != "":
+		reference = fmt.Sprintf("%s@%s", reference, r.VersionConstraint)
+	}
+
+	return reference
+}
@@ -1,21 +1,44 @@
 package freezer
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/ForestEckhardt/freezer/github"
+	"github.com/Masterminds/semver/v3"
 	"github.com/paketo-buildpacks/packit/v2/vacation"
 )
 
 //go:generate faux --interface GitReleaseFetcher --output fakes/git_release_fetcher.go
 type GitReleaseFetcher interface {
 	Get(org, repo string) (github.Release, error)
+	GetReleaseByTag(org, repo, tag string) (github.Release, error)
 	GetReleaseAsset(asset github.ReleaseAsset) (io.ReadCloser, error)
 	GetReleaseTarball(url string) (io.ReadCloser, error)
+	List(org, repo string) ([]github.Release, error)
+}
+
+// GitReleaseFetcherContext is an optional interface that may be implemented by
+// a GitReleaseFetcher. Its requests are aborted when their context is done and
+// it can ask whether the latest release has changed since it was cached. When
+// a GitReleaseFetcher does not implement it, its other methods are used and
+// the latest release is always fetched in full.
+//
+//go:generate faux --interface GitReleaseFetcherContext --output fakes/git_release_fetcher_context.go
+type GitReleaseFetcherContext interface {
+	GitReleaseFetcher
+	GetContext(ctx context.Context, org, repo string) (github.Release, error)
+	GetReleaseByTagContext(ctx context.Context, org, repo, tag string) (github.Release, error)
+	GetReleaseAssetContext(ctx context.Context, asset github.ReleaseAsset) (io.ReadCloser, error)
+	GetReleaseTarballContext(ctx context.Context, url string) (io.ReadCloser, error)
+	GetIfModifiedContext(ctx context.Context, org, repo, etag, lastModified string) (github.Release, error)
+	ListContext(ctx context.Context, org, repo string) ([]github.Release, error)
 }
 
 //go:generate faux --interface Packager --output fakes/packager.go
@@ -23,31 +46,54 @@ type Packager interface {
 	Execute(buildpackDir, output, version string, cached bool) error
 }
 
+// PackagerContext is an optional interface that may be implemented by a
+// Packager that stops packaging when its context is done. When a Packager
+// does not implement it, Execute is used instead.
+//
+//go:generate faux --interface PackagerContext --output fakes/packager_context.go
+type PackagerContext interface {
+	Packager
+	ExecuteContext(ctx context.Context, buildpackDir, output, version string, cached bool) error
+}
+
 //go:generate faux --interface BuildpackCache --output fakes/buildpack_cache.go
 type BuildpackCache interface {
 	Get(key string) (CacheEntry, bool, error)
 	Set(key string, cachedEntry CacheEntry) error
+	Lock(key string) error
+	Unlock(key string) error
 	Dir() string
 }
 
 type RemoteFetcher struct {
 	buildpackCache    BuildpackCache
-	gitReleaseFetcher GitReleaseFetcher
-	packager          Packager
+	gitReleaseFetcher GitReleaseFetcherContext
+	packager          PackagerContext
+	assetMatcher      AssetMatcher
 	fileSystem        func(dir string, pattern string) (string, error)
+
+	freshness
 }
 
 func NewRemoteFetcher(buildpackCache BuildpackCache, gitReleaseFetcher GitReleaseFetcher, packager Packager) RemoteFetcher {
 	return RemoteFetcher{
 		buildpackCache:    buildpackCache,
-		gitReleaseFetcher: gitReleaseFetcher,
-		packager:          packager,
+		gitReleaseFetcher: gitReleaseFetcherContext(gitReleaseFetcher),
+		packager:          packagerContext(packager),
+		assetMatcher:      NewPatternAssetMatcher(),
 		fileSystem:        os.MkdirTemp,
+		freshness:         freshness{offlineMode: offlineModeFromEnv()},
 	}
 }
 
 func (r RemoteFetcher) WithPackager(packager Packager) RemoteFetcher {
-	r.packager = packager
+	r.packager = packagerContext(packager)
+	return r
+}
+
+// WithAssetMatcher sets how the release asset to download is chosen.
+func (r RemoteFetcher) WithAssetMatcher(assetMatcher AssetMatcher) RemoteFetcher {
+	r.assetMatcher = assetMatcher
 	return r
 }
 
@@ -56,24 +102,103 @@ func (r RemoteFetcher) WithFileSystem(fileSystem func(string, string) (string, e
 	return r
 }
 
+// WithOfflineMode determines whether buildpacks are only resolved from the
+// cache, without contacting GitHub. It defaults to the value of
+// FREEZER_OFFLINE.
+func (r RemoteFetcher) WithOfflineMode(offlineMode bool) RemoteFetcher {
+	r.offlineMode = offlineMode
+	return r
+}
+
+// WithTTL sets the freshness window within which a cached latest release, or
+// release matching a version constraint, is returned without asking GitHub
+// whether there is a newer one. A TTL of zero, the default, asks every time.
+func (r RemoteFetcher) WithTTL(ttl time.Duration) RemoteFetcher {
+	r.ttl = ttl
+	return r
+}
+
+// WithForceRefresh determines whether GitHub is asked for the latest release
+// even when the cached one is still within the freshness window.
+func (r RemoteFetcher) WithForceRefresh(forceRefresh bool) RemoteFetcher {
+	r.forceRefresh = forceRefresh
+	return r
+}
+
 func (r RemoteFetcher) Get(buildpack RemoteBuildpack) (string, error) {
-	release, err := r.gitReleaseFetcher.Get(buildpack.Org, buildpack.Repo)
+	return r.GetContext(context.Background(), buildpack)
+}
+
+// GetContext is like Get but abandons the fetch when ctx is done, returning an
+// error that names the buildpack.
+func (r RemoteFetcher) GetContext(ctx context.Context, buildpack RemoteBuildpack) (string, error) {
+	path, err := r.get(ctx, buildpack)
+	if err != nil {
+		return "", contextError(ctx, buildpack.reference(), err)
+	}
+
+	return path, nil
+}
+
+func (r RemoteFetcher) get(ctx context.Context, buildpack RemoteBuildpack) (string, error) {
+	buildpackCacheDir := buildpack.cacheDir(r.buildpackCache.Dir())
+
+	key := buildpack.cacheKey()
+
+	err := r.buildpackCache.Lock(key)
 	if err != nil {
 		return "", err
 	}
+	defer r.buildpackCache.Unlock(key)
 
-	buildpackCacheDir := filepath.Join(r.buildpackCache.Dir(), buildpack.Org, buildpack.Repo, buildpack.Platform, buildpack.Arch)
-	if buildpack.Offline {
-		buildpackCacheDir = filepath.Join(buildpackCacheDir, "cached")
+	cachedEntry, exist, err := r.buildpackCache.Get(key)
+	if err != nil {
+		return "", err
 	}
 
-	key := buildpack.UncachedKey
-	if buildpack.Offline {
-		key = buildpack.CachedKey
+	uri, ok, err := r.offline(key, cachedEntry, exist)
+	if err != nil || ok {
+		return uri, err
+	}
+
+	//A pinned release never changes so there is no need to ask GitHub about it
+	//once it has been cached
+	if exist && buildpack.Version != "" && cachedEntry.Version == strings.TrimPrefix(buildpack.Version, "v") {
+		return cachedEntry.URI, nil
+	}
+
+	if exist && buildpack.Version == "" && r.fresh(cachedEntry) {
+		return cachedEntry.URI, nil
+	}
+
+	//The validators of an entry whose file is missing or corrupt must not be
+	//sent, as the release would then be reported as unchanged
+	var validators CacheEntry
+	if exist {
+		validators = cachedEntry
+	}
+
+	release, err := r.getRelease(ctx, buildpack, validators)
+	if errors.Is(err, github.ErrNotModified) {
+		err = r.checked(r.buildpackCache, key, cachedEntry)
+		if err != nil {
+			return "", err
+		}
+
+		return cachedEntry.URI, nil
 	}
 
-	cachedEntry, exist, err := r.buildpackCache.Get(key)
 	if err != nil {
+		//When GitHub is rate limiting requests the cached version is better than
+		//failing. The cache key includes any version or constraint so the cached
+		//version always satisfies the buildpack.
+		var limitErr *github.RateLimitError
+		if exist && errors.As(err, &limitErr) {
+			if _, statErr := os.Stat(cachedEntry.URI); statErr == nil {
+				return cachedEntry.URI, nil
+			}
+		}
+
 		return "", err
 	}
 
@@ -91,38 +216,24 @@ func (r RemoteFetcher) Get(buildpack RemoteBuildpack) (string, error) {
 		missingReleaseArtifacts := !(len(release.Assets) > 0)
 		var bundle io.ReadCloser
 		if missingReleaseArtifacts || buildpack.Offline {
-			bundle, err = r.gitReleaseFetcher.GetReleaseTarball(release.TarballURL)
+			bundle, err = r.gitReleaseFetcher.GetReleaseTarballContext(ctx, release.TarballURL)
 			if err != nil {
 				return "", err
 			}
-		} else if buildpack.Platform == "linux" && buildpack.Arch == "amd64" && len(release.Assets) == 2 {
-			//This if is for backward compatibility
-			bundle, err = r.gitReleaseFetcher.GetReleaseAsset(release.Assets[0])
+		} else {
+			asset, err := r.assetMatcher.Match(buildpack, release)
 			if err != nil {
 				return "", err
 			}
-		} else {
-			var assetName string
-			if buildpack.Platform == "linux" && buildpack.Arch == "amd64" {
-				assetName = "" + buildpack.Repo + "-" + tagName + ".cnb"
-			} else {
-				assetName = "" + buildpack.Repo + "-" + tagName + "-" + buildpack.Platform + "-" + buildpack.Arch + ".cnb"
-			}
-
-			var downloadAssetIndex int
-			for i, asset := range release.Assets {
-				if asset.Name == assetName {
-					downloadAssetIndex = i
-					break
-				}
-			}
 
-			bundle, err = r.gitReleaseFetcher.GetReleaseAsset(release.Assets[downloadAssetIndex])
+			bundle, err = r.gitReleaseFetcher.GetReleaseAssetContext(ctx, asset)
 			if err != nil {
 				return "", err
 			}
 		}
 
+		defer bundle.Close()
+
 		path = filepath.Join(buildpackCacheDir, fmt.Sprintf("%s.cnb", tagName))
 
 		if missingReleaseArtifacts || buildpack.Offline {
@@ -132,39 +243,199 @@ func (r RemoteFetcher) Get(buildpack RemoteBuildpack) (string, error) {
 			}
 			defer os.RemoveAll(downloadDir)
 
-			err = vacation.NewArchive(bundle).StripComponents(1).Decompress(downloadDir)
+			err = vacation.NewArchive(contextReader{ctx, bundle}).StripComponents(1).Decompress(downloadDir)
 			if err != nil {
 				return "", err
 			}
 
-			err = r.packager.Execute(downloadDir, path, tagName, buildpack.Offline)
+			err = r.packager.ExecuteContext(ctx, downloadDir, path, tagName, buildpack.Offline)
 			if err != nil {
 				return "", err
 			}
 
 		} else {
-			file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+			err = download(path, contextReader{ctx, bundle})
 			if err != nil {
 				return "", err
 			}
-			defer file.Close()
+		}
 
-			_, err = io.Copy(file, bundle)
-			if err != nil {
-				return "", err
-			}
+		//Only the latest release, or the release matching a constraint, needs to
+		//be checked for again
+		var checkedAt time.Time
+		if buildpack.Version == "" {
+			checkedAt = time.Now()
 		}
 
 		err = r.buildpackCache.Set(key, CacheEntry{
-			Version: tagName,
-			URI:     path,
+			Version:      tagName,
+			URI:          path,
+			ETag:         release.ETag,
+			LastModified: release.LastModified,
+			CheckedAt:    checkedAt,
 		})
 
 		if err != nil {
 			return "", err
 		}
 
+	} else if release.ETag != cachedEntry.ETag || release.LastModified != cachedEntry.LastModified {
+		//The release metadata can change without the release being replaced, in
+		//which case only the validators need updating
+		cachedEntry.ETag = release.ETag
+		cachedEntry.LastModified = release.LastModified
+		cachedEntry.CheckedAt = time.Now()
+
+		err = r.buildpackCache.Set(key, cachedEntry)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		err = r.checked(r.buildpackCache, key, cachedEntry)
+		if err != nil {
+			return "", err
+		}
 	}
 
 	return path, nil
 }
+
+// getRelease returns the release the buildpack refers to. When the latest
+// release is wanted and the cached entry has validators the request is
+// conditional, returning github.ErrNotModified if the cached entry is current.
+func (r RemoteFetcher) getRelease(ctx context.Context, buildpack RemoteBuildpack, cachedEntry CacheEntry) (github.Release, error) {
+	if buildpack.Version == "" {
+		if buildpack.VersionConstraint != "" {
+			return r.resolveConstraint(ctx, buildpack)
+		}
+
+		if cachedEntry.ETag != "" || cachedEntry.LastModified != "" {
+			return r.gitReleaseFetcher.GetIfModifiedContext(ctx, buildpack.Org, buildpack.Repo, cachedEntry.ETag, cachedEntry.LastModified)
+		}
+
+		return r.gitReleaseFetcher.GetContext(ctx, buildpack.Org, buildpack.Repo)
+	}
+
+	//Releases may be tagged with or without a leading v so try the version as
+	//given first and then fall back to the other form
+	tag := buildpack.Version
+	alternateTag := "v" + tag
+	if strings.HasPrefix(tag, "v") {
+		alternateTag = strings.TrimPrefix(tag, "v")
+	}
+
+	release, err := r.gitReleaseFetcher.GetReleaseByTagContext(ctx, buildpack.Org, buildpack.Repo, tag)
+	if err != nil {
+		//Asking again would only be refused again
+		var limitErr *github.RateLimitError
+		if errors.As(err, &limitErr) {
+			return github.Release{}, err
+		}
+
+		var alternateErr error
+		release, alternateErr = r.gitReleaseFetcher.GetReleaseByTagContext(ctx, buildpack.Org, buildpack.Repo, alternateTag)
+		if alternateErr != nil {
+			return github.Release{}, err
+		}
+	}
+
+	return release, nil
+}
+
+// resolveConstraint returns the release with the highest version that
+// satisfies the buildpack's version constraint. Releases whose tags are not
+// valid semantic versions are ignored.
+func (r RemoteFetcher) resolveConstraint(ctx context.Context, buildpack RemoteBuildpack) (github.Release, error) {
+	constraint, err := semver.NewConstraint(buildpack.VersionConstraint)
+	if err != nil {
+		return github.Release{}, fmt.Errorf("invalid version constraint %q: %w", buildpack.VersionConstraint, err)
+	}
+
+	releases, err := r.gitReleaseFetcher.ListContext(ctx, buildpack.Org, buildpack.Repo)
+	if err != nil {
+		return github.Release{}, err
+	}
+
+	var (
+		match        github.Release
+		matchVersion *semver.Version
+	)
+	for _, release := range releases {
+		if (release.Draft && !buildpack.AllowDraft) || (release.Prerelease && !buildpack.AllowPrerelease) {
+			continue
+		}
+
+		version, err := semver.NewVersion(release.TagName)
+		if err != nil {
+			continue
+		}
+
+		if !constraint.Check(version) && !(buildpack.AllowPrerelease && checkPrerelease(constraint, version)) {
+			continue
+		}
+
+		if matchVersion == nil || version.GreaterThan(matchVersion) {
+			match = release
+			matchVersion = version
+		}
+	}
+
+	if matchVersion == nil {
+		return github.Release{}, fmt.Errorf("%w: no release of %s/%s satisfies version constraint %q", github.ErrReleaseNotFound, buildpack.Org, buildpack.Repo, buildpack.VersionConstraint)
+	}
+
+	return match, nil
+}
+
+// checkPrerelease reports whether the release version of a prerelease, such
+// as 2.1.0 for 2.1.0-rc.1, satisfies constraint. Constraints never match
+// prereleases themselves unless they name one.
+func checkPrerelease(constraint *semver.Constraints, version *semver.Version) bool {
+	if version.Prerelease() == "" {
+		return false
+	}
+
+	release, err := version.SetPrerelease("")
+	if err != nil {
+		return false
+	}
+
+	return constraint.Check(&release)
+}
+
+// download streams content into a temporary file next to path and renames it
+// into place once it is complete, so that a failed or interrupted download
+// never leaves a truncated file at path.
+func download(path string, content io.Reader) error {
+	file, err := os.CreateTemp(filepath.Dir(path), fmt.Sprintf(".%s.*", filepath.Base(path)))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	_, err = io.Copy(file, content)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	return moveIntoPlace(file, path)
+}
+
+// moveIntoPlace closes file, a complete temporary file in the directory of
+// path, and renames it to path. Temporary files are only readable by their
+// owner so it is first made readable by everyone, as any other cached file is.
+func moveIntoPlace(file *os.File, path string) error {
+	err := file.Chmod(0644)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
@@ -4,11 +4,15 @@ import (
 	"archive/tar"
 	"bytes"
 	"compress/gzip"
+	gocontext "context"
 	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"testing"
+	"testing/iotest"
+	"time"
 
 	"github.com/ForestEckhardt/freezer"
 	"github.com/ForestEckhardt/freezer/fakes"
@@ -27,10 +31,11 @@ func testRemoteFetcher(t *testing.T, context spec.G, it spec.S) {
 		downloadDir string
 		tmpDir      string
 
-		gitReleaseFetcher *fakes.GitReleaseFetcher
+		gitReleaseFetcher *fakes.GitReleaseFetcherContext
+		assetMatcher      *fakes.AssetMatcher
 		buildpackCache    *fakes.BuildpackCache
 		remoteBuildpack   freezer.RemoteBuildpack
-		packager          *fakes.Packager
+		packager          *fakes.PackagerContext
 		fileSystem        func(string, string) (string, error)
 		remoteFetcher     freezer.RemoteFetcher
 	)
@@ -41,8 +46,8 @@ func testRemoteFetcher(t *testing.T, context spec.G, it spec.S) {
 		cacheDir, err = os.MkdirTemp("", "cache")
 		Expect(err).NotTo(HaveOccurred())
 
-		gitReleaseFetcher = &fakes.GitReleaseFetcher{}
-		gitReleaseFetcher.GetCall.Returns.Release = github.Release{
+		gitReleaseFetcher = &fakes.GitReleaseFetcherContext{}
+		gitReleaseFetcher.GetContextCall.Returns.Release = github.Release{
 			TagName: "some-tag",
 			Assets: []github.ReleaseAsset{
 				{
@@ -63,10 +68,10 @@ func testRemoteFetcher(t *testing.T, context spec.G, it spec.S) {
 		Expect(tw.Close()).To(Succeed())
 		Expect(gw.Close()).To(Succeed())
 
-		gitReleaseFetcher.GetReleaseAssetCall.Returns.ReadCloser = io.NopCloser(buffer)
-		gitReleaseFetcher.GetReleaseTarballCall.Returns.ReadCloser = io.NopCloser(buffer)
+		gitReleaseFetcher.GetReleaseAssetContextCall.Returns.ReadCloser = io.NopCloser(buffer)
+		gitReleaseFetcher.GetReleaseTarballContextCall.Returns.ReadCloser = io.NopCloser(buffer)
 
-		packager = &fakes.Packager{}
+		packager = &fakes.PackagerContext{}
 		buildpackCache = &fakes.BuildpackCache{}
 		buildpackCache.DirCall.Stub = func() string {
 			return cacheDir
@@ -75,7 +80,6 @@ func testRemoteFetcher(t *testing.T, context spec.G, it spec.S) {
 
 		remoteBuildpack = freezer.NewRemoteBuildpack("some-org", "some-repo", "some-platform", "some-arch")
 		remoteBuildpack.Offline = false
-		remoteBuildpack.Version = "some-version"
 
 		tmpDir, err = os.MkdirTemp("", "tmpDir")
 		Expect(err).NotTo(HaveOccurred())
@@ -87,7 +91,12 @@ func testRemoteFetcher(t *testing.T, context spec.G, it spec.S) {
 			return downloadDir, nil
 		}
 
-		remoteFetcher = freezer.NewRemoteFetcher(buildpackCache, gitReleaseFetcher, packager).WithFileSystem(fileSystem)
+		assetMatcher = &fakes.AssetMatcher{}
+		assetMatcher.MatchCall.Stub = func(_ freezer.RemoteBuildpack, release github.Release) (github.ReleaseAsset, error) {
+			return release.Assets[0], nil
+		}
+
+		remoteFetcher = freezer.NewRemoteFetcher(buildpackCache, gitReleaseFetcher, packager).WithFileSystem(fileSystem).WithAssetMatcher(assetMatcher)
 
 	})
 
@@ -109,10 +118,12 @@ func testRemoteFetcher(t *testing.T, context spec.G, it spec.S) {
 				uri, err := remoteFetcher.Get(remoteBuildpack)
 				Expect(err).ToNot(HaveOccurred())
 
-				Expect(gitReleaseFetcher.GetCall.Receives.Org).To(Equal("some-org"))
-				Expect(gitReleaseFetcher.GetCall.Receives.Repo).To(Equal("some-repo"))
+				Expect(gitReleaseFetcher.GetContextCall.Receives.Org).To(Equal("some-org"))
+				Expect(gitReleaseFetcher.GetContextCall.Receives.Repo).To(Equal("some-repo"))
 
+				Expect(buildpackCache.LockCall.Receives.Key).To(Equal("some-org:some-repo:some-platform:some-arch"))
 				Expect(buildpackCache.GetCall.Receives.Key).To(Equal("some-org:some-repo:some-platform:some-arch"))
+				Expect(buildpackCache.UnlockCall.Receives.Key).To(Equal("some-org:some-repo:some-platform:some-arch"))
 
 				Expect(buildpackCache.SetCall.CallCount).To(Equal(0))
 
@@ -135,12 +146,12 @@ func testRemoteFetcher(t *testing.T, context spec.G, it spec.S) {
 						uri, err := remoteFetcher.Get(remoteBuildpack)
 						Expect(err).ToNot(HaveOccurred())
 
-						Expect(gitReleaseFetcher.GetCall.Receives.Org).To(Equal("some-org"))
-						Expect(gitReleaseFetcher.GetCall.Receives.Repo).To(Equal("some-repo"))
+						Expect(gitReleaseFetcher.GetContextCall.Receives.Org).To(Equal("some-org"))
+						Expect(gitReleaseFetcher.GetContextCall.Receives.Repo).To(Equal("some-repo"))
 
 						Expect(buildpackCache.GetCall.Receives.Key).To(Equal("some-org:some-repo:some-platform:some-arch"))
 
-						Expect(gitReleaseFetcher.GetReleaseAssetCall.Receives.Asset).To(Equal(github.ReleaseAsset{
+						Expect(gitReleaseFetcher.GetReleaseAssetContextCall.Receives.Asset).To(Equal(github.ReleaseAsset{
 							URL: "some-url",
 						}))
 
@@ -167,17 +178,17 @@ func testRemoteFetcher(t *testing.T, context spec.G, it spec.S) {
 						uri, err := remoteFetcher.Get(remoteBuildpack)
 						Expect(err).ToNot(HaveOccurred())
 
-						Expect(gitReleaseFetcher.GetCall.Receives.Org).To(Equal("some-org"))
-						Expect(gitReleaseFetcher.GetCall.Receives.Repo).To(Equal("some-repo"))
+						Expect(gitReleaseFetcher.GetContextCall.Receives.Org).To(Equal("some-org"))
+						Expect(gitReleaseFetcher.GetContextCall.Receives.Repo).To(Equal("some-repo"))
 
 						Expect(buildpackCache.GetCall.Receives.Key).To(Equal("some-org:some-repo:some-platform:some-arch:cached"))
 
-						Expect(gitReleaseFetcher.GetReleaseTarballCall.Receives.Url).To(Equal("some-tarball-url"))
+						Expect(gitReleaseFetcher.GetReleaseTarballContextCall.Receives.Url).To(Equal("some-tarball-url"))
 
-						Expect(packager.ExecuteCall.Receives.BuildpackDir).To(Equal(downloadDir))
-						Expect(packager.ExecuteCall.Receives.Output).To(Equal(filepath.Join(cacheDir, "some-org", "some-repo", "some-platform", "some-arch", "cached", "some-tag.cnb")))
-						Expect(packager.ExecuteCall.Receives.Version).To(Equal("some-tag"))
-						Expect(packager.ExecuteCall.Receives.Cached).To(BeTrue())
+						Expect(packager.ExecuteContextCall.Receives.BuildpackDir).To(Equal(downloadDir))
+						Expect(packager.ExecuteContextCall.Receives.Output).To(Equal(filepath.Join(cacheDir, "some-org", "some-repo", "some-platform", "some-arch", "cached", "some-tag.cnb")))
+						Expect(packager.ExecuteContextCall.Receives.Version).To(Equal("some-tag"))
+						Expect(packager.ExecuteContextCall.Receives.Cached).To(BeTrue())
 
 						Expect(buildpackCache.SetCall.CallCount).To(Equal(1))
 
@@ -190,7 +201,7 @@ func testRemoteFetcher(t *testing.T, context spec.G, it spec.S) {
 				it.Before(func() {
 					var err error
 
-					gitReleaseFetcher.GetCall.Returns.Release = github.Release{
+					gitReleaseFetcher.GetContextCall.Returns.Release = github.Release{
 						TagName:    "some-tag",
 						TarballURL: "some-tarball-url",
 					}
@@ -214,11 +225,11 @@ func testRemoteFetcher(t *testing.T, context spec.G, it spec.S) {
 					Expect(tw.Close()).To(Succeed())
 					Expect(gw.Close()).To(Succeed())
 
-					gitReleaseFetcher.GetReleaseTarballCall.Returns.ReadCloser = io.NopCloser(buffer)
+					gitReleaseFetcher.GetReleaseTarballContextCall.Returns.ReadCloser = io.NopCloser(buffer)
 
 					Expect(os.MkdirAll(filepath.Join(cacheDir, "some-org", "some-repo"), os.ModePerm)).To(Succeed())
 
-					packager.ExecuteCall.Stub = func(string, string, string, bool) error {
+					packager.ExecuteContextCall.Stub = func(gocontext.Context, string, string, string, bool) error {
 						content, err := os.ReadFile(filepath.Join(downloadDir, "some-file"))
 						if err != nil {
 							return err
@@ -237,19 +248,19 @@ func testRemoteFetcher(t *testing.T, context spec.G, it spec.S) {
 						uri, err := remoteFetcher.Get(remoteBuildpack)
 						Expect(err).ToNot(HaveOccurred())
 
-						Expect(gitReleaseFetcher.GetCall.Receives.Org).To(Equal("some-org"))
-						Expect(gitReleaseFetcher.GetCall.Receives.Repo).To(Equal("some-repo"))
+						Expect(gitReleaseFetcher.GetContextCall.Receives.Org).To(Equal("some-org"))
+						Expect(gitReleaseFetcher.GetContextCall.Receives.Repo).To(Equal("some-repo"))
 
 						Expect(buildpackCache.GetCall.Receives.Key).To(Equal("some-org:some-repo:some-platform:some-arch"))
 
-						Expect(gitReleaseFetcher.GetReleaseTarballCall.Receives.Url).To(Equal("some-tarball-url"))
+						Expect(gitReleaseFetcher.GetReleaseTarballContextCall.Receives.Url).To(Equal("some-tarball-url"))
 
-						Expect(packager.ExecuteCall.Receives.BuildpackDir).To(Equal(downloadDir))
-						Expect(packager.ExecuteCall.Receives.Output).To(Equal(filepath.Join(cacheDir, "some-org", "some-repo", "some-platform", "some-arch", "some-tag.cnb")))
-						Expect(packager.ExecuteCall.Receives.Version).To(Equal("some-tag"))
-						Expect(packager.ExecuteCall.Receives.Cached).To(BeFalse())
+						Expect(packager.ExecuteContextCall.Receives.BuildpackDir).To(Equal(downloadDir))
+						Expect(packager.ExecuteContextCall.Receives.Output).To(Equal(filepath.Join(cacheDir, "some-org", "some-repo", "some-platform", "some-arch", "some-tag.cnb")))
+						Expect(packager.ExecuteContextCall.Receives.Version).To(Equal("some-tag"))
+						Expect(packager.ExecuteContextCall.Receives.Cached).To(BeFalse())
 
-						Expect(packager.ExecuteCall.Returns.Error).To(BeNil())
+						Expect(packager.ExecuteContextCall.Returns.Error).To(BeNil())
 
 						Expect(buildpackCache.SetCall.CallCount).To(Equal(1))
 
@@ -263,19 +274,19 @@ func testRemoteFetcher(t *testing.T, context spec.G, it spec.S) {
 						uri, err := remoteFetcher.Get(remoteBuildpack)
 						Expect(err).ToNot(HaveOccurred())
 
-						Expect(gitReleaseFetcher.GetCall.Receives.Org).To(Equal("some-org"))
-						Expect(gitReleaseFetcher.GetCall.Receives.Repo).To(Equal("some-repo"))
+						Expect(gitReleaseFetcher.GetContextCall.Receives.Org).To(Equal("some-org"))
+						Expect(gitReleaseFetcher.GetContextCall.Receives.Repo).To(Equal("some-repo"))
 
 						Expect(buildpackCache.GetCall.Receives.Key).To(Equal("some-org:some-repo:some-platform:some-arch:cached"))
 
-						Expect(gitReleaseFetcher.GetReleaseTarballCall.Receives.Url).To(Equal("some-tarball-url"))
+						Expect(gitReleaseFetcher.GetReleaseTarballContextCall.Receives.Url).To(Equal("some-tarball-url"))
 
-						Expect(packager.ExecuteCall.Receives.BuildpackDir).To(Equal(downloadDir))
-						Expect(packager.ExecuteCall.Receives.Output).To(Equal(filepath.Join(cacheDir, "some-org", "some-repo", "some-platform", "some-arch", "cached", "some-tag.cnb")))
-						Expect(packager.ExecuteCall.Receives.Version).To(Equal("some-tag"))
-						Expect(packager.ExecuteCall.Receives.Cached).To(BeTrue())
+						Expect(packager.ExecuteContextCall.Receives.BuildpackDir).To(Equal(downloadDir))
+						Expect(packager.ExecuteContextCall.Receives.Output).To(Equal(filepath.Join(cacheDir, "some-org", "some-repo", "some-platform", "some-arch", "cached", "some-tag.cnb")))
+						Expect(packager.ExecuteContextCall.Receives.Version).To(Equal("some-tag"))
+						Expect(packager.ExecuteContextCall.Receives.Cached).To(BeTrue())
 
-						Expect(packager.ExecuteCall.Returns.Error).To(BeNil())
+						Expect(packager.ExecuteContextCall.Returns.Error).To(BeNil())
 
 						Expect(buildpackCache.SetCall.CallCount).To(Equal(1))
 
@@ -294,12 +305,12 @@ func testRemoteFetcher(t *testing.T, context spec.G, it spec.S) {
 				uri, err := remoteFetcher.Get(remoteBuildpack)
 				Expect(err).ToNot(HaveOccurred())
 
-				Expect(gitReleaseFetcher.GetCall.Receives.Org).To(Equal("some-org"))
-				Expect(gitReleaseFetcher.GetCall.Receives.Repo).To(Equal("some-repo"))
+				Expect(gitReleaseFetcher.GetContextCall.Receives.Org).To(Equal("some-org"))
+				Expect(gitReleaseFetcher.GetContextCall.Receives.Repo).To(Equal("some-repo"))
 
 				Expect(buildpackCache.GetCall.CallCount).To(Equal(1))
 
-				Expect(gitReleaseFetcher.GetReleaseAssetCall.Receives.Asset).To(Equal(github.ReleaseAsset{
+				Expect(gitReleaseFetcher.GetReleaseAssetContextCall.Receives.Asset).To(Equal(github.ReleaseAsset{
 					URL: "some-url",
 				}))
 
@@ -322,7 +333,7 @@ func testRemoteFetcher(t *testing.T, context spec.G, it spec.S) {
 
 		context("when there is a v prepending the release tag", func() {
 			it.Before(func() {
-				gitReleaseFetcher.GetCall.Returns.Release = github.Release{
+				gitReleaseFetcher.GetContextCall.Returns.Release = github.Release{
 					TagName: "v1.2.3",
 					Assets: []github.ReleaseAsset{
 						{
@@ -343,12 +354,12 @@ func testRemoteFetcher(t *testing.T, context spec.G, it spec.S) {
 				uri, err := remoteFetcher.Get(remoteBuildpack)
 				Expect(err).ToNot(HaveOccurred())
 
-				Expect(gitReleaseFetcher.GetCall.Receives.Org).To(Equal("some-org"))
-				Expect(gitReleaseFetcher.GetCall.Receives.Repo).To(Equal("some-repo"))
+				Expect(gitReleaseFetcher.GetContextCall.Receives.Org).To(Equal("some-org"))
+				Expect(gitReleaseFetcher.GetContextCall.Receives.Repo).To(Equal("some-repo"))
 
 				Expect(buildpackCache.GetCall.Receives.Key).To(Equal("some-org:some-repo:some-platform:some-arch"))
 
-				Expect(gitReleaseFetcher.GetReleaseAssetCall.Receives.Asset).To(Equal(github.ReleaseAsset{
+				Expect(gitReleaseFetcher.GetReleaseAssetContextCall.Receives.Asset).To(Equal(github.ReleaseAsset{
 					URL: "some-url",
 				}))
 
@@ -369,10 +380,599 @@ func testRemoteFetcher(t *testing.T, context spec.G, it spec.S) {
 			})
 		})
 
+		context("when a version is pinned", func() {
+			it.Before(func() {
+				remoteBuildpack.Version = "1.2.3"
+
+				gitReleaseFetcher.GetReleaseByTagContextCall.Returns.Release = github.Release{
+					TagName: "v1.2.3",
+					Assets: []github.ReleaseAsset{
+						{
+							URL: "some-url",
+						},
+					},
+					TarballURL: "some-tarball-url",
+				}
+
+				buildpackCache.GetCall.Returns.Bool = false
+			})
+
+			it("fetches the release with the given tag", func() {
+				uri, err := remoteFetcher.Get(remoteBuildpack)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(gitReleaseFetcher.GetContextCall.CallCount).To(Equal(0))
+				Expect(gitReleaseFetcher.GetReleaseByTagContextCall.CallCount).To(Equal(1))
+				Expect(gitReleaseFetcher.GetReleaseByTagContextCall.Receives.Org).To(Equal("some-org"))
+				Expect(gitReleaseFetcher.GetReleaseByTagContextCall.Receives.Repo).To(Equal("some-repo"))
+				Expect(gitReleaseFetcher.GetReleaseByTagContextCall.Receives.Tag).To(Equal("1.2.3"))
+
+				Expect(buildpackCache.GetCall.Receives.Key).To(Equal("some-org:some-repo:some-platform:some-arch@1.2.3"))
+
+				Expect(buildpackCache.SetCall.Receives.Key).To(Equal("some-org:some-repo:some-platform:some-arch@1.2.3"))
+				Expect(buildpackCache.SetCall.Receives.CachedEntry).To(Equal(freezer.CacheEntry{
+					Version: "1.2.3",
+					URI:     filepath.Join(cacheDir, "some-org", "some-repo", "some-platform", "some-arch", "1.2.3.cnb"),
+				}))
+
+				Expect(uri).To(Equal(filepath.Join(cacheDir, "some-org", "some-repo", "some-platform", "some-arch", "1.2.3.cnb")))
+			})
+
+			context("when the release tag has a different v prefix than the version", func() {
+				it.Before(func() {
+					remoteBuildpack.Version = "v1.2.3"
+
+					release := gitReleaseFetcher.GetReleaseByTagContextCall.Returns.Release
+					gitReleaseFetcher.GetReleaseByTagContextCall.Stub = func(_ gocontext.Context, org, repo, tag string) (github.Release, error) {
+						if tag != "1.2.3" {
+							return github.Release{}, errors.New("unexpected response status: 404 Not Found")
+						}
+
+						return release, nil
+					}
+				})
+
+				it("falls back to the other form of the tag", func() {
+					uri, err := remoteFetcher.Get(remoteBuildpack)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(gitReleaseFetcher.GetReleaseByTagContextCall.CallCount).To(Equal(2))
+					Expect(gitReleaseFetcher.GetReleaseByTagContextCall.Receives.Tag).To(Equal("1.2.3"))
+
+					Expect(buildpackCache.GetCall.Receives.Key).To(Equal("some-org:some-repo:some-platform:some-arch@1.2.3"))
+
+					Expect(uri).To(Equal(filepath.Join(cacheDir, "some-org", "some-repo", "some-platform", "some-arch", "1.2.3.cnb")))
+				})
+			})
+
+			context("when the pinned version is already cached", func() {
+				it.Before(func() {
+					buildpackCache.GetCall.Returns.Bool = true
+					buildpackCache.GetCall.Returns.CacheEntry = freezer.CacheEntry{
+						Version: "1.2.3",
+						URI:     "keep-this-uri",
+					}
+				})
+
+				it("returns the cached buildpack without contacting github", func() {
+					uri, err := remoteFetcher.Get(remoteBuildpack)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(gitReleaseFetcher.GetContextCall.CallCount).To(Equal(0))
+					Expect(gitReleaseFetcher.GetReleaseByTagContextCall.CallCount).To(Equal(0))
+					Expect(buildpackCache.SetCall.CallCount).To(Equal(0))
+
+					Expect(uri).To(Equal("keep-this-uri"))
+				})
+			})
+
+			context("when the resulting buildpack should be cached", func() {
+				it.Before(func() {
+					remoteBuildpack.Offline = true
+				})
+
+				it("uses a cached key that includes the version", func() {
+					_, err := remoteFetcher.Get(remoteBuildpack)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(buildpackCache.GetCall.Receives.Key).To(Equal("some-org:some-repo:some-platform:some-arch:cached@1.2.3"))
+					Expect(packager.ExecuteContextCall.Receives.Version).To(Equal("1.2.3"))
+				})
+			})
+
+			context("when neither form of the tag exists", func() {
+				it.Before(func() {
+					gitReleaseFetcher.GetReleaseByTagContextCall.Returns.Error = errors.New("unexpected response status: 404 Not Found")
+				})
+
+				it("returns an error", func() {
+					_, err := remoteFetcher.Get(remoteBuildpack)
+					Expect(err).To(MatchError("unexpected response status: 404 Not Found"))
+
+					Expect(gitReleaseFetcher.GetReleaseByTagContextCall.CallCount).To(Equal(2))
+				})
+			})
+		})
+
+		context("when a version constraint is given", func() {
+			it.Before(func() {
+				remoteBuildpack.VersionConstraint = "1.x"
+
+				gitReleaseFetcher.ListContextCall.Returns.ReleaseSlice = []github.Release{
+					{TagName: "v2.0.0", Assets: []github.ReleaseAsset{{URL: "2.0.0-url"}}},
+					{TagName: "v1.3.0", Assets: []github.ReleaseAsset{{URL: "1.3.0-url"}}, Prerelease: true},
+					{TagName: "v1.2.1", Assets: []github.ReleaseAsset{{URL: "1.2.1-url"}}, Draft: true},
+					{TagName: "v1.2.0", Assets: []github.ReleaseAsset{{URL: "1.2.0-url"}}},
+					{TagName: "not-a-version", Assets: []github.ReleaseAsset{{URL: "other-url"}}},
+					{TagName: "v1.1.0", Assets: []github.ReleaseAsset{{URL: "1.1.0-url"}}},
+				}
+
+				buildpackCache.GetCall.Returns.Bool = false
+			})
+
+			context("when there is a prerelease that satisfies the constraint", func() {
+				it.Before(func() {
+					gitReleaseFetcher.ListContextCall.Returns.ReleaseSlice = append(gitReleaseFetcher.ListContextCall.Returns.ReleaseSlice,
+						github.Release{TagName: "v1.4.0-rc.1", Assets: []github.ReleaseAsset{{URL: "1.4.0-rc.1-url"}}, Prerelease: true},
+					)
+				})
+
+				it("ignores it unless prereleases are allowed", func() {
+					_, err := remoteFetcher.Get(remoteBuildpack)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(gitReleaseFetcher.GetReleaseAssetContextCall.Receives.Asset).To(Equal(github.ReleaseAsset{
+						URL: "1.2.0-url",
+					}))
+				})
+			})
+
+			it("fetches the highest release that satisfies the constraint", func() {
+				uri, err := remoteFetcher.Get(remoteBuildpack)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(gitReleaseFetcher.GetContextCall.CallCount).To(Equal(0))
+				Expect(gitReleaseFetcher.ListContextCall.Receives.Org).To(Equal("some-org"))
+				Expect(gitReleaseFetcher.ListContextCall.Receives.Repo).To(Equal("some-repo"))
+
+				Expect(gitReleaseFetcher.GetReleaseAssetContextCall.Receives.Asset).To(Equal(github.ReleaseAsset{
+					URL: "1.2.0-url",
+				}))
+
+				Expect(buildpackCache.GetCall.Receives.Key).To(Equal("some-org:some-repo:some-platform:some-arch@1.x"))
+				Expect(buildpackCache.SetCall.Receives.Key).To(Equal("some-org:some-repo:some-platform:some-arch@1.x"))
+				Expect(buildpackCache.SetCall.Receives.CachedEntry.Version).To(Equal("1.2.0"))
+
+				Expect(uri).To(Equal(filepath.Join(cacheDir, "some-org", "some-repo", "some-platform", "some-arch", "1.2.0.cnb")))
+			})
+
+			context("when prereleases and drafts are allowed", func() {
+				it.Before(func() {
+					remoteBuildpack.AllowPrerelease = true
+					remoteBuildpack.AllowDraft = true
+				})
+
+				it("considers them when choosing a release", func() {
+					uri, err := remoteFetcher.Get(remoteBuildpack)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(gitReleaseFetcher.GetReleaseAssetContextCall.Receives.Asset).To(Equal(github.ReleaseAsset{
+						URL: "1.3.0-url",
+					}))
+
+					Expect(uri).To(Equal(filepath.Join(cacheDir, "some-org", "some-repo", "some-platform", "some-arch", "1.3.0.cnb")))
+				})
+
+				context("when a prerelease is tagged with its prerelease version", func() {
+					it.Before(func() {
+						gitReleaseFetcher.ListContextCall.Returns.ReleaseSlice = append(gitReleaseFetcher.ListContextCall.Returns.ReleaseSlice,
+							github.Release{TagName: "v1.4.0-rc.1", Assets: []github.ReleaseAsset{{URL: "1.4.0-rc.1-url"}}, Prerelease: true},
+						)
+					})
+
+					it("considers it when choosing a release", func() {
+						uri, err := remoteFetcher.Get(remoteBuildpack)
+						Expect(err).ToNot(HaveOccurred())
+
+						Expect(gitReleaseFetcher.GetReleaseAssetContextCall.Receives.Asset).To(Equal(github.ReleaseAsset{
+							URL: "1.4.0-rc.1-url",
+						}))
+
+						Expect(uri).To(Equal(filepath.Join(cacheDir, "some-org", "some-repo", "some-platform", "some-arch", "1.4.0-rc.1.cnb")))
+					})
+
+					it("prefers the release it precedes", func() {
+						gitReleaseFetcher.ListContextCall.Returns.ReleaseSlice = append(gitReleaseFetcher.ListContextCall.Returns.ReleaseSlice,
+							github.Release{TagName: "v1.4.0", Assets: []github.ReleaseAsset{{URL: "1.4.0-url"}}},
+						)
+
+						_, err := remoteFetcher.Get(remoteBuildpack)
+						Expect(err).ToNot(HaveOccurred())
+
+						Expect(gitReleaseFetcher.GetReleaseAssetContextCall.Receives.Asset).To(Equal(github.ReleaseAsset{
+							URL: "1.4.0-url",
+						}))
+					})
+				})
+			})
+
+			context("when the resolved release is already cached", func() {
+				it.Before(func() {
+					buildpackCache.GetCall.Returns.Bool = true
+					buildpackCache.GetCall.Returns.CacheEntry = freezer.CacheEntry{
+						Version: "1.2.0",
+						URI:     "keep-this-uri",
+					}
+				})
+
+				it("keeps the cached buildpack", func() {
+					uri, err := remoteFetcher.Get(remoteBuildpack)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(gitReleaseFetcher.GetReleaseAssetContextCall.CallCount).To(Equal(0))
+					Expect(buildpackCache.SetCall.CallCount).To(Equal(0))
+
+					Expect(uri).To(Equal("keep-this-uri"))
+				})
+			})
+
+			context("failure cases", func() {
+				context("when the constraint is invalid", func() {
+					it.Before(func() {
+						remoteBuildpack.VersionConstraint = "not a constraint"
+					})
+
+					it("returns an error", func() {
+						_, err := remoteFetcher.Get(remoteBuildpack)
+						Expect(err).To(MatchError(ContainSubstring(`invalid version constraint "not a constraint"`)))
+					})
+				})
+
+				context("when listing the releases fails", func() {
+					it.Before(func() {
+						gitReleaseFetcher.ListContextCall.Returns.Error = errors.New("unable to list releases")
+					})
+
+					it("returns an error", func() {
+						_, err := remoteFetcher.Get(remoteBuildpack)
+						Expect(err).To(MatchError("unable to list releases"))
+					})
+				})
+
+				context("when no release satisfies the constraint", func() {
+					it.Before(func() {
+						remoteBuildpack.VersionConstraint = "3.x"
+					})
+
+					it("returns an error", func() {
+						_, err := remoteFetcher.Get(remoteBuildpack)
+						Expect(err).To(MatchError(`release not found: no release of some-org/some-repo satisfies version constraint "3.x"`))
+						Expect(err).To(MatchError(github.ErrReleaseNotFound))
+					})
+				})
+			})
+		})
+
+		context("when offline mode is enabled", func() {
+			it.Before(func() {
+				buildpackCache.GetCall.Returns.CacheEntry = freezer.CacheEntry{
+					Version: "some-other-tag",
+					URI:     "keep-this-uri",
+				}
+
+				remoteFetcher = remoteFetcher.WithOfflineMode(true)
+			})
+
+			it("returns the cached buildpack without contacting github", func() {
+				uri, err := remoteFetcher.Get(remoteBuildpack)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(gitReleaseFetcher.GetContextCall.CallCount).To(Equal(0))
+				Expect(buildpackCache.SetCall.CallCount).To(Equal(0))
+
+				Expect(uri).To(Equal("keep-this-uri"))
+			})
+
+			context("when the buildpack is not cached", func() {
+				it.Before(func() {
+					buildpackCache.GetCall.Returns.Bool = false
+				})
+
+				it("returns an error naming the key", func() {
+					_, err := remoteFetcher.Get(remoteBuildpack)
+					Expect(err).To(MatchError(freezer.ErrNotCached))
+					Expect(err).To(MatchError(ContainSubstring(`no cache entry for key "some-org:some-repo:some-platform:some-arch"`)))
+
+					Expect(gitReleaseFetcher.GetContextCall.CallCount).To(Equal(0))
+				})
+			})
+
+			context("when FREEZER_OFFLINE is set", func() {
+				it.Before(func() {
+					Expect(os.Setenv("FREEZER_OFFLINE", "1")).To(Succeed())
+					remoteFetcher = freezer.NewRemoteFetcher(buildpackCache, gitReleaseFetcher, packager)
+				})
+
+				it.After(func() {
+					Expect(os.Unsetenv("FREEZER_OFFLINE")).To(Succeed())
+				})
+
+				it("enables offline mode by default", func() {
+					uri, err := remoteFetcher.Get(remoteBuildpack)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(gitReleaseFetcher.GetContextCall.CallCount).To(Equal(0))
+
+					Expect(uri).To(Equal("keep-this-uri"))
+				})
+			})
+		})
+
+		context("when a TTL is set", func() {
+			it.Before(func() {
+				buildpackCache.GetCall.Returns.CacheEntry = freezer.CacheEntry{
+					Version:   "some-tag",
+					URI:       "keep-this-uri",
+					CheckedAt: time.Now().Add(-time.Hour),
+				}
+
+				remoteFetcher = remoteFetcher.WithTTL(2 * time.Hour)
+			})
+
+			it("returns the cached buildpack without contacting github within the window", func() {
+				uri, err := remoteFetcher.Get(remoteBuildpack)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(gitReleaseFetcher.GetContextCall.CallCount).To(Equal(0))
+				Expect(buildpackCache.SetCall.CallCount).To(Equal(0))
+
+				Expect(uri).To(Equal("keep-this-uri"))
+			})
+
+			context("when the window has passed", func() {
+				it.Before(func() {
+					remoteFetcher = remoteFetcher.WithTTL(30 * time.Minute)
+				})
+
+				it("checks github and records when it did", func() {
+					uri, err := remoteFetcher.Get(remoteBuildpack)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(gitReleaseFetcher.GetContextCall.CallCount).To(Equal(1))
+					Expect(gitReleaseFetcher.GetReleaseAssetContextCall.CallCount).To(Equal(0))
+
+					Expect(buildpackCache.SetCall.Receives.Key).To(Equal("some-org:some-repo:some-platform:some-arch"))
+					Expect(buildpackCache.SetCall.Receives.CachedEntry.URI).To(Equal("keep-this-uri"))
+					Expect(buildpackCache.SetCall.Receives.CachedEntry.CheckedAt).To(BeTemporally("~", time.Now(), time.Minute))
+
+					Expect(uri).To(Equal("keep-this-uri"))
+				})
+			})
+
+			context("when github answers that the release has not been modified", func() {
+				it.Before(func() {
+					remoteFetcher = remoteFetcher.WithTTL(30 * time.Minute)
+
+					buildpackCache.GetCall.Returns.CacheEntry.ETag = `"some-etag"`
+					gitReleaseFetcher.GetIfModifiedContextCall.Returns.Error = github.ErrNotModified
+				})
+
+				it("records when it checked", func() {
+					_, err := remoteFetcher.Get(remoteBuildpack)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(buildpackCache.SetCall.Receives.CachedEntry.ETag).To(Equal(`"some-etag"`))
+					Expect(buildpackCache.SetCall.Receives.CachedEntry.CheckedAt).To(BeTemporally("~", time.Now(), time.Minute))
+				})
+			})
+
+			context("when a refresh is forced", func() {
+				it.Before(func() {
+					remoteFetcher = remoteFetcher.WithForceRefresh(true)
+				})
+
+				it("checks github within the window", func() {
+					_, err := remoteFetcher.Get(remoteBuildpack)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(gitReleaseFetcher.GetContextCall.CallCount).To(Equal(1))
+				})
+			})
+
+			context("when a version is pinned", func() {
+				it.Before(func() {
+					remoteBuildpack.Version = "1.2.3"
+					gitReleaseFetcher.GetReleaseByTagContextCall.Returns.Release = github.Release{
+						TagName: "1.2.3",
+						Assets:  []github.ReleaseAsset{{URL: "some-url"}},
+					}
+
+					Expect(os.MkdirAll(filepath.Join(cacheDir, "some-org", "some-repo", "some-platform", "some-arch"), os.ModePerm)).To(Succeed())
+				})
+
+				it("does not record when it checked", func() {
+					_, err := remoteFetcher.Get(remoteBuildpack)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(gitReleaseFetcher.GetReleaseByTagContextCall.CallCount).To(Equal(1))
+					Expect(buildpackCache.SetCall.Receives.CachedEntry.CheckedAt).To(BeZero())
+				})
+			})
+		})
+
+		context("when the cached entry has validators", func() {
+			it.Before(func() {
+				buildpackCache.GetCall.Returns.CacheEntry = freezer.CacheEntry{
+					Version:      "some-tag",
+					URI:          "keep-this-uri",
+					ETag:         `"some-etag"`,
+					LastModified: "some-last-modified",
+				}
+
+				gitReleaseFetcher.GetIfModifiedContextCall.Returns.Error = github.ErrNotModified
+			})
+
+			it("asks whether the latest release has changed", func() {
+				uri, err := remoteFetcher.Get(remoteBuildpack)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(gitReleaseFetcher.GetContextCall.CallCount).To(Equal(0))
+				Expect(gitReleaseFetcher.GetIfModifiedContextCall.Receives.Org).To(Equal("some-org"))
+				Expect(gitReleaseFetcher.GetIfModifiedContextCall.Receives.Repo).To(Equal("some-repo"))
+				Expect(gitReleaseFetcher.GetIfModifiedContextCall.Receives.Etag).To(Equal(`"some-etag"`))
+				Expect(gitReleaseFetcher.GetIfModifiedContextCall.Receives.LastModified).To(Equal("some-last-modified"))
+
+				Expect(buildpackCache.SetCall.CallCount).To(Equal(0))
+
+				Expect(uri).To(Equal("keep-this-uri"))
+			})
+
+			context("when a new release has been published", func() {
+				it.Before(func() {
+					gitReleaseFetcher.GetIfModifiedContextCall.Returns.Error = nil
+					gitReleaseFetcher.GetIfModifiedContextCall.Returns.Release = github.Release{
+						TagName:      "some-other-tag",
+						Assets:       []github.ReleaseAsset{{URL: "some-url"}},
+						ETag:         `"some-other-etag"`,
+						LastModified: "some-other-last-modified",
+					}
+
+					Expect(os.MkdirAll(filepath.Join(cacheDir, "some-org", "some-repo", "some-platform", "some-arch"), os.ModePerm)).To(Succeed())
+				})
+
+				it("fetches it and stores its validators", func() {
+					uri, err := remoteFetcher.Get(remoteBuildpack)
+					Expect(err).ToNot(HaveOccurred())
+
+					cachedEntry := buildpackCache.SetCall.Receives.CachedEntry
+					Expect(cachedEntry.CheckedAt).To(BeTemporally("~", time.Now(), time.Minute))
+
+					cachedEntry.CheckedAt = time.Time{}
+					Expect(cachedEntry).To(Equal(freezer.CacheEntry{
+						Version:      "some-other-tag",
+						URI:          filepath.Join(cacheDir, "some-org", "some-repo", "some-platform", "some-arch", "some-other-tag.cnb"),
+						ETag:         `"some-other-etag"`,
+						LastModified: "some-other-last-modified",
+					}))
+
+					Expect(uri).To(Equal(filepath.Join(cacheDir, "some-org", "some-repo", "some-platform", "some-arch", "some-other-tag.cnb")))
+				})
+			})
+
+			context("when the cached file is missing or corrupt", func() {
+				it.Before(func() {
+					buildpackCache.GetCall.Returns.Bool = false
+					gitReleaseFetcher.GetContextCall.Returns.Release.ETag = `"some-etag"`
+				})
+
+				it("fetches the latest release again without asking whether it has changed", func() {
+					uri, err := remoteFetcher.Get(remoteBuildpack)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(gitReleaseFetcher.GetIfModifiedContextCall.CallCount).To(Equal(0))
+					Expect(gitReleaseFetcher.GetContextCall.CallCount).To(Equal(1))
+					Expect(gitReleaseFetcher.GetReleaseAssetContextCall.CallCount).To(Equal(1))
+
+					Expect(uri).To(Equal(filepath.Join(cacheDir, "some-org", "some-repo", "some-platform", "some-arch", "some-tag.cnb")))
+					Expect(uri).To(BeAnExistingFile())
+					Expect(buildpackCache.SetCall.Receives.CachedEntry.ETag).To(Equal(`"some-etag"`))
+				})
+			})
+
+			context("when the release has changed but its tag has not", func() {
+				it.Before(func() {
+					gitReleaseFetcher.GetIfModifiedContextCall.Returns.Error = nil
+					gitReleaseFetcher.GetIfModifiedContextCall.Returns.Release = github.Release{
+						TagName: "some-tag",
+						ETag:    `"some-other-etag"`,
+					}
+				})
+
+				it("only updates the validators", func() {
+					uri, err := remoteFetcher.Get(remoteBuildpack)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(gitReleaseFetcher.GetReleaseAssetContextCall.CallCount).To(Equal(0))
+					cachedEntry := buildpackCache.SetCall.Receives.CachedEntry
+					Expect(cachedEntry.CheckedAt).To(BeTemporally("~", time.Now(), time.Minute))
+
+					cachedEntry.CheckedAt = time.Time{}
+					Expect(cachedEntry).To(Equal(freezer.CacheEntry{
+						Version: "some-tag",
+						URI:     "keep-this-uri",
+						ETag:    `"some-other-etag"`,
+					}))
+
+					Expect(uri).To(Equal("keep-this-uri"))
+				})
+			})
+		})
+
+		context("when GitHub is rate limiting requests", func() {
+			var limitErr *github.RateLimitError
+
+			it.Before(func() {
+				limitErr = &github.RateLimitError{Status: "403 Forbidden"}
+				gitReleaseFetcher.GetContextCall.Returns.Error = limitErr
+
+				Expect(os.WriteFile(filepath.Join(cacheDir, "some-tag.cnb"), nil, 0644)).To(Succeed())
+				buildpackCache.GetCall.Returns.CacheEntry = freezer.CacheEntry{
+					Version: "some-tag",
+					URI:     filepath.Join(cacheDir, "some-tag.cnb"),
+				}
+			})
+
+			it("falls back to the cached buildpack", func() {
+				uri, err := remoteFetcher.Get(remoteBuildpack)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(buildpackCache.SetCall.CallCount).To(Equal(0))
+
+				Expect(uri).To(Equal(filepath.Join(cacheDir, "some-tag.cnb")))
+			})
+
+			context("when nothing is cached", func() {
+				it.Before(func() {
+					buildpackCache.GetCall.Returns.Bool = false
+				})
+
+				it("returns the rate limit error", func() {
+					_, err := remoteFetcher.Get(remoteBuildpack)
+					Expect(err).To(MatchError(limitErr))
+				})
+			})
+
+			context("when the cached file is missing", func() {
+				it.Before(func() {
+					Expect(os.Remove(filepath.Join(cacheDir, "some-tag.cnb"))).To(Succeed())
+				})
+
+				it("returns the rate limit error", func() {
+					_, err := remoteFetcher.Get(remoteBuildpack)
+					Expect(err).To(MatchError(limitErr))
+				})
+			})
+
+			context("when a version is pinned", func() {
+				it.Before(func() {
+					remoteBuildpack.Version = "1.2.3"
+					gitReleaseFetcher.GetReleaseByTagContextCall.Returns.Error = limitErr
+					buildpackCache.GetCall.Returns.Bool = false
+				})
+
+				it("does not try the other form of the tag", func() {
+					_, err := remoteFetcher.Get(remoteBuildpack)
+					Expect(err).To(MatchError(limitErr))
+
+					Expect(gitReleaseFetcher.GetReleaseByTagContextCall.CallCount).To(Equal(1))
+				})
+			})
+		})
+
 		context("failure cases", func() {
 			context("when there is a failure in the gitReleaseFetcher get", func() {
 				it.Before(func() {
-					gitReleaseFetcher.GetCall.Returns.Error = errors.New("unable to get release")
+					gitReleaseFetcher.GetContextCall.Returns.Error = errors.New("unable to get release")
 				})
 
 				it("returns an error", func() {
@@ -381,6 +981,75 @@ func testRemoteFetcher(t *testing.T, context spec.G, it spec.S) {
 				})
 			})
 
+			context("when no asset of the release matches", func() {
+				it.Before(func() {
+					buildpackCache.GetCall.Returns.Bool = false
+
+					assetMatcher.MatchCall.Stub = nil
+					assetMatcher.MatchCall.Returns.Error = fmt.Errorf("%w: some-details", github.ErrAssetNotFound)
+				})
+
+				it("returns an error", func() {
+					_, err := remoteFetcher.Get(remoteBuildpack)
+					Expect(err).To(MatchError("asset not found: some-details"))
+					Expect(err).To(MatchError(github.ErrAssetNotFound))
+
+					Expect(assetMatcher.MatchCall.Receives.Buildpack).To(Equal(remoteBuildpack))
+					Expect(assetMatcher.MatchCall.Receives.Release.TagName).To(Equal("some-tag"))
+					Expect(gitReleaseFetcher.GetReleaseAssetContextCall.CallCount).To(Equal(0))
+				})
+			})
+
+			context("when the download fails part way through", func() {
+				var bundle *os.File
+
+				it.Before(func() {
+					buildpackCache.GetCall.Returns.Bool = false
+
+					Expect(os.WriteFile(filepath.Join(tmpDir, "some-bundle"), []byte("some-partial-content"), 0644)).To(Succeed())
+
+					var err error
+					bundle, err = os.Open(filepath.Join(tmpDir, "some-bundle"))
+					Expect(err).NotTo(HaveOccurred())
+
+					gitReleaseFetcher.GetReleaseAssetContextCall.Returns.ReadCloser = struct {
+						io.Reader
+						io.Closer
+					}{io.MultiReader(bundle, iotest.ErrReader(errors.New("connection reset"))), bundle}
+				})
+
+				it("returns an error without leaving a partial file in the cache", func() {
+					_, err := remoteFetcher.Get(remoteBuildpack)
+					Expect(err).To(MatchError("connection reset"))
+
+					files, err := os.ReadDir(filepath.Join(cacheDir, "some-org", "some-repo", "some-platform", "some-arch"))
+					Expect(err).NotTo(HaveOccurred())
+					Expect(files).To(BeEmpty())
+
+					Expect(buildpackCache.SetCall.CallCount).To(Equal(0))
+				})
+
+				it("closes the download", func() {
+					_, err := remoteFetcher.Get(remoteBuildpack)
+					Expect(err).To(HaveOccurred())
+
+					Expect(bundle.Close()).To(MatchError(os.ErrClosed))
+				})
+			})
+
+			context("when the cache key cannot be locked", func() {
+				it.Before(func() {
+					buildpackCache.LockCall.Returns.Error = errors.New("failed lock")
+				})
+
+				it("returns an error", func() {
+					_, err := remoteFetcher.Get(remoteBuildpack)
+					Expect(err).To(MatchError("failed lock"))
+
+					Expect(buildpackCache.GetCall.CallCount).To(Equal(0))
+				})
+			})
+
 			context("cache get fails", func() {
 				it.Before(func() {
 					buildpackCache.GetCall.Returns.Error = errors.New("failed get")
@@ -395,7 +1064,7 @@ func testRemoteFetcher(t *testing.T, context spec.G, it spec.S) {
 			context("when getting the release tarball fails", func() {
 				it.Before(func() {
 					remoteBuildpack.Offline = true
-					gitReleaseFetcher.GetReleaseTarballCall.Returns.Error = errors.New("unable to get release tarball")
+					gitReleaseFetcher.GetReleaseTarballContextCall.Returns.Error = errors.New("unable to get release tarball")
 				})
 
 				it("returns an error", func() {
@@ -406,7 +1075,7 @@ func testRemoteFetcher(t *testing.T, context spec.G, it spec.S) {
 
 			context("when getting the release asset fails", func() {
 				it.Before(func() {
-					gitReleaseFetcher.GetReleaseAssetCall.Returns.Error = errors.New("unable to get release asset")
+					gitReleaseFetcher.GetReleaseAssetContextCall.Returns.Error = errors.New("unable to get release asset")
 				})
 
 				it("returns an error", func() {
@@ -417,7 +1086,7 @@ func testRemoteFetcher(t *testing.T, context spec.G, it spec.S) {
 
 			context("when creating a temp directory fails", func() {
 				it.Before(func() {
-					gitReleaseFetcher.GetCall.Returns.Release = github.Release{
+					gitReleaseFetcher.GetContextCall.Returns.Release = github.Release{
 						TagName:    "some-tag",
 						TarballURL: "some-tarball-url",
 					}
@@ -441,7 +1110,7 @@ func testRemoteFetcher(t *testing.T, context spec.G, it spec.S) {
 
 			context("when decompression fails", func() {
 				it.Before(func() {
-					gitReleaseFetcher.GetCall.Returns.Release = github.Release{
+					gitReleaseFetcher.GetContextCall.Returns.Release = github.Release{
 						TagName:    "some-tag",
 						TarballURL: "some-tarball-url",
 					}
@@ -449,7 +1118,7 @@ func testRemoteFetcher(t *testing.T, context spec.G, it spec.S) {
 					buildpackCache.GetCall.Returns.CacheEntry = freezer.CacheEntry{
 						Version: "some-other-tag",
 					}
-					gitReleaseFetcher.GetReleaseTarballCall.Returns.ReadCloser = io.NopCloser(bytes.NewBuffer(nil))
+					gitReleaseFetcher.GetReleaseTarballContextCall.Returns.ReadCloser = io.NopCloser(bytes.NewBuffer(nil))
 				})
 
 				it("returns an error", func() {
@@ -460,7 +1129,7 @@ func testRemoteFetcher(t *testing.T, context spec.G, it spec.S) {
 
 			context("when packing fails", func() {
 				it.Before(func() {
-					gitReleaseFetcher.GetCall.Returns.Release = github.Release{
+					gitReleaseFetcher.GetContextCall.Returns.Release = github.Release{
 						TagName:    "some-tag",
 						TarballURL: "some-tarball-url",
 					}
@@ -468,7 +1137,7 @@ func testRemoteFetcher(t *testing.T, context spec.G, it spec.S) {
 					buildpackCache.GetCall.Returns.CacheEntry = freezer.CacheEntry{
 						Version: "some-other-tag",
 					}
-					packager.ExecuteCall.Returns.Error = errors.New("failed to package buildpack")
+					packager.ExecuteContextCall.Returns.Error = errors.New("failed to package buildpack")
 				})
 
 				it("returns an error", func() {
@@ -491,4 +1160,109 @@ func testRemoteFetcher(t *testing.T, context spec.G, it spec.S) {
 			})
 		})
 	})
+
+	context("GetContext", func() {
+		var (
+			ctx    gocontext.Context
+			cancel gocontext.CancelFunc
+		)
+
+		it.Before(func() {
+			ctx, cancel = gocontext.WithCancel(gocontext.Background())
+
+			remoteBuildpack.Offline = true
+			buildpackCache.GetCall.Returns.Bool = false
+		})
+
+		it.After(func() {
+			cancel()
+		})
+
+		it("passes the context to every request and to the packager", func() {
+			_, err := remoteFetcher.GetContext(ctx, remoteBuildpack)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(gitReleaseFetcher.GetContextCall.Receives.Ctx).To(BeIdenticalTo(ctx))
+			Expect(gitReleaseFetcher.GetReleaseTarballContextCall.Receives.Ctx).To(BeIdenticalTo(ctx))
+			Expect(packager.ExecuteContextCall.Receives.Ctx).To(BeIdenticalTo(ctx))
+		})
+
+		context("when the release fetcher and packager cannot be given a context", func() {
+			var (
+				plainReleaseFetcher *fakes.GitReleaseFetcher
+				plainPackager       *fakes.Packager
+			)
+
+			it.Before(func() {
+				plainReleaseFetcher = &fakes.GitReleaseFetcher{}
+				plainReleaseFetcher.GetCall.Returns.Release = gitReleaseFetcher.GetContextCall.Returns.Release
+				plainReleaseFetcher.GetReleaseTarballCall.Returns.ReadCloser = gitReleaseFetcher.GetReleaseTarballContextCall.Returns.ReadCloser
+
+				plainPackager = &fakes.Packager{}
+
+				remoteFetcher = freezer.NewRemoteFetcher(buildpackCache, plainReleaseFetcher, plainPackager).WithFileSystem(fileSystem)
+			})
+
+			it("uses them without it", func() {
+				_, err := remoteFetcher.GetContext(ctx, remoteBuildpack)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(plainReleaseFetcher.GetCall.Receives.Org).To(Equal("some-org"))
+				Expect(plainReleaseFetcher.GetReleaseTarballCall.Receives.Url).To(Equal("some-tarball-url"))
+				Expect(plainPackager.ExecuteCall.Receives.BuildpackDir).To(Equal(downloadDir))
+			})
+
+			it("fetches the latest release in full when the cached entry has validators", func() {
+				buildpackCache.GetCall.Returns.Bool = true
+				buildpackCache.GetCall.Returns.CacheEntry = freezer.CacheEntry{
+					Version: "some-tag",
+					URI:     "keep-this-uri",
+					ETag:    `"some-etag"`,
+				}
+
+				uri, err := remoteFetcher.GetContext(ctx, remoteBuildpack)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(uri).To(Equal("keep-this-uri"))
+
+				Expect(plainReleaseFetcher.GetCall.CallCount).To(Equal(1))
+				Expect(plainPackager.ExecuteCall.CallCount).To(Equal(0))
+			})
+		})
+
+		context("failure cases", func() {
+			context("when the context is done before the release is found", func() {
+				it.Before(func() {
+					remoteBuildpack.Version = "1.2.3"
+					gitReleaseFetcher.GetReleaseByTagContextCall.Stub = func(ctx gocontext.Context, _, _, _ string) (github.Release, error) {
+						return github.Release{}, ctx.Err()
+					}
+
+					cancel()
+				})
+
+				it("returns an error that names the buildpack", func() {
+					_, err := remoteFetcher.GetContext(ctx, remoteBuildpack)
+					Expect(err).To(MatchError("fetching buildpack github.com/some-org/some-repo@1.2.3: context canceled"))
+					Expect(err).To(MatchError(gocontext.Canceled))
+				})
+			})
+
+			context("when the context is done while the release is being decompressed", func() {
+				it.Before(func() {
+					gitReleaseFetcher.GetReleaseTarballContextCall.Stub = func(gocontext.Context, string) (io.ReadCloser, error) {
+						cancel()
+						return io.NopCloser(bytes.NewBuffer([]byte("some-tarball"))), nil
+					}
+				})
+
+				it("returns an error that names the buildpack without packaging it", func() {
+					_, err := remoteFetcher.GetContext(ctx, remoteBuildpack)
+					Expect(err).To(MatchError(ContainSubstring("fetching buildpack github.com/some-org/some-repo: context canceled")))
+					Expect(err).To(MatchError(gocontext.Canceled))
+
+					Expect(packager.ExecuteContextCall.CallCount).To(Equal(0))
+				})
+			})
+		})
+	})
 }
@@ -0,0 +1,79 @@
+package freezer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"path/filepath"
+)
+
+const urlCacheDir = "url"
+
+// URLBuildpack is a buildpack downloaded from an http, https or file URL. The
+// URL refers either to a packaged .cnb file or to a source archive, such as a
+// .tgz, that is packaged once it has been downloaded.
+type URLBuildpack struct {
+	URL         string
+	UncachedKey string
+	CachedKey   string
+	Offline     bool
+	Version     string
+
+	// Checksum is the expected digest of the file at URL, of the form
+	// sha256:<hex>. When it is set the download is verified against it and,
+	// once cached, the URL is never requested again.
+	Checksum string
+}
+
+func NewURLBuildpack(uri string) URLBuildpack {
+	key := redactURL(uri)
+
+	return URLBuildpack{
+		URL:         uri,
+		UncachedKey: key,
+		CachedKey:   fmt.Sprintf("%s:cached", key),
+	}
+}
+
+// cacheKey returns the key the buildpack is stored under in the cache, which
+// includes the checksum so that pinned downloads are kept apart from the
+// latest content of the URL.
+func (u URLBuildpack) cacheKey() string {
+	key := u.UncachedKey
+	if u.Offline {
+		key = u.CachedKey
+	}
+
+	if u.Checksum != "" {
+		key = fmt.Sprintf("%s@%s", key, u.Checksum)
+	}
+
+	return key
+}
+
+// cacheDir returns the directory within cacheDir the buildpack is stored in,
+// named after a digest of the URL as URLs do not make good file names.
+func (u URLBuildpack) cacheDir(cacheDir string) string {
+	sum := sha256.Sum256([]byte(u.UncachedKey))
+
+	dir := filepath.Join(cacheDir, urlCacheDir, hex.EncodeToString(sum[:8]))
+	if u.Offline {
+		dir = filepath.Join(dir, "cached")
+	}
+
+	return dir
+}
+
+// redactURL removes any credentials from uri so that they are not written to
+// the cache index or to error messages.
+func redactURL(uri string) string {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.User == nil {
+		return uri
+	}
+
+	parsed.User = nil
+
+	return parsed.String()
+}
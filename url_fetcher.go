@@ -0,0 +1,309 @@
+package freezer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ForestEckhardt/freezer/internal/httpclient"
+	"github.com/paketo-buildpacks/packit/v2/vacation"
+)
+
+// ErrChecksumMismatch is returned when a buildpack downloaded from a URL does
+// not match the checksum it was referenced with.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// ErrURLNotFound is matched, with errors.Is, by the errors returned when the
+// server a buildpack is downloaded from responds that it does not exist.
+var ErrURLNotFound = errors.New("url not found")
+
+var defaultURLClient = httpclient.New()
+
+// URLFetcher fetches buildpacks from http, https and file URLs. Packaged .cnb
+// files are cached as they are and anything else is treated as a source
+// archive and packaged, just as RemoteFetcher does for release tarballs.
+type URLFetcher struct {
+	buildpackCache BuildpackCache
+	packager       PackagerContext
+	client         *http.Client
+	fileSystem     func(dir string, pattern string) (string, error)
+	offlineMode    bool
+}
+
+func NewURLFetcher(buildpackCache BuildpackCache, packager Packager) URLFetcher {
+	return URLFetcher{
+		buildpackCache: buildpackCache,
+		packager:       packagerContext(packager),
+		client:         defaultURLClient,
+		fileSystem:     os.MkdirTemp,
+		offlineMode:    offlineModeFromEnv(),
+	}
+}
+
+// WithClient sets the client buildpacks are downloaded with, for example one
+// that trusts the CA of an internal artifact server.
+func (u URLFetcher) WithClient(client *http.Client) URLFetcher {
+	u.client = client
+	return u
+}
+
+func (u URLFetcher) WithFileSystem(fileSystem func(string, string) (string, error)) URLFetcher {
+	u.fileSystem = fileSystem
+	return u
+}
+
+// WithOfflineMode determines whether buildpacks at http and https URLs are
+// only resolved from the cache. It defaults to the value of FREEZER_OFFLINE.
+func (u URLFetcher) WithOfflineMode(offlineMode bool) URLFetcher {
+	u.offlineMode = offlineMode
+	return u
+}
+
+func (u URLFetcher) Get(buildpack URLBuildpack) (string, error) {
+	return u.GetContext(context.Background(), buildpack)
+}
+
+// GetContext is like Get but abandons the fetch when ctx is done, returning an
+// error that names the buildpack.
+func (u URLFetcher) GetContext(ctx context.Context, buildpack URLBuildpack) (string, error) {
+	path, err := u.get(ctx, buildpack)
+	if err != nil {
+		return "", contextError(ctx, buildpack.UncachedKey, err)
+	}
+
+	return path, nil
+}
+
+func (u URLFetcher) get(ctx context.Context, buildpack URLBuildpack) (string, error) {
+	uri, err := url.Parse(buildpack.URL)
+	if err != nil {
+		return "", err
+	}
+
+	if uri.Scheme != "http" && uri.Scheme != "https" && uri.Scheme != "file" {
+		return "", fmt.Errorf("unsupported URL scheme %q: expected http, https or file", uri.Scheme)
+	}
+
+	key := buildpack.cacheKey()
+
+	err = u.buildpackCache.Lock(key)
+	if err != nil {
+		return "", err
+	}
+	defer u.buildpackCache.Unlock(key)
+
+	cachedEntry, exist, err := u.buildpackCache.Get(key)
+	if err != nil {
+		return "", err
+	}
+
+	//Files on disk can be read without a network
+	if u.offlineMode && uri.Scheme != "file" {
+		if !exist {
+			return "", fmt.Errorf("%w: no cache entry for key %q", ErrNotCached, key)
+		}
+
+		return cachedEntry.URI, nil
+	}
+
+	//Content with a known checksum never changes
+	if exist && buildpack.Checksum != "" {
+		return cachedEntry.URI, nil
+	}
+
+	buildpackCacheDir := buildpack.cacheDir(u.buildpackCache.Dir())
+	err = os.MkdirAll(buildpackCacheDir, os.ModePerm)
+	if err != nil {
+		return "", err
+	}
+
+	archive, err := os.CreateTemp(buildpackCacheDir, ".download.*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(archive.Name())
+	defer archive.Close()
+
+	var validators CacheEntry
+	if exist {
+		validators = cachedEntry
+	}
+
+	hash := sha256.New()
+	validators, modified, err := u.download(ctx, uri, validators, io.MultiWriter(archive, hash))
+	if err != nil {
+		return "", err
+	}
+
+	if !modified {
+		return cachedEntry.URI, nil
+	}
+
+	digest := "sha256:" + hex.EncodeToString(hash.Sum(nil))
+	if buildpack.Checksum != "" && digest != buildpack.Checksum {
+		return "", fmt.Errorf("%w: %s has digest %s, expected %s", ErrChecksumMismatch, buildpack.UncachedKey, digest, buildpack.Checksum)
+	}
+
+	//The server may not support conditional requests, or the file may have
+	//been touched without changing
+	if exist && cachedEntry.SourceDigest == digest {
+		if cachedEntry.ETag != validators.ETag || cachedEntry.LastModified != validators.LastModified {
+			cachedEntry.ETag = validators.ETag
+			cachedEntry.LastModified = validators.LastModified
+
+			err = u.buildpackCache.Set(key, cachedEntry)
+			if err != nil {
+				return "", err
+			}
+		}
+
+		return cachedEntry.URI, nil
+	}
+
+	path := filepath.Join(buildpackCacheDir, fmt.Sprintf("%s.cnb", strings.TrimPrefix(digest, "sha256:")))
+
+	if strings.HasSuffix(uri.Path, ".cnb") {
+		err = moveIntoPlace(archive, path)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		err = u.pack(ctx, buildpack, archive, path)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	err = u.buildpackCache.Set(key, CacheEntry{
+		Version:      buildpack.Version,
+		URI:          path,
+		SourceDigest: digest,
+		ETag:         validators.ETag,
+		LastModified: validators.LastModified,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// download copies the file at uri to w and returns the validators of the
+// response. For http and https URLs the request is conditional on the
+// validators of cachedEntry and nothing is copied, and false returned, if the
+// file has not changed.
+func (u URLFetcher) download(ctx context.Context, uri *url.URL, cachedEntry CacheEntry, w io.Writer) (CacheEntry, bool, error) {
+	if uri.Scheme == "file" {
+		file, err := os.Open(filepath.FromSlash(uri.Path))
+		if err != nil {
+			return CacheEntry{}, false, err
+		}
+		defer file.Close()
+
+		_, err = io.Copy(w, contextReader{ctx, file})
+		if err != nil {
+			return CacheEntry{}, false, err
+		}
+
+		return CacheEntry{}, true, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", uri.String(), nil)
+	if err != nil {
+		return CacheEntry{}, false, err
+	}
+
+	if cachedEntry.ETag != "" {
+		req.Header.Set("If-None-Match", cachedEntry.ETag)
+	}
+
+	if cachedEntry.LastModified != "" {
+		req.Header.Set("If-Modified-Since", cachedEntry.LastModified)
+	}
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return CacheEntry{}, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return cachedEntry, false, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return CacheEntry{}, false, httpclient.NewStatusError(resp, redactURL(uri.String()), ErrURLNotFound)
+	}
+
+	_, err = io.Copy(w, contextReader{ctx, resp.Body})
+	if err != nil {
+		return CacheEntry{}, false, err
+	}
+
+	return CacheEntry{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}, true, nil
+}
+
+// pack decompresses the source archive and packages the buildpack within it
+// at path.
+func (u URLFetcher) pack(ctx context.Context, buildpack URLBuildpack, archive *os.File, path string) error {
+	_, err := archive.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	downloadDir, err := u.fileSystem("", "url-buildpack")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(downloadDir)
+
+	err = vacation.NewArchive(contextReader{ctx, archive}).Decompress(downloadDir)
+	if err != nil {
+		return err
+	}
+
+	buildpackDir, err := sourceRoot(downloadDir)
+	if err != nil {
+		return err
+	}
+
+	err = u.packager.ExecuteContext(ctx, buildpackDir, path, buildpack.Version, buildpack.Offline)
+	if err != nil {
+		return fmt.Errorf("failed to package buildpack: %w", err)
+	}
+
+	return nil
+}
+
+// sourceRoot returns the directory of a decompressed source archive that
+// holds the buildpack. Archives of a source tree usually contain a single top
+// level directory, as release tarballs do, but may also contain the tree
+// itself.
+func sourceRoot(dir string) (string, error) {
+	_, err := os.Stat(filepath.Join(dir, "buildpack.toml"))
+	if err == nil {
+		return dir, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	if len(entries) == 1 && entries[0].IsDir() {
+		return filepath.Join(dir, entries[0].Name()), nil
+	}
+
+	return dir, nil
+}